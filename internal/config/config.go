@@ -22,6 +22,7 @@ type Config struct {
 	OAuthConfig map[string]OAuthConfig `json:"oauth" yaml:"oauth"`
 	Email       Email                  `json:"email" yaml:"email"`
 	GithubApp   GithubApp              `json:"githubApp" yaml:"githubApp"`
+	Health      Health                 `json:"health" yaml:"health"`
 }
 
 func Current(cfgs ...Cfg) *Config {
@@ -49,6 +50,8 @@ type Server struct {
 	Debug       bool   `json:"debug" yaml:"debug" default:"false"`
 	GracePeriod int    `json:"gracePeriod" yaml:"gracePeriod" default:"30"`
 	PluginPath  string `json:"pluginPath" yaml:"pluginPath" default:"./_plugins"`
+	// ArtifactPath directory of local artifact store of pipeline
+	ArtifactPath string `json:"artifactPath" yaml:"artifactPath" default:"./_artifacts"`
 }
 
 type Email struct {
@@ -86,6 +89,20 @@ type Database struct {
 type GithubApp struct {
 	AppID      int64  `json:"appId" yaml:"appId" default:"1485539"`
 	PrivateKey string `json:"privateKey" yaml:"privateKey" default:"./github_app_private_key.pem"`
+	// WebhookSecret secret of webhook signatures, push and pull_request events are rejected without it
+	WebhookSecret string `json:"webhookSecret" yaml:"webhookSecret"`
+	// APIURL base url of GitHub API, empty means https://api.github.com
+	APIURL string `json:"apiURL" yaml:"apiURL"`
+}
+
+// Health retention of health check history, raw results are downsampled to per-minute and per-hour aggregates
+type Health struct {
+	// RawRetention days raw results of health checks are kept
+	RawRetention int `json:"rawRetention" yaml:"rawRetention" default:"7"`
+	// MinuteRetention days per-minute aggregates are kept
+	MinuteRetention int `json:"minuteRetention" yaml:"minuteRetention" default:"30"`
+	// HourRetention days per-hour aggregates are kept, monthly reports need at least 31 days
+	HourRetention int `json:"hourRetention" yaml:"hourRetention" default:"400"`
 }
 
 type Cfg func(c *Config)
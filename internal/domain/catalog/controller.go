@@ -0,0 +1,188 @@
+package catalog
+
+import (
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type Controller struct {
+	service *Service
+}
+
+func NewController() *Controller {
+	return &Controller{
+		service: GetService(),
+	}
+}
+
+// @Summary	list catalog service
+// @Tags		catalog
+// @Param		groupID	query		string	false	"id of owning group"
+// @Success	200		{object}	response.Response{data=[]CatalogService}
+// @Router		/catalog/list [get]
+// @Produce	json
+func (c *Controller) handleListCatalogService(ctx *gin.Context) {
+	var groupID uuid.UUID
+	if gid := ctx.Query("groupID"); gid != "" {
+		id, err := uuid.Parse(gid)
+		if err != nil {
+			response.Error(ctx, response.CodeParamsError)
+			return
+		}
+		groupID = id
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+	if res, err := c.service.ListCatalogService(groupID, userID.(string)); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	add catalog service
+// @Tags		catalog
+// @Param		service	body		CatalogService	true	"service info"
+// @Success	200		{object}	response.Response
+// @Router		/catalog/add [post]
+// @Produce	json
+func (c *Controller) handleAddCatalogService(ctx *gin.Context) {
+	cs := new(CatalogService)
+	if err := ctx.ShouldBindJSON(cs); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+	if err := c.service.AddCatalogService(cs, userID.(string)); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, nil)
+	}
+}
+
+// @Summary	update catalog service
+// @Tags		catalog
+// @Param		service	body		CatalogService	true	"service info"
+// @Param		id		path		string			true	"service id"
+// @Success	200		{object}	response.Response
+// @Router		/catalog/{id} [put]
+// @Produce	json
+func (c *Controller) handleUpdateCatalogService(ctx *gin.Context) {
+	cs := new(CatalogService)
+	if err := ctx.ShouldBindJSON(cs); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	} else {
+		cs.ID = id
+	}
+
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	if err := c.service.UpdateCatalogService(cs, userID.(string)); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, nil)
+	}
+}
+
+// @Summary	delete catalog service
+// @Tags		catalog
+// @Param		id	path		string	true	"service id"
+// @Success	200	{object}	response.Response
+// @Router		/catalog/{id} [delete]
+// @Produce	json
+func (c *Controller) handleDeleteCatalogService(ctx *gin.Context) {
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	} else {
+		if err := c.service.DeleteCatalogService(id, userID.(string)); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+		} else {
+			response.Success(ctx, nil)
+		}
+	}
+}
+
+// @Summary	detail catalog service
+// @Tags		catalog
+// @Param		id	path		string	true	"service id"
+// @Success	200	{object}	response.Response{data=CatalogService}
+// @Router		/catalog/{id}/detail [get]
+// @Produce	json
+func (c *Controller) handleDetailCatalogService(ctx *gin.Context) {
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	} else {
+		if res, err := c.service.DetailCatalogServiceOfMember(id, userID.(string)); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeNotFound, err.Error())
+		} else {
+			response.Success(ctx, res)
+		}
+	}
+}
+
+// @Summary		get status of catalog service
+// @Description	status of service is aggregated from its module, hosts, health checks, schedules and workflows
+// @Tags			catalog
+// @Param			id	path		string	true	"service id"
+// @Success		200	{object}	response.Response{data=ServiceStatus}
+// @Router			/catalog/{id}/status [get]
+// @Produce		json
+func (c *Controller) handleGetServiceStatus(ctx *gin.Context) {
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	} else {
+		if res, err := c.service.GetServiceStatus(id, userID.(string)); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeNotFound, err.Error())
+		} else {
+			response.Success(ctx, res)
+		}
+	}
+}
+
+func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
+	api := group.Group("/catalog")
+
+	api.GET("/list", c.handleListCatalogService)
+	api.POST("/add", c.handleAddCatalogService)
+	api.PUT("/:id", c.handleUpdateCatalogService)
+	api.DELETE("/:id", c.handleDeleteCatalogService)
+	api.GET("/:id/detail", c.handleDetailCatalogService)
+	api.GET("/:id/status", c.handleGetServiceStatus)
+}
@@ -0,0 +1,105 @@
+package catalog
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	StatusUp       = "up"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+	StatusUnknown  = "unknown"
+
+	KindModule   = "module"
+	KindHost     = "host"
+	KindHealth   = "health"
+	KindSchedule = "schedule"
+	KindWorkflow = "workflow"
+)
+
+// CatalogService groups a repository with the hosts it runs on, the health checks watching it,
+// its scheduled jobs and workflows, it is owned by a user group
+type CatalogService struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey" swaggerignore:"true" example:"00000000-0000-0000-0000-000000000000"`
+	Title       string    `json:"title" gorm:"unique;not null" validate:"required"`
+	Desc        string    `json:"desc"`
+	GroupID     uuid.UUID `json:"groupID" gorm:"type:uuid;index" validate:"required" example:"00000000-0000-0000-0000-000000000000"`
+	ModuleID    int64     `json:"moduleID"`
+	HostIDs     UUIDs     `json:"hostIDs" gorm:"type:text"`
+	HealthIDs   UUIDs     `json:"healthIDs" gorm:"type:text"`
+	ScheduleIDs UUIDs     `json:"scheduleIDs" gorm:"type:text"`
+	WorkflowIDs Strings   `json:"workflowIDs" gorm:"type:text"`
+
+	database.BaseModel
+}
+
+func (s *CatalogService) TableName() string {
+	return "catalog_service"
+}
+
+func (s *CatalogService) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// ServiceStatus is overall status of service aggregated from status of its components
+type ServiceStatus struct {
+	ID         uuid.UUID          `json:"id" example:"00000000-0000-0000-0000-000000000000"`
+	Title      string             `json:"title"`
+	Status     string             `json:"status" example:"up"`
+	Components []*ComponentStatus `json:"components"`
+	CheckedAt  time.Time          `json:"checkedAt"`
+}
+
+// ComponentStatus is status of a module, host, health check, schedule or workflow of service
+type ComponentStatus struct {
+	Kind   string    `json:"kind" example:"health"`
+	ID     string    `json:"id"`
+	Title  string    `json:"title"`
+	Status string    `json:"status" example:"up"` // up, degraded, down or unknown
+	Detail string    `json:"detail"`              // status reported by component, e.g. status of last run
+	Time   time.Time `json:"time"`                // time of detail, zero if it is unknown
+}
+
+type UUIDs []uuid.UUID
+
+func (u *UUIDs) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), u)
+	case []byte:
+		return json.Unmarshal(v, u)
+	default:
+		return nil
+	}
+}
+
+func (u UUIDs) Value() (driver.Value, error) {
+	s, err := json.Marshal(u)
+	return string(s), err
+}
+
+type Strings []string
+
+func (s *Strings) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case []byte:
+		return json.Unmarshal(v, s)
+	default:
+		return nil
+	}
+}
+
+func (s Strings) Value() (driver.Value, error) {
+	res, err := json.Marshal(s)
+	return string(res), err
+}
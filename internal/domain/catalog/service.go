@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"github.com/MR5356/aurora/internal/domain/health"
+	"github.com/MR5356/aurora/internal/domain/host"
+	"github.com/MR5356/aurora/internal/domain/module"
+	"github.com/MR5356/aurora/internal/domain/pipeline"
+	"github.com/MR5356/aurora/internal/domain/schedule"
+	"github.com/MR5356/aurora/internal/domain/user"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/pkg/util/structutil"
+	"github.com/MR5356/aurora/pkg/util/validate"
+	"github.com/google/uuid"
+	"slices"
+	"sync"
+)
+
+var (
+	onceService sync.Once
+	service     *Service
+)
+
+type Service struct {
+	catalogDB *database.BaseMapper[*CatalogService]
+}
+
+func GetService() *Service {
+	onceService.Do(func() {
+		service = &Service{
+			catalogDB: database.NewMapper(database.GetDB(), &CatalogService{}),
+		}
+	})
+	return service
+}
+
+// ListCatalogService list services of groups of which user is a member, services of group are listed if groupID is not nil
+func (s *Service) ListCatalogService(groupID uuid.UUID, userID string) ([]*CatalogService, error) {
+	if groupID != uuid.Nil && !user.GetService().IsGroupMember(userID, groupID) {
+		return nil, fmt.Errorf("user is not a member of group %s", groupID)
+	}
+	services, err := s.catalogDB.List(&CatalogService{GroupID: groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*CatalogService, 0, len(services))
+	member := make(map[uuid.UUID]bool)
+	for _, cs := range services {
+		isMember, ok := member[cs.GroupID]
+		if !ok {
+			isMember = user.GetService().IsGroupMember(userID, cs.GroupID)
+			member[cs.GroupID] = isMember
+		}
+		if isMember {
+			res = append(res, cs)
+		}
+	}
+	return res, nil
+}
+
+// AddCatalogService add service to group of which user is a member
+func (s *Service) AddCatalogService(cs *CatalogService, userID string) error {
+	cs.ID = uuid.Nil
+	if err := validate.Validate(cs); err != nil {
+		return err
+	}
+	if err := s.checkLinks(cs, nil, userID); err != nil {
+		return err
+	}
+	return s.catalogDB.Insert(cs)
+}
+
+// UpdateCatalogService update service of group of which user is a member, it can be moved to another group of user
+func (s *Service) UpdateCatalogService(cs *CatalogService, userID string) error {
+	old, err := s.getMemberService(cs.ID, userID)
+	if err != nil {
+		return err
+	}
+	if err := validate.Validate(cs); err != nil {
+		return err
+	}
+	if err := s.checkLinks(cs, old, userID); err != nil {
+		return err
+	}
+	return s.catalogDB.Update(&CatalogService{ID: cs.ID}, structutil.Struct2Map(cs))
+}
+
+// DeleteCatalogService delete service of group of which user is a member
+func (s *Service) DeleteCatalogService(id uuid.UUID, userID string) error {
+	if _, err := s.getMemberService(id, userID); err != nil {
+		return err
+	}
+	return s.catalogDB.Delete(&CatalogService{ID: id})
+}
+
+// DetailCatalogServiceOfMember detail service of group of which user is a member
+func (s *Service) DetailCatalogServiceOfMember(id uuid.UUID, userID string) (*CatalogService, error) {
+	return s.getMemberService(id, userID)
+}
+
+// getMemberService get service of group of which user is a member
+func (s *Service) getMemberService(id uuid.UUID, userID string) (*CatalogService, error) {
+	cs, err := s.DetailCatalogService(id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.GetService().IsGroupMember(userID, cs.GroupID) {
+		return nil, fmt.Errorf("user is not a member of group %s", cs.GroupID)
+	}
+	return cs, nil
+}
+
+func (s *Service) DetailCatalogService(id uuid.UUID) (*CatalogService, error) {
+	if id == uuid.Nil {
+		return nil, errors.New("service not found")
+	}
+	return s.catalogDB.Detail(&CatalogService{ID: id})
+}
+
+// checkLinks check owning group of which user is a member and linked hosts, health checks and schedules exist.
+// module and workflows newly linked compared with old service must be owned by user, as links are shared with group
+func (s *Service) checkLinks(cs, old *CatalogService, userID string) error {
+	if old == nil {
+		old = new(CatalogService)
+	}
+	if _, err := user.GetService().DetailGroup(cs.GroupID); err != nil {
+		return fmt.Errorf("group %s not found", cs.GroupID)
+	}
+	if !user.GetService().IsGroupMember(userID, cs.GroupID) {
+		return fmt.Errorf("user is not a member of group %s", cs.GroupID)
+	}
+	if cs.ModuleID != 0 {
+		if cs.ModuleID == old.ModuleID {
+			if _, err := module.GetService().DetailModule(cs.ModuleID); err != nil {
+				return err
+			}
+		} else if _, err := module.GetService().GetModuleDetail(cs.ModuleID, userID); err != nil {
+			return err
+		}
+	}
+	for _, id := range cs.HostIDs {
+		if _, err := host.GetService().DetailHost(id); id == uuid.Nil || err != nil {
+			return fmt.Errorf("host %s not found", id)
+		}
+	}
+	for _, id := range cs.HealthIDs {
+		if _, err := health.GetService().DetailHealth(id); id == uuid.Nil || err != nil {
+			return fmt.Errorf("health check %s not found", id)
+		}
+	}
+	for _, id := range cs.ScheduleIDs {
+		if _, err := schedule.GetService().DetailSchedule(id); id == uuid.Nil || err != nil {
+			return fmt.Errorf("schedule %s not found", id)
+		}
+	}
+	for _, id := range cs.WorkflowIDs {
+		wf, err := pipeline.GetService().GetWorkflow(&pipeline.Workflow{ID: id})
+		if id == "" || err != nil || (wf.Owner != userID && !slices.Contains(old.WorkflowIDs, id)) {
+			return fmt.Errorf("workflow %s not found", id)
+		}
+	}
+	return nil
+}
+
+func (s *Service) Initialize() error {
+	return database.GetDB().AutoMigrate(&CatalogService{})
+}
@@ -0,0 +1,275 @@
+package catalog
+
+import (
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/health"
+	"github.com/MR5356/aurora/internal/domain/host"
+	"github.com/MR5356/aurora/internal/domain/module"
+	"github.com/MR5356/aurora/internal/domain/pipeline"
+	"github.com/MR5356/aurora/internal/domain/schedule"
+	"github.com/MR5356/aurora/internal/domain/user"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
+	"github.com/MR5356/aurora/pkg/util/sshutil"
+	"github.com/google/uuid"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+var setupOnce sync.Once
+
+func setupTest(t *testing.T) *Service {
+	setupOnce.Do(func() {
+		cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+		database.NewDatabase(cfg)
+		eventbus.NewEventBus(cfg)
+		if err := database.GetDB().AutoMigrate(
+			&user.Group{}, &user.Relation{}, &host.Host{}, &host.Group{}, &health.Health{}, &schedule.Schedule{}, &schedule.Record{},
+			&pipeline.Workflow{}, &pipeline.Nodes{}, &pipeline.Edges{}, &pipeline.Run{},
+			&module.Module{}, &module.ModuleMetadata{}, &module.SCMConnection{}, &module.InstallationIDRelation{},
+		); err != nil {
+			t.Fatalf("auto migrate failed: %v", err)
+		}
+		if err := GetService().Initialize(); err != nil {
+			t.Fatalf("initialize catalog failed: %v", err)
+		}
+	})
+	return GetService()
+}
+
+func create(t *testing.T, value any) {
+	if err := database.GetDB().Create(value).Error; err != nil {
+		t.Fatalf("create %T failed: %v", value, err)
+	}
+}
+
+// hostInfo returns address of a listening port if online, otherwise of a closed port
+func hostInfo(t *testing.T, online bool) sshutil.HostInfo {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	if online {
+		t.Cleanup(func() { _ = l.Close() })
+	} else {
+		_ = l.Close()
+	}
+	addr := l.Addr().(*net.TCPAddr)
+	return sshutil.HostInfo{Host: addr.IP.String(), Port: uint16(addr.Port)}
+}
+
+func TestService_CatalogService(t *testing.T) {
+	s := setupTest(t)
+
+	group := &user.Group{ID: uuid.New(), Title: "team-crud"}
+	create(t, group)
+	other := &user.Group{ID: uuid.New(), Title: "team-other"}
+	create(t, other)
+	create(t, &user.Relation{UserID: "crud-member", GroupID: group.ID})
+	create(t, &user.Relation{UserID: "crud-peer", GroupID: group.ID})
+	h := &host.Host{Title: "web-1", HostInfo: hostInfo(t, true)}
+	create(t, h)
+	m := &module.Module{ID: 2, Owner: "aurora", Name: "crud", InstallationID: 2}
+	create(t, m)
+	wf := &pipeline.Workflow{Title: "crud", Owner: "crud-other"}
+	create(t, wf)
+	owned := &pipeline.Workflow{Title: "crud-owned", Owner: "crud-member"}
+	create(t, owned)
+
+	cs := &CatalogService{Title: "crud", GroupID: group.ID, HostIDs: UUIDs{h.ID}}
+	if err := s.AddCatalogService(cs, "crud-member"); err != nil {
+		t.Fatalf("add service failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cs   *CatalogService
+	}{
+		{"no group", &CatalogService{Title: "no-group"}},
+		{"unknown group", &CatalogService{Title: "unknown-group", GroupID: uuid.New()}},
+		{"unknown module", &CatalogService{Title: "unknown-module", GroupID: group.ID, ModuleID: 404}},
+		{"unknown host", &CatalogService{Title: "unknown-host", GroupID: group.ID, HostIDs: UUIDs{uuid.New()}}},
+		{"nil host", &CatalogService{Title: "nil-host", GroupID: group.ID, HostIDs: UUIDs{uuid.Nil}}},
+		{"unknown health", &CatalogService{Title: "unknown-health", GroupID: group.ID, HealthIDs: UUIDs{uuid.New()}}},
+		{"unknown schedule", &CatalogService{Title: "unknown-schedule", GroupID: group.ID, ScheduleIDs: UUIDs{uuid.New()}}},
+		{"unknown workflow", &CatalogService{Title: "unknown-workflow", GroupID: group.ID, WorkflowIDs: Strings{"404"}}},
+		{"duplicated title", &CatalogService{Title: "crud", GroupID: group.ID}},
+		{"group not member of", &CatalogService{Title: "other-group", GroupID: other.ID}},
+		{"module not owned", &CatalogService{Title: "module-not-owned", GroupID: group.ID, ModuleID: m.ID}},
+		{"workflow not owned", &CatalogService{Title: "workflow-not-owned", GroupID: group.ID, WorkflowIDs: Strings{wf.ID}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.AddCatalogService(tt.cs, "crud-member"); err == nil {
+				t.Errorf("expected error adding service")
+			}
+		})
+	}
+
+	res, err := s.DetailCatalogService(cs.ID)
+	if err != nil {
+		t.Fatalf("detail service failed: %v", err)
+	}
+	if len(res.HostIDs) != 1 || res.HostIDs[0] != h.ID {
+		t.Errorf("unexpected hosts: %v", res.HostIDs)
+	}
+
+	if err := s.UpdateCatalogService(&CatalogService{ID: cs.ID, Title: "crud", Desc: "updated", GroupID: group.ID}, "crud-other"); err == nil {
+		t.Errorf("expected error of updating service by user not member of group")
+	}
+	if err := s.UpdateCatalogService(&CatalogService{ID: cs.ID, Title: "crud", GroupID: other.ID}, "crud-member"); err == nil {
+		t.Errorf("expected error of moving service to group not member of")
+	}
+	if err := s.UpdateCatalogService(&CatalogService{ID: cs.ID, Title: "crud", Desc: "updated", GroupID: group.ID}, "crud-member"); err != nil {
+		t.Fatalf("update service failed: %v", err)
+	}
+	if res, _ = s.DetailCatalogService(cs.ID); res.Desc != "updated" || len(res.HostIDs) != 0 {
+		t.Errorf("service not updated: %+v", res)
+	}
+
+	// links already on service are kept by member not owning them, only new links must be owned
+	if err := s.UpdateCatalogService(&CatalogService{ID: cs.ID, Title: "crud", GroupID: group.ID, WorkflowIDs: Strings{owned.ID}}, "crud-member"); err != nil {
+		t.Fatalf("link owned workflow failed: %v", err)
+	}
+	if err := s.UpdateCatalogService(&CatalogService{ID: cs.ID, Title: "crud", Desc: "peer", GroupID: group.ID, WorkflowIDs: Strings{owned.ID}}, "crud-peer"); err != nil {
+		t.Fatalf("update service by member of group failed: %v", err)
+	}
+	if err := s.UpdateCatalogService(&CatalogService{ID: cs.ID, Title: "crud", GroupID: group.ID, WorkflowIDs: Strings{owned.ID, wf.ID}}, "crud-peer"); err == nil {
+		t.Errorf("expected error of linking workflow not owned")
+	}
+
+	if _, err := s.DetailCatalogServiceOfMember(cs.ID, "crud-peer"); err != nil {
+		t.Errorf("detail service by member of group failed: %v", err)
+	}
+	if _, err := s.DetailCatalogServiceOfMember(cs.ID, "crud-other"); err == nil {
+		t.Errorf("expected error of detailing service by user not member of group")
+	}
+
+	list, err := s.ListCatalogService(group.ID, "crud-member")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected 1 service of group, got %d, error: %v", len(list), err)
+	}
+	if list, _ = s.ListCatalogService(uuid.Nil, "crud-peer"); len(list) != 1 {
+		t.Errorf("expected 1 service of groups of member, got %d", len(list))
+	}
+	if list, _ = s.ListCatalogService(uuid.Nil, "crud-other"); len(list) != 0 {
+		t.Errorf("expected no service of groups not member of, got %d", len(list))
+	}
+	if _, err := s.ListCatalogService(group.ID, "crud-other"); err == nil {
+		t.Errorf("expected error of listing services of group not member of")
+	}
+
+	if err := s.DeleteCatalogService(cs.ID, "crud-other"); err == nil {
+		t.Errorf("expected error of deleting service by user not member of group")
+	}
+	if err := s.DeleteCatalogService(cs.ID, "crud-member"); err != nil {
+		t.Fatalf("delete service failed: %v", err)
+	}
+	if _, err := s.DetailCatalogService(cs.ID); err == nil {
+		t.Errorf("expected service deleted")
+	}
+	if _, err := s.DetailCatalogService(uuid.Nil); err == nil {
+		t.Errorf("expected error of nil id")
+	}
+}
+
+func TestService_GetServiceStatus(t *testing.T) {
+	s := setupTest(t)
+
+	group := &user.Group{ID: uuid.New(), Title: "team-status"}
+	create(t, group)
+	create(t, &user.Relation{UserID: "status-member", GroupID: group.ID})
+	create(t, &user.Relation{UserID: "status-peer", GroupID: group.ID})
+	m := &module.Module{ID: 1, Owner: "aurora", Name: "api", InstallationID: 1}
+	create(t, m)
+	create(t, &module.InstallationIDRelation{InstallationID: 1, Owner: "status-member"})
+	create(t, &module.ModuleMetadata{ModuleID: m.ID, SyncedAt: time.Now(), OpenPRCount: 2})
+	online := &host.Host{Title: "online", HostInfo: hostInfo(t, true)}
+	create(t, online)
+	hc := &health.Health{Title: "api", Type: "http", Enabled: true, Params: "[]", Status: "up"}
+	create(t, hc)
+	sc := &schedule.Schedule{Title: "backup", CronString: "0 0 * * * *", Executor: "test", Enabled: true}
+	create(t, sc)
+	create(t, &schedule.Record{ScheduleID: sc.ID, Title: "backup", Executor: "test", Status: schedule.TaskStatusSuccess})
+	wf := &pipeline.Workflow{Title: "deploy", Owner: "status-member"}
+	create(t, wf)
+
+	cs := &CatalogService{
+		Title:       "status",
+		GroupID:     group.ID,
+		ModuleID:    m.ID,
+		HostIDs:     UUIDs{online.ID},
+		HealthIDs:   UUIDs{hc.ID},
+		ScheduleIDs: UUIDs{sc.ID},
+		WorkflowIDs: Strings{wf.ID},
+	}
+	if err := s.AddCatalogService(cs, "status-member"); err != nil {
+		t.Fatalf("add service failed: %v", err)
+	}
+
+	expectStatus := func(status string, components map[string]string) {
+		t.Helper()
+		for _, userID := range []string{"status-member", "status-peer"} {
+			res, err := s.GetServiceStatus(cs.ID, userID)
+			if err != nil {
+				t.Fatalf("get status by %s failed: %v", userID, err)
+			}
+			if res.Status != status {
+				t.Errorf("expected service %s by %s, got %s", status, userID, res.Status)
+			}
+			for _, c := range res.Components {
+				if want, ok := components[c.Kind]; ok && c.Status != want {
+					t.Errorf("expected %s %s by %s, got %s (%s)", c.Kind, want, userID, c.Status, c.Detail)
+				}
+			}
+		}
+	}
+
+	// workflow never ran
+	expectStatus(StatusUp, map[string]string{
+		KindModule: StatusUp, KindHost: StatusUp, KindHealth: StatusUp, KindSchedule: StatusUp, KindWorkflow: StatusUnknown,
+	})
+
+	create(t, &pipeline.Run{ID: uuid.NewString(), WorkflowId: wf.ID, Status: pipeline.RunStatusFailure})
+	expectStatus(StatusDegraded, map[string]string{KindWorkflow: StatusDegraded})
+
+	offline := &host.Host{Title: "offline", HostInfo: hostInfo(t, false)}
+	create(t, offline)
+	cs.HostIDs = UUIDs{online.ID, offline.ID}
+	if err := s.UpdateCatalogService(cs, "status-member"); err != nil {
+		t.Fatalf("update service failed: %v", err)
+	}
+	expectStatus(StatusDown, nil)
+
+	if _, err := s.GetServiceStatus(cs.ID, "status-other"); err == nil {
+		t.Errorf("expected error of getting status by user not member of group")
+	}
+
+	if _, err := s.GetServiceStatus(uuid.New(), "status-member"); err == nil {
+		t.Errorf("expected error of unknown service")
+	}
+}
+
+func TestAggregateStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{nil, StatusUnknown},
+		{[]string{StatusUnknown, StatusUnknown}, StatusUnknown},
+		{[]string{StatusUp, StatusUnknown}, StatusUp},
+		{[]string{StatusUp, StatusDegraded, StatusUnknown}, StatusDegraded},
+		{[]string{StatusDown, StatusDegraded, StatusUp}, StatusDown},
+	}
+	for _, tt := range tests {
+		components := make([]*ComponentStatus, 0)
+		for _, status := range tt.statuses {
+			components = append(components, &ComponentStatus{Status: status})
+		}
+		if got := aggregateStatus(components); got != tt.want {
+			t.Errorf("aggregateStatus(%v) = %s, want %s", tt.statuses, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package catalog
+
+import (
+	"fmt"
+	"github.com/MR5356/aurora/internal/domain/health"
+	"github.com/MR5356/aurora/internal/domain/host"
+	"github.com/MR5356/aurora/internal/domain/module"
+	"github.com/MR5356/aurora/internal/domain/pipeline"
+	"github.com/MR5356/aurora/internal/domain/schedule"
+	"github.com/google/uuid"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// hostProbeTimeout timeout of dialing ssh port of host
+const hostProbeTimeout = 2 * time.Second
+
+// statusSeverity severity of statuses, the most severe status of components is status of service
+var statusSeverity = map[string]int{
+	StatusUnknown:  0,
+	StatusUp:       1,
+	StatusDegraded: 2,
+	StatusDown:     3,
+}
+
+// GetServiceStatus aggregate status of module, hosts, health checks, schedules and workflows of service of group
+// of which user is a member
+func (s *Service) GetServiceStatus(id uuid.UUID, userID string) (*ServiceStatus, error) {
+	cs, err := s.getMemberService(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	components := make([]*ComponentStatus, 0)
+	if cs.ModuleID != 0 {
+		components = append(components, moduleStatus(cs.ModuleID))
+	}
+	components = append(components, hostStatuses(cs.HostIDs)...)
+	for _, id := range cs.HealthIDs {
+		components = append(components, healthStatus(id))
+	}
+	for _, id := range cs.ScheduleIDs {
+		components = append(components, scheduleStatus(id))
+	}
+	for _, id := range cs.WorkflowIDs {
+		components = append(components, workflowStatus(id))
+	}
+
+	return &ServiceStatus{
+		ID:         cs.ID,
+		Title:      cs.Title,
+		Status:     aggregateStatus(components),
+		Components: components,
+		CheckedAt:  time.Now(),
+	}, nil
+}
+
+// aggregateStatus returns the most severe status of components, unknown components are ignored unless all are unknown
+func aggregateStatus(components []*ComponentStatus) string {
+	res := StatusUnknown
+	for _, c := range components {
+		if statusSeverity[c.Status] > statusSeverity[res] {
+			res = c.Status
+		}
+	}
+	return res
+}
+
+func notFound(kind, id string) *ComponentStatus {
+	return &ComponentStatus{Kind: kind, ID: id, Status: StatusDegraded, Detail: "not found"}
+}
+
+// moduleStatus module is degraded if its metadata can not be synced
+func moduleStatus(id int64) *ComponentStatus {
+	m, err := module.GetService().DetailModule(id)
+	if err != nil {
+		return notFound(KindModule, strconv.FormatInt(id, 10))
+	}
+	res := &ComponentStatus{Kind: KindModule, ID: strconv.FormatInt(id, 10), Title: m.Owner + "/" + m.Name, Status: StatusUnknown}
+	if meta := m.Metadata; meta != nil {
+		res.Time = meta.SyncedAt
+		if meta.SyncError != "" {
+			res.Status, res.Detail = StatusDegraded, meta.SyncError
+		} else {
+			res.Status, res.Detail = StatusUp, fmt.Sprintf("%d open pull requests", meta.OpenPRCount)
+		}
+	}
+	return res
+}
+
+// hostStatuses host is up if its ssh port is reachable, hosts are probed concurrently
+func hostStatuses(ids []uuid.UUID) []*ComponentStatus {
+	res := make([]*ComponentStatus, len(ids))
+	wg := sync.WaitGroup{}
+	for i, id := range ids {
+		h, err := host.GetService().DetailHost(id)
+		if err != nil {
+			res[i] = notFound(KindHost, id.String())
+			continue
+		}
+		res[i] = &ComponentStatus{Kind: KindHost, ID: id.String(), Title: h.Title}
+
+		wg.Add(1)
+		go func(c *ComponentStatus, addr string) {
+			defer wg.Done()
+			conn, err := net.DialTimeout("tcp", addr, hostProbeTimeout)
+			c.Time = time.Now()
+			if err != nil {
+				c.Status, c.Detail = StatusDown, err.Error()
+				return
+			}
+			_ = conn.Close()
+			c.Status, c.Detail = StatusUp, "online"
+		}(res[i], net.JoinHostPort(h.HostInfo.Host, strconv.Itoa(int(h.HostInfo.Port))))
+	}
+	wg.Wait()
+	return res
+}
+
+// healthStatus status of last result of health check
+func healthStatus(id uuid.UUID) *ComponentStatus {
+	h, err := health.GetService().DetailHealth(id)
+	if err != nil {
+		return notFound(KindHealth, id.String())
+	}
+	res := &ComponentStatus{Kind: KindHealth, ID: id.String(), Title: h.Title, Detail: h.Status, Time: h.UpdatedAt}
+	switch {
+	case !h.Enabled:
+		res.Status, res.Detail = StatusUnknown, "disabled"
+	case h.Status == "up":
+		res.Status = StatusUp
+	case h.Status == "down":
+		res.Status = StatusDown
+	case h.Status == "error":
+		res.Status = StatusDegraded
+	default:
+		res.Status = StatusUnknown
+	}
+	return res
+}
+
+// scheduleStatus status of last run of schedule, failed runs degrade service
+func scheduleStatus(id uuid.UUID) *ComponentStatus {
+	sc, err := schedule.GetService().DetailSchedule(id)
+	if err != nil {
+		return notFound(KindSchedule, id.String())
+	}
+	res := &ComponentStatus{Kind: KindSchedule, ID: id.String(), Title: sc.Title, Status: StatusUnknown}
+	if !sc.Enabled {
+		res.Detail = "disabled"
+		return res
+	}
+	records, err := schedule.GetService().PageScheduleRecord(1, 1, &schedule.Record{ScheduleID: id})
+	if err != nil || len(records.Data) == 0 {
+		return res
+	}
+	last := records.Data[0]
+	res.Detail, res.Time = last.Status, last.StartTime
+	switch last.Status {
+	case schedule.TaskStatusSuccess, schedule.TaskStatusRunning:
+		res.Status = StatusUp
+	case schedule.TaskStatusError:
+		res.Status = StatusDegraded
+	}
+	return res
+}
+
+// workflowStatus status of last run of workflow, failed runs degrade service
+func workflowStatus(id string) *ComponentStatus {
+	wf, err := pipeline.GetService().GetWorkflow(&pipeline.Workflow{ID: id})
+	if id == "" || err != nil {
+		return notFound(KindWorkflow, id)
+	}
+	res := &ComponentStatus{Kind: KindWorkflow, ID: id, Title: wf.Title, Status: StatusUnknown}
+	runs, err := pipeline.GetService().PageRun(1, 1, &pipeline.Run{WorkflowId: id})
+	if err != nil || len(runs.Data) == 0 {
+		return res
+	}
+	last := runs.Data[0]
+	res.Detail, res.Time = last.Status, last.StartTime
+	switch last.Status {
+	case pipeline.RunStatusSuccess, pipeline.RunStatusRunning:
+		res.Status = StatusUp
+	case pipeline.RunStatusFailure:
+		res.Status = StatusDegraded
+	}
+	return res
+}
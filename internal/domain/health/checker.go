@@ -3,14 +3,15 @@ package health
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/MR5356/aurora/pkg/util/structutil"
 	"github.com/MR5356/health"
 	"github.com/MR5356/health/database"
 	"github.com/MR5356/health/host"
-	"github.com/MR5356/health/url"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cast"
+	"time"
 )
 
 const (
@@ -19,8 +20,23 @@ const (
 	defaultHttpCron = "*/2 * * * * *"
 	defaultSSHCron  = "*/10 * * * * *"
 	defaultDBCron   = "*/10 * * * * *"
+	defaultTCPCron  = "*/5 * * * * *"
+	defaultDNSCron  = "*/10 * * * * *"
+	defaultGRPCCron = "*/5 * * * * *"
+	defaultTLSCron  = "0 * * * * *"
+
+	defaultTimeout       = 5 * time.Second
+	defaultSlowThreshold = 460 // milliseconds
 )
 
+// checkerBuilders build checkers of types from params, ErrParam is returned if any param is invalid
+var checkerBuilders = map[string]func(params Params, timeout time.Duration) (health.Checker, error){
+	typeTCP:  newTCPChecker,
+	typeDNS:  newDNSChecker,
+	typeGRPC: newGRPCChecker,
+	typeTLS:  newTLSChecker,
+}
+
 var (
 	ErrParam    = errors.New("invalid param")
 	StatusError = health.Status("error")
@@ -36,11 +52,35 @@ func getCron(t string) string {
 		return defaultSSHCron
 	case "database":
 		return defaultDBCron
+	case "tcp":
+		return defaultTCPCron
+	case "dns":
+		return defaultDNSCron
+	case "grpc":
+		return defaultGRPCCron
+	case "tls":
+		return defaultTLSCron
 	default:
 		return defaultCron
 	}
 }
 
+// checkSpec cron of health check, interval of health overrides default cron of type
+func checkSpec(h *Health) string {
+	if h.Interval > 0 {
+		return fmt.Sprintf("@every %ds", h.Interval)
+	}
+	return getCron(h.Type)
+}
+
+// checkTimeout timeout of a check of health
+func checkTimeout(h *Health) time.Duration {
+	if h.Timeout > 0 {
+		return time.Duration(h.Timeout) * time.Millisecond
+	}
+	return defaultTimeout
+}
+
 func (s *Service) initChecker() error {
 	if healths, err := s.healthDb.List(&Health{Enabled: true}); err != nil {
 		return err
@@ -57,16 +97,39 @@ func (s *Service) initChecker() error {
 type Checker struct {
 	health  *Health
 	service *Service
+
+	// consecutive down and up results
+	failures  int
+	successes int
 }
 
 func (c *Checker) Run() {
 	logrus.Debugf("health check: %s", c.health.Title)
+	prev := c.health.Status
 	c.health.RTT = 0
 	c.health.Status = string(health.StatusUnknown)
+	c.health.Warning = ""
+	var result any
+	var observed string
 	defer func() {
 		if err := c.service.healthDb.Update(&Health{ID: c.health.ID}, structutil.Struct2Map(c.health)); err != nil {
 			logrus.Errorf("update health failed, error: %v", err)
 		}
+
+		// invalid params are reported right away
+		if observed == "" {
+			observed = c.health.Status
+		}
+		res, _ := json.Marshal(result)
+		healthRecord := &Record{
+			ParentId: c.health.ID,
+			Status:   observed,
+			Rtt:      c.health.RTT,
+			Result:   string(res),
+		}
+		if err := c.service.healthRecordDb.Insert(healthRecord); err != nil {
+			logrus.Errorf("insert health record failed, error: %v", err)
+		}
 	}()
 	var checker health.Checker
 	var params Params
@@ -77,12 +140,13 @@ func (c *Checker) Run() {
 	}
 	switch c.health.Type {
 	case "http":
-		if str, err := cast.ToStringE(params.GetKey("url")); err != nil {
-			logrus.Errorf("http url is empty")
+		if hc, err := newHTTPChecker(params, checkTimeout(c.health)); err != nil {
+			logrus.Errorf("invalid http params, error: %v", err)
 			c.health.Status = string(StatusError)
+			result = &HTTPResult{Error: err.Error()}
 			return
 		} else {
-			checker = url.NewChecker(str)
+			checker = hc
 		}
 	case "ssh":
 		privateKey, err := cast.ToStringE(params.GetKey("privateKey"))
@@ -121,17 +185,17 @@ func (c *Checker) Run() {
 			c.health.Status = string(StatusError)
 			return
 		}
-		checker = host.NewSSHChecker(&host.HostInfo{
+		checker = host.NewSSHCheckerWithTimeout(&host.HostInfo{
 			PrivateKey: privateKey,
 			Passphrase: passphrase,
 			Host:       hostStr,
 			Port:       port,
 			Username:   username,
 			Password:   password,
-		})
+		}, checkTimeout(c.health))
 	case "ping":
 		if str, err := cast.ToStringE(params.GetKey("host")); err == nil {
-			checker = host.NewPingChecker(str)
+			checker = host.NewPingCheckerWithTimeout(str, checkTimeout(c.health))
 		} else {
 			logrus.Errorf("ping host is empty")
 			c.health.Status = string(StatusError)
@@ -150,33 +214,76 @@ func (c *Checker) Run() {
 			c.health.Status = string(StatusError)
 			return
 		}
-		checker = database.NewChecker(dbType, dsn)
+		checker = &timeoutChecker{checker: database.NewChecker(dbType, dsn), timeout: checkTimeout(c.health)}
+	case "tcp", "dns", "grpc", "tls":
+		var err error
+		if checker, err = checkerBuilders[c.health.Type](params, checkTimeout(c.health)); err != nil {
+			logrus.Errorf("invalid %s params, error: %v", c.health.Type, err)
+			c.health.Status = string(StatusError)
+			result = map[string]string{"error": err.Error()}
+			return
+		}
 	default:
 		logrus.Errorf("unknown health check type: %s", c.health.Type)
 		c.health.Status = string(StatusError)
 		return
 	}
 	res := checker.Check()
-	c.health.Status = string(res.Status)
+	observed = string(res.Status)
+	c.health.Status = c.nextStatus(prev, observed)
 	c.health.RTT = res.RTT
+	result = res.Result
+	if w, ok := result.(warner); ok {
+		c.health.Warning = w.warning()
+	}
+}
+
+// nextStatus health turns down after FailuresBeforeDown consecutive down results and up after
+// SuccessesBeforeUp consecutive up results, previous status is kept until then
+func (c *Checker) nextStatus(prev, observed string) string {
+	switch observed {
+	case string(health.StatusUp):
+		c.successes, c.failures = c.successes+1, 0
+		if prev == observed || c.successes >= max(c.health.SuccessesBeforeUp, 1) {
+			return observed
+		}
+	case string(health.StatusDown):
+		c.failures, c.successes = c.failures+1, 0
+		if prev == observed || c.failures >= max(c.health.FailuresBeforeDown, 1) {
+			return observed
+		}
+	default:
+		c.failures, c.successes = 0, 0
+		return observed
+	}
+	return prev
+}
 
-	//result, _ := json.Marshal(res.Result)
-	//healthRecord := &Record{
-	//	ParentId: c.health.ID,
-	//	Status:   string(res.Status),
-	//	Rtt:      res.RTT,
-	//	Result:   string(result),
-	//}
-	//if err := c.service.healthRecordDb.Insert(healthRecord); err != nil {
-	//	logrus.Errorf("insert health record failed, error: %v", err)
-	//}
+// timeoutChecker reports down if check does not finish in time, for checkers without timeout
+type timeoutChecker struct {
+	checker health.Checker
+	timeout time.Duration
+}
+
+func (c *timeoutChecker) Check() *health.Health {
+	res := make(chan *health.Health, 1)
+	go func() {
+		res <- c.checker.Check()
+	}()
+	select {
+	case r := <-res:
+		return r
+	case <-time.After(c.timeout):
+		return health.NewHealth().Down().SetRTT(c.timeout.Milliseconds()).SetResult(map[string]string{"error": "timeout"})
+	}
 }
 
 func (s *Service) startChecker(health *Health) error {
 	if _, ok := s.cronJobMap.Load(health.ID); ok {
 		return errors.New("cron job already exists")
 	}
-	if jobId, err := s.cron.AddJob(getCron(health.Type), &Checker{health: health, service: s}); err != nil {
+	job := cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(&Checker{health: health, service: s})
+	if jobId, err := s.cron.AddJob(checkSpec(health), job); err != nil {
 		return err
 	} else {
 		s.cronJobMap.Store(health.ID, jobId)
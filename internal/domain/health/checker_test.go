@@ -0,0 +1,105 @@
+package health
+
+import (
+	"github.com/MR5356/health"
+	"testing"
+	"time"
+)
+
+func TestChecker_NextStatus(t *testing.T) {
+	c := &Checker{health: &Health{FailuresBeforeDown: 3, SuccessesBeforeUp: 2}}
+	status := "unknown"
+	steps := []struct {
+		observed string
+		want     string
+	}{
+		{"up", "unknown"},
+		{"up", "up"},
+		{"down", "up"},
+		{"down", "up"},
+		{"up", "up"},
+		{"down", "up"},
+		{"down", "up"},
+		{"down", "down"},
+		{"up", "down"},
+		{"down", "down"},
+		{"up", "down"},
+		{"up", "up"},
+		{"error", "error"},
+		{"up", "error"},
+	}
+	for i, step := range steps {
+		if status = c.nextStatus(status, step.observed); status != step.want {
+			t.Fatalf("step %d: expected %s after %s, got %s", i, step.want, step.observed, status)
+		}
+	}
+
+	// thresholds of 0 change status right away
+	c = &Checker{health: &Health{}}
+	if status = c.nextStatus("up", "down"); status != "down" {
+		t.Errorf("expected down, got %s", status)
+	}
+}
+
+func TestCheckSpec(t *testing.T) {
+	if spec := checkSpec(&Health{Type: "ping", Interval: 30}); spec != "@every 30s" {
+		t.Errorf("unexpected spec of interval: %s", spec)
+	}
+	if spec := checkSpec(&Health{Type: "http"}); spec != defaultHttpCron {
+		t.Errorf("unexpected default spec: %s", spec)
+	}
+	if timeout := checkTimeout(&Health{Timeout: 1500}); timeout != 1500*time.Millisecond {
+		t.Errorf("unexpected timeout: %s", timeout)
+	}
+}
+
+type sleepChecker time.Duration
+
+func (c sleepChecker) Check() *health.Health {
+	time.Sleep(time.Duration(c))
+	return health.NewHealth().Up()
+}
+
+func TestTimeoutChecker(t *testing.T) {
+	if res := (&timeoutChecker{checker: sleepChecker(time.Second), timeout: 10 * time.Millisecond}).Check(); res.Status != health.StatusDown {
+		t.Errorf("expected down of timeout, got %s", res.Status)
+	}
+	if res := (&timeoutChecker{checker: sleepChecker(0), timeout: time.Second}).Check(); res.Status != health.StatusUp {
+		t.Errorf("expected up, got %s", res.Status)
+	}
+}
+
+func TestService_HealthStatisticsSlowThreshold(t *testing.T) {
+	s := setupTest(t)
+	tests := []struct {
+		rtt       int64
+		threshold int64
+		slow      bool
+	}{
+		{300, 200, true},
+		{300, 0, false},
+		{500, 0, true},
+		{500, 1000, false},
+	}
+	healths := make([]*Health, len(tests))
+	for i, tt := range tests {
+		healths[i] = &Health{Title: "slow", Type: "ping", Params: "[]", Enabled: true, Status: "up", RTT: tt.rtt, SlowThreshold: tt.threshold}
+		if err := s.healthDb.Insert(healths[i]); err != nil {
+			t.Fatalf("insert health failed: %v", err)
+		}
+	}
+
+	statistics, err := s.HealthStatistics()
+	if err != nil {
+		t.Fatalf("get statistics failed: %v", err)
+	}
+	slow := make(map[string]bool)
+	for _, h := range statistics.SlowList {
+		slow[h.ID.String()] = true
+	}
+	for i, tt := range tests {
+		if slow[healths[i].ID.String()] != tt.slow {
+			t.Errorf("expected slow of rtt %d and threshold %d to be %v", tt.rtt, tt.threshold, tt.slow)
+		}
+	}
+}
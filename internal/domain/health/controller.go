@@ -1,12 +1,15 @@
 package health
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/MR5356/aurora/internal/response"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
 	"sync/atomic"
 	"time"
 )
@@ -160,6 +163,106 @@ func (c *Controller) handleGetTimeRangeRecord(ctx *gin.Context) {
 	}
 }
 
+// @Summary		get series of health
+// @Description	rtt percentiles and status timeline of health check, resolution is chosen by time range if it is empty
+// @Tags			health
+// @Param			id			path		string	true	"health id"
+// @Param			startTime	query		string	false	"start time, an hour ago by default"
+// @Param			endTime		query		string	false	"end time, now by default"
+// @Param			resolution	query		string	false	"raw, minute or hour"
+// @Success		200			{object}	response.Response{data=Series}
+// @Router			/health/{id}/series [get]
+// @Produce		json
+func (c *Controller) handleGetHealthSeries(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	endTime, startTime := time.Now(), time.Now().Add(-time.Hour)
+	if et := ctx.Query("endTime"); et != "" {
+		if endTime, err = time.Parse(time.RFC3339, et); err != nil {
+			response.Error(ctx, response.CodeParamsError)
+			return
+		}
+	}
+	if st := ctx.Query("startTime"); st != "" {
+		if startTime, err = time.Parse(time.RFC3339, st); err != nil {
+			response.Error(ctx, response.CodeParamsError)
+			return
+		}
+	}
+	if res, err := c.service.GetHealthSeries(id, startTime, endTime, ctx.Query("resolution")); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary		get uptime report of health
+// @Description	availability, incidents, mttr and longest outage of health check in the last 24h, 7d, 30d and calendar months
+// @Tags			health
+// @Param			id		path		string	true	"health id"
+// @Param			months	query		int		false	"calendar months in report, current month included, 3 by default"
+// @Success		200		{object}	response.Response{data=UptimeReport}
+// @Router			/health/{id}/report [get]
+// @Produce		json
+func (c *Controller) handleGetUptimeReport(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	months, _ := strconv.Atoi(ctx.Query("months"))
+	if res, err := c.service.GetUptimeReport(id, months); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeNotFound, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary		export uptime report
+// @Description	uptime report of all health checks in the last 24h, 7d, 30d and calendar month
+// @Tags			health
+// @Param			month	query		string	false	"calendar month, e.g. 2024-01, the last month by default"
+// @Param			format	query		string	false	"json or csv, json by default"
+// @Success		200		{object}	response.Response{data=[]UptimeReport}
+// @Router			/health/report/export [get]
+// @Produce		json,text/csv
+func (c *Controller) handleExportUptimeReport(ctx *gin.Context) {
+	month := time.Now().AddDate(0, 0, -time.Now().Day())
+	if m := ctx.Query("month"); m != "" {
+		var err error
+		if month, err = time.ParseInLocation("2006-01", m, time.Local); err != nil {
+			response.Error(ctx, response.CodeParamsError)
+			return
+		}
+	}
+	format := ctx.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	res, err := c.service.ExportUptimeReport(month)
+	if err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+		return
+	}
+	if format == "json" {
+		response.Success(ctx, res)
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteUptimeCSV(buf, res); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "uptime-"+month.Format("2006-01")+".csv"))
+	ctx.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
+
 // @Summary	get health check types
 // @Tags		health
 // @Success	200	{object}	response.Response{data=[]CheckType}
@@ -250,4 +353,7 @@ func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
 	api.GET("/statistics/sse", c.handleGetStatisticsWithSSE)
 
 	api.GET("/:id/record", c.handleGetTimeRangeRecord)
+	api.GET("/:id/series", c.handleGetHealthSeries)
+	api.GET("/:id/report", c.handleGetUptimeReport)
+	api.GET("/report/export", c.handleExportUptimeReport)
 }
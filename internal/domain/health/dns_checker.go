@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/MR5356/health"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNSResult is result of dns check
+type DNSResult struct {
+	Answers []string `json:"answers"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// dnsChecker resolves record of name, it is down if any of expected answers is missing
+type dnsChecker struct {
+	name       string
+	recordType string
+	expected   []string
+	resolver   *net.Resolver
+	timeout    time.Duration
+}
+
+func newDNSChecker(params Params, timeout time.Duration) (health.Checker, error) {
+	c := &dnsChecker{resolver: net.DefaultResolver, timeout: timeout}
+	var err error
+	if c.name, err = params.GetString("name", ""); err != nil || c.name == "" {
+		return nil, fmt.Errorf("%w: name is empty", ErrParam)
+	}
+	if c.recordType, err = params.GetString("recordType", "A"); err != nil {
+		return nil, fmt.Errorf("%w: recordType", ErrParam)
+	}
+	c.recordType = strings.ToUpper(c.recordType)
+	switch c.recordType {
+	case "A", "AAAA", "CNAME", "MX", "NS", "TXT":
+	default:
+		return nil, fmt.Errorf("%w: record type %s is not supported", ErrParam, c.recordType)
+	}
+
+	expected, err := params.GetString("expected", "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: expected", ErrParam)
+	}
+	for _, e := range strings.Split(expected, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			c.expected = append(c.expected, c.normalize(e))
+		}
+	}
+
+	server, err := params.GetString("server", "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: server", ErrParam)
+	}
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		c.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, server)
+			},
+		}
+	}
+	return c, nil
+}
+
+// normalize answer for comparing, names are case-insensitive and ips may be written in different forms
+func (c *dnsChecker) normalize(answer string) string {
+	if c.recordType == "TXT" {
+		return answer
+	}
+	if ip := net.ParseIP(answer); ip != nil {
+		return ip.String()
+	}
+	return strings.ToLower(strings.TrimSuffix(answer, "."))
+}
+
+func (c *dnsChecker) lookup(ctx context.Context) ([]string, error) {
+	res := make([]string, 0)
+	switch c.recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if c.recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := c.resolver.LookupIP(ctx, network, c.name)
+		for _, ip := range ips {
+			res = append(res, ip.String())
+		}
+		return res, err
+	case "CNAME":
+		cname, err := c.resolver.LookupCNAME(ctx, c.name)
+		if cname != "" {
+			res = append(res, cname)
+		}
+		return res, err
+	case "MX":
+		mxs, err := c.resolver.LookupMX(ctx, c.name)
+		for _, mx := range mxs {
+			res = append(res, mx.Host)
+		}
+		return res, err
+	case "NS":
+		nss, err := c.resolver.LookupNS(ctx, c.name)
+		for _, ns := range nss {
+			res = append(res, ns.Host)
+		}
+		return res, err
+	default:
+		return c.resolver.LookupTXT(ctx, c.name)
+	}
+}
+
+func (c *dnsChecker) Check() *health.Health {
+	res := health.NewHealth()
+	result := &DNSResult{}
+	res.SetResult(result)
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	start := time.Now()
+	answers, err := c.lookup(ctx)
+	res.SetRTT(time.Since(start).Milliseconds())
+	result.Answers = answers
+	if err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+	if len(answers) == 0 {
+		result.Error = "no answer"
+		return res.Down()
+	}
+
+	found := make(map[string]bool, len(answers))
+	for _, a := range answers {
+		found[c.normalize(a)] = true
+	}
+	for _, e := range c.expected {
+		if !found[e] {
+			result.Error = fmt.Sprintf("expected answer %s not found", e)
+			return res.Down()
+		}
+	}
+	return res.Up()
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"errors"
+	"github.com/MR5356/health"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveDNS udp dns server answering A, MX and TXT of example.test., other names do not exist
+func serveDNS(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	name := dnsmessage.MustNewName("example.test.")
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			h, err := p.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			q, err := p.Question()
+			if err != nil {
+				continue
+			}
+
+			exists := strings.EqualFold(q.Name.String(), name.String())
+			header := dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true}
+			if !exists {
+				header.RCode = dnsmessage.RCodeNameError
+			}
+			b := dnsmessage.NewBuilder(nil, header)
+			_ = b.StartQuestions()
+			_ = b.Question(q)
+			_ = b.StartAnswers()
+			if exists {
+				rh := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+				switch q.Type {
+				case dnsmessage.TypeA:
+					_ = b.AResource(rh, dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}})
+					_ = b.AResource(rh, dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}})
+				case dnsmessage.TypeMX:
+					_ = b.MXResource(rh, dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("Mail.Example.Test.")})
+				case dnsmessage.TypeTXT:
+					_ = b.TXTResource(rh, dnsmessage.TXTResource{TXT: []string{"v=spf1 -all"}})
+				}
+			}
+			msg, err := b.Finish()
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(msg, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSChecker(t *testing.T) {
+	server := serveDNS(t)
+
+	tests := []struct {
+		name   string
+		params Params
+		status health.Status
+	}{
+		{"a", params("name", "example.test", "server", server), health.StatusUp},
+		{"a expected", params("name", "example.test", "server", server, "expected", "10.0.0.2, 10.0.0.1"), health.StatusUp},
+		{"a missing", params("name", "example.test", "server", server, "expected", "10.0.0.1,10.0.0.3"), health.StatusDown},
+		{"aaaa no answer", params("name", "example.test", "server", server, "recordType", "AAAA"), health.StatusDown},
+		{"mx", params("name", "example.test", "server", server, "recordType", "mx", "expected", "mail.example.test"), health.StatusUp},
+		{"txt", params("name", "example.test", "server", server, "recordType", "TXT", "expected", "v=spf1 -all"), health.StatusUp},
+		{"not exist", params("name", "missing.test", "server", server), health.StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newDNSChecker(tt.params, time.Second)
+			if err != nil {
+				t.Fatalf("new dns checker failed: %v", err)
+			}
+			res := c.Check()
+			result := res.Result.(*DNSResult)
+			if res.Status != tt.status {
+				t.Errorf("expected %s, got %s: %v %s", tt.status, res.Status, result.Answers, result.Error)
+			}
+			if res.Status == health.StatusDown && result.Error == "" {
+				t.Errorf("expected error of down result")
+			}
+		})
+	}
+}
+
+func TestDNSChecker_InvalidParams(t *testing.T) {
+	tests := []Params{
+		params(),
+		params("name", "example.test", "recordType", "SRV"),
+	}
+	for i, ps := range tests {
+		if _, err := newDNSChecker(ps, time.Second); !errors.Is(err, ErrParam) {
+			t.Errorf("case %d: expected ErrParam, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/MR5356/health"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"time"
+)
+
+// GRPCResult is result of grpc health check
+type GRPCResult struct {
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// grpcChecker checks service with grpc health checking protocol, it is up if service is serving
+type grpcChecker struct {
+	address string
+	service string
+	creds   credentials.TransportCredentials
+	timeout time.Duration
+}
+
+func newGRPCChecker(params Params, timeout time.Duration) (health.Checker, error) {
+	c := &grpcChecker{creds: insecure.NewCredentials(), timeout: timeout}
+	var err error
+	if c.address, err = params.GetString("address", ""); err != nil || c.address == "" {
+		return nil, fmt.Errorf("%w: address is empty", ErrParam)
+	}
+	if c.service, err = params.GetString("service", ""); err != nil {
+		return nil, fmt.Errorf("%w: service", ErrParam)
+	}
+	useTLS, err := params.GetBool("tls", false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: tls", ErrParam)
+	}
+	if useTLS {
+		insecureSkipVerify, err := params.GetBool("insecureSkipVerify", false)
+		if err != nil {
+			return nil, fmt.Errorf("%w: insecureSkipVerify", ErrParam)
+		}
+		caCert, err := params.GetString("caCert", "")
+		if err != nil {
+			return nil, fmt.Errorf("%w: caCert", ErrParam)
+		}
+		tlsConfig, err := newTLSConfig(insecureSkipVerify, "", caCert, "", "")
+		if err != nil {
+			return nil, err
+		}
+		c.creds = credentials.NewTLS(tlsConfig)
+	}
+	return c, nil
+}
+
+func (c *grpcChecker) Check() *health.Health {
+	res := health.NewHealth()
+	result := &GRPCResult{}
+	res.SetResult(result)
+
+	conn, err := grpc.NewClient(c.address, grpc.WithTransportCredentials(c.creds))
+	if err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: c.service})
+	res.SetRTT(time.Since(start).Milliseconds())
+	if err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+	result.Status = resp.GetStatus().String()
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return res.Down()
+	}
+	return res.Up()
+}
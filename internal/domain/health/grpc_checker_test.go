@@ -0,0 +1,71 @@
+package health
+
+import (
+	"errors"
+	"github.com/MR5356/health"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGRPCChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	server := grpc.NewServer()
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(server, hs)
+	hs.SetServingStatus("aurora", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("worker", healthpb.HealthCheckResponse_NOT_SERVING)
+	go func() { _ = server.Serve(ln) }()
+	defer server.Stop()
+	addr := ln.Addr().String()
+
+	tests := []struct {
+		name    string
+		service string
+		status  health.Status
+		result  string
+	}{
+		{"server", "", health.StatusUp, "SERVING"},
+		{"serving", "aurora", health.StatusUp, "SERVING"},
+		{"not serving", "worker", health.StatusDown, "NOT_SERVING"},
+		{"unknown service", "missing", health.StatusDown, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newGRPCChecker(params("address", addr, "service", tt.service), time.Second)
+			if err != nil {
+				t.Fatalf("new grpc checker failed: %v", err)
+			}
+			res := c.Check()
+			result := res.Result.(*GRPCResult)
+			if res.Status != tt.status || result.Status != tt.result {
+				t.Errorf("expected %s %q, got %s %q: %s", tt.status, tt.result, res.Status, result.Status, result.Error)
+			}
+		})
+	}
+
+	server.Stop()
+	c, _ := newGRPCChecker(params("address", addr), time.Second)
+	if res := c.Check(); res.Status != health.StatusDown || res.Result.(*GRPCResult).Error == "" {
+		t.Errorf("expected down with error after server stopped, got %s", res.Status)
+	}
+}
+
+func TestGRPCChecker_InvalidParams(t *testing.T) {
+	tests := []Params{
+		params(),
+		params("address", "127.0.0.1:50051", "tls", "yes please"),
+		params("address", "127.0.0.1:50051", "tls", true, "caCert", "not pem"),
+	}
+	for i, ps := range tests {
+		if _, err := newGRPCChecker(ps, time.Second); !errors.Is(err, ErrParam) {
+			t.Errorf("case %d: expected ErrParam, got %v", i, err)
+		}
+	}
+}
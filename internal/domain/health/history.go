@@ -0,0 +1,482 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/health"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	ResolutionRaw    = "raw"
+	ResolutionMinute = "minute"
+	ResolutionHour   = "hour"
+
+	// downsampleSpec cron of downsampling and cleaning history of health checks
+	downsampleSpec = "30 * * * * *"
+
+	// rawRangeLimit and minuteRangeLimit are the longest time ranges queried with raw and minute resolution by default
+	rawRangeLimit    = 6 * time.Hour
+	minuteRangeLimit = 7 * 24 * time.Hour
+)
+
+var (
+	// rttBuckets upper bounds of buckets of rtt histogram in milliseconds, the last bucket of histogram has no upper bound
+	rttBuckets = []int64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000}
+
+	resolutionSteps = map[string]time.Duration{
+		ResolutionMinute: time.Minute,
+		ResolutionHour:   time.Hour,
+	}
+
+	// downsampleWindows time range of sources aggregated at a time
+	downsampleWindows = map[string]time.Duration{
+		ResolutionMinute: time.Hour,
+		ResolutionHour:   24 * time.Hour,
+	}
+)
+
+// GetHealthSeries get rtt percentiles and status timeline of health check in time range,
+// resolution is chosen by length and age of time range if it is empty
+func (s *Service) GetHealthSeries(id uuid.UUID, startTime, endTime time.Time, resolution string) (*Series, error) {
+	if !endTime.After(startTime) {
+		return nil, errors.New("end time must be after start time")
+	}
+	if resolution == "" {
+		resolution = autoResolution(startTime, endTime, time.Now())
+	}
+	series, _, err := s.series(id, startTime, endTime, resolution)
+	return series, err
+}
+
+// series of health check in time range with resolution, samples of the timeline are returned with it
+func (s *Service) series(id uuid.UUID, startTime, endTime time.Time, resolution string) (*Series, []*sample, error) {
+	var samples []*sample
+	var rtts []int64
+	total := newAggregate(id, resolution, startTime)
+	switch resolution {
+	case ResolutionRaw:
+		records, err := s.listRecords(id, startTime, endTime)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range records {
+			total.add(r.Status, r.Rtt)
+			if r.Status == string(health.StatusUp) {
+				rtts = append(rtts, r.Rtt)
+			}
+			samples = append(samples, &sample{status: r.Status, start: r.CreatedAt, count: 1, down: boolCount(r.Status == string(health.StatusDown))})
+		}
+	case ResolutionMinute, ResolutionHour:
+		step := resolutionSteps[resolution]
+		aggregates := make([]*RecordAggregate, 0)
+		if err := s.aggregateDb.DB.Where("parent_id = ? AND resolution = ?", id, resolution).
+			Where("bucket_time >= ? AND bucket_time < ?", startTime.Truncate(step), endTime).
+			Order("bucket_time").Find(&aggregates).Error; err != nil {
+			return nil, nil, err
+		}
+		from := startTime
+		for _, a := range aggregates {
+			total.merge(a)
+			samples = append(samples, &sample{status: a.status(), start: a.BucketTime, end: a.BucketTime.Add(step), count: a.Total, down: a.Down})
+			from = a.BucketTime.Add(step)
+		}
+
+		// results which are not downsampled yet
+		records, err := s.listRecords(id, from, endTime)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range records {
+			total.add(r.Status, r.Rtt)
+			samples = append(samples, &sample{status: r.Status, start: r.CreatedAt, count: 1, down: boolCount(r.Status == string(health.StatusDown))})
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown resolution %s", resolution)
+	}
+
+	series := &Series{
+		Resolution: resolution,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Total:      total.Total,
+		Up:         total.Up,
+		Down:       total.Down,
+		Unknown:    total.Unknown,
+		Error:      total.Error,
+		RTT:        total.rttStats(),
+		Timeline:   timeline(samples),
+	}
+	if resolution == ResolutionRaw {
+		series.RTT = exactRTTStats(rtts)
+	}
+	return series, samples, nil
+}
+
+func (s *Service) listRecords(id uuid.UUID, startTime, endTime time.Time) ([]*Record, error) {
+	res := make([]*Record, 0)
+	if err := s.healthRecordDb.DB.Where("parent_id = ?", id).Where("created_at >= ? AND created_at < ?", startTime, endTime).
+		Order("created_at").Find(&res).Error; err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// autoResolution raw records are used for short and recent time ranges, per-hour aggregates for long or old ones
+func autoResolution(startTime, endTime, now time.Time) string {
+	cfg := config.Current().Health
+	span := endTime.Sub(startTime)
+	switch {
+	case span <= rawRangeLimit && !startTime.Before(now.AddDate(0, 0, -cfg.RawRetention)):
+		return ResolutionRaw
+	case span <= minuteRangeLimit && !startTime.Before(now.AddDate(0, 0, -cfg.MinuteRetention)):
+		return ResolutionMinute
+	default:
+		return ResolutionHour
+	}
+}
+
+// sample is a raw result or an aggregate of results
+type sample struct {
+	status string
+	start  time.Time
+	end    time.Time // zero for raw result, it lasts until the next sample
+	count  int64
+	down   int64 // down results in sample
+}
+
+// endOf end of the i-th sample, raw result lasts until the next one, aggregate lasts until the raw result right after it
+func endOf(samples []*sample, i int) time.Time {
+	smp := samples[i]
+	end := smp.end
+	if i+1 < len(samples) {
+		next := samples[i+1].start
+		if end.IsZero() || (samples[i+1].end.IsZero() && next.After(end) && next.Sub(end) < end.Sub(smp.start)) {
+			end = next
+		}
+	} else if end.IsZero() {
+		end = smp.start
+	}
+	return end
+}
+
+// timeline merge consecutive samples with the same status into segments, periods without results are left as gaps
+func timeline(samples []*sample) []*Segment {
+	res := make([]*Segment, 0)
+	for i, smp := range samples {
+		end := endOf(samples, i)
+		if len(res) > 0 {
+			last := res[len(res)-1]
+			if last.Status == smp.status && !smp.start.After(last.EndTime) {
+				last.EndTime = end
+				last.Count += smp.count
+				continue
+			}
+		}
+		res = append(res, &Segment{Status: smp.status, StartTime: smp.start, EndTime: end, Count: smp.count})
+	}
+	return res
+}
+
+func newAggregate(id uuid.UUID, resolution string, bucket time.Time) *RecordAggregate {
+	return &RecordAggregate{
+		ParentId:   id,
+		Resolution: resolution,
+		BucketTime: bucket,
+		Histogram:  make(Histogram, len(rttBuckets)+1),
+	}
+}
+
+// add a raw result to aggregate
+func (a *RecordAggregate) add(status string, rtt int64) {
+	if status == string(health.StatusUp) {
+		a.merge(&RecordAggregate{Total: 1, Up: 1, RttMin: rtt, RttMax: rtt, RttSum: rtt, Histogram: histogramOf(rtt)})
+		return
+	}
+	a.merge(&RecordAggregate{Total: 1, Down: boolCount(status == string(health.StatusDown)), Error: boolCount(status == string(StatusError)),
+		Unknown: boolCount(status != string(health.StatusDown) && status != string(StatusError))})
+}
+
+// merge results of other aggregate into aggregate
+func (a *RecordAggregate) merge(o *RecordAggregate) {
+	if o.Up > 0 {
+		if a.Up == 0 || o.RttMin < a.RttMin {
+			a.RttMin = o.RttMin
+		}
+		if a.Up == 0 || o.RttMax > a.RttMax {
+			a.RttMax = o.RttMax
+		}
+		a.RttSum += o.RttSum
+	}
+	a.Total += o.Total
+	a.Up += o.Up
+	a.Down += o.Down
+	a.Unknown += o.Unknown
+	a.Error += o.Error
+	for len(a.Histogram) < len(o.Histogram) {
+		a.Histogram = append(a.Histogram, 0)
+	}
+	for i, n := range o.Histogram {
+		a.Histogram[i] += n
+	}
+}
+
+// status of aggregate is down if results are down at least as often as up
+func (a *RecordAggregate) status() string {
+	switch {
+	case a.Down > 0 && a.Down >= a.Up:
+		return string(health.StatusDown)
+	case a.Up > 0:
+		return string(health.StatusUp)
+	case a.Error > 0:
+		return string(StatusError)
+	default:
+		return string(health.StatusUnknown)
+	}
+}
+
+func (a *RecordAggregate) rttStats() RTTStats {
+	if a.Up == 0 {
+		return RTTStats{}
+	}
+	return RTTStats{
+		Count: a.Up,
+		Min:   a.RttMin,
+		Max:   a.RttMax,
+		Avg:   int64(math.Round(float64(a.RttSum) / float64(a.Up))),
+		P50:   a.Histogram.percentile(50, a.Up, a.RttMin, a.RttMax),
+		P90:   a.Histogram.percentile(90, a.Up, a.RttMin, a.RttMax),
+		P95:   a.Histogram.percentile(95, a.Up, a.RttMin, a.RttMax),
+		P99:   a.Histogram.percentile(99, a.Up, a.RttMin, a.RttMax),
+	}
+}
+
+func histogramOf(rtt int64) Histogram {
+	h := make(Histogram, len(rttBuckets)+1)
+	h[sort.Search(len(rttBuckets), func(i int) bool { return rtt <= rttBuckets[i] })]++
+	return h
+}
+
+// percentile estimate percentile by linear interpolation in the bucket it falls in
+func (h Histogram) percentile(p float64, count, min, max int64) int64 {
+	rank := p / 100 * float64(count)
+	var cum int64
+	for i, n := range h {
+		if n == 0 {
+			continue
+		}
+		if float64(cum+n) >= rank {
+			lower, upper := int64(0), max
+			if i > 0 {
+				lower = rttBuckets[i-1]
+			}
+			if i < len(rttBuckets) {
+				upper = rttBuckets[i]
+			}
+			lower, upper = clamp(lower, min, max), clamp(upper, min, max)
+			return int64(math.Round(float64(lower) + (rank-float64(cum))/float64(n)*float64(upper-lower)))
+		}
+		cum += n
+	}
+	return max
+}
+
+// exactRTTStats statistics of raw rtt, percentiles are nearest-rank
+func exactRTTStats(rtts []int64) RTTStats {
+	if len(rtts) == 0 {
+		return RTTStats{}
+	}
+	sort.Slice(rtts, func(i, j int) bool { return rtts[i] < rtts[j] })
+	var sum int64
+	for _, rtt := range rtts {
+		sum += rtt
+	}
+	percentile := func(p float64) int64 {
+		return rtts[int(math.Max(math.Ceil(p/100*float64(len(rtts))), 1))-1]
+	}
+	return RTTStats{
+		Count: int64(len(rtts)),
+		Min:   rtts[0],
+		Max:   rtts[len(rtts)-1],
+		Avg:   int64(math.Round(float64(sum) / float64(len(rtts)))),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P95:   percentile(95),
+		P99:   percentile(99),
+	}
+}
+
+func clamp(v, min, max int64) int64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func boolCount(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// downsampleHistory aggregate raw records into minutes and minutes into hours, history out of retention is dropped
+// after everything is aggregated
+func (s *Service) downsampleHistory() {
+	if !s.downsampling.CompareAndSwap(false, true) {
+		logrus.Warnf("history of health checks is downsampling, skip")
+		return
+	}
+	defer s.downsampling.Store(false)
+
+	now := time.Now()
+	if err := s.downsample(now); err != nil {
+		logrus.Errorf("downsample history of health checks failed, error: %v", err)
+		return
+	}
+	if err := s.cleanHistory(now); err != nil {
+		logrus.Errorf("clean history of health checks failed, error: %v", err)
+	}
+}
+
+func (s *Service) downsample(now time.Time) error {
+	var ids []uuid.UUID
+	if err := s.healthRecordDb.DB.Model(&Record{}).Distinct("parent_id").Pluck("parent_id", &ids).Error; err != nil {
+		return err
+	}
+	var errs []error
+	for _, id := range ids {
+		if err := s.downsampleResolution(id, ResolutionMinute, now); err != nil {
+			errs = append(errs, fmt.Errorf("downsample %s to minutes: %w", id, err))
+			continue
+		}
+		if err := s.downsampleResolution(id, ResolutionHour, now); err != nil {
+			errs = append(errs, fmt.Errorf("downsample %s to hours: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// downsampleResolution aggregate finished buckets after the last aggregated one,
+// minutes are aggregated from raw records and hours from minutes
+func (s *Service) downsampleResolution(id uuid.UUID, resolution string, now time.Time) error {
+	step := resolutionSteps[resolution]
+	end := now.Truncate(step)
+
+	from := time.Time{}
+	last := new(RecordAggregate)
+	if err := s.aggregateDb.DB.Where("parent_id = ? AND resolution = ?", id, resolution).Order("bucket_time desc").Take(last).Error; err == nil {
+		from = last.BucketTime.Add(step)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	for {
+		// skip periods without results
+		first, err := s.firstSource(id, resolution, from)
+		if err != nil {
+			return err
+		}
+		if first == nil || !first.Truncate(step).Before(end) {
+			return nil
+		}
+		from = first.Truncate(step)
+		to := from.Add(downsampleWindows[resolution])
+		if to.After(end) {
+			to = end
+		}
+
+		buckets := make(map[int64]*RecordAggregate)
+		bucketOf := func(t time.Time) *RecordAggregate {
+			bucket := t.Truncate(step).Local()
+			if _, ok := buckets[bucket.UnixNano()]; !ok {
+				buckets[bucket.UnixNano()] = newAggregate(id, resolution, bucket)
+			}
+			return buckets[bucket.UnixNano()]
+		}
+		if resolution == ResolutionMinute {
+			records, err := s.listRecords(id, from, to)
+			if err != nil {
+				return err
+			}
+			for _, r := range records {
+				bucketOf(r.CreatedAt).add(r.Status, r.Rtt)
+			}
+		} else {
+			minutes := make([]*RecordAggregate, 0)
+			if err := s.aggregateDb.DB.Where("parent_id = ? AND resolution = ?", id, ResolutionMinute).
+				Where("bucket_time >= ? AND bucket_time < ?", from, to).Find(&minutes).Error; err != nil {
+				return err
+			}
+			for _, m := range minutes {
+				bucketOf(m.BucketTime).merge(m)
+			}
+		}
+
+		aggregates := make([]*RecordAggregate, 0, len(buckets))
+		for _, a := range buckets {
+			aggregates = append(aggregates, a)
+		}
+		if len(aggregates) > 0 {
+			if err := s.aggregateDb.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&aggregates).Error; err != nil {
+				return err
+			}
+		}
+		from = to
+	}
+}
+
+// firstSource time of the first source of resolution at or after from
+func (s *Service) firstSource(id uuid.UUID, resolution string, from time.Time) (*time.Time, error) {
+	var err error
+	var res time.Time
+	if resolution == ResolutionMinute {
+		r := new(Record)
+		err = s.healthRecordDb.DB.Where("parent_id = ? AND created_at >= ?", id, from).Order("created_at").Take(r).Error
+		res = r.CreatedAt
+	} else {
+		a := new(RecordAggregate)
+		err = s.aggregateDb.DB.Where("parent_id = ? AND resolution = ? AND bucket_time >= ?", id, ResolutionMinute, from).Order("bucket_time").Take(a).Error
+		res = a.BucketTime
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// cleanHistory drop history out of retention, retention not greater than 0 keeps history forever
+func (s *Service) cleanHistory(now time.Time) error {
+	cfg := config.Current().Health
+	var errs []error
+	if cfg.RawRetention > 0 {
+		errs = append(errs, s.healthRecordDb.DB.Unscoped().Where("created_at < ?", now.AddDate(0, 0, -cfg.RawRetention)).Delete(&Record{}).Error)
+	}
+	for resolution, retention := range map[string]int{ResolutionMinute: cfg.MinuteRetention, ResolutionHour: cfg.HourRetention} {
+		if retention > 0 {
+			errs = append(errs, s.aggregateDb.DB.Where("resolution = ? AND bucket_time < ?", resolution, now.AddDate(0, 0, -retention)).Delete(&RecordAggregate{}).Error)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteHistory delete raw records and aggregates of health check
+func (s *Service) deleteHistory(id uuid.UUID) error {
+	return errors.Join(
+		s.healthRecordDb.DB.Unscoped().Where("parent_id = ?", id).Delete(&Record{}).Error,
+		s.aggregateDb.DB.Where("parent_id = ?", id).Delete(&RecordAggregate{}).Error,
+	)
+}
@@ -0,0 +1,204 @@
+package health
+
+import (
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var setupOnce sync.Once
+
+// setupTest migrate tables without starting checkers and downsampling job of Initialize
+func setupTest(t *testing.T) *Service {
+	setupOnce.Do(func() {
+		cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+		database.NewDatabase(cfg)
+		if err := database.GetDB().AutoMigrate(&Health{}, &Record{}, &RecordAggregate{}); err != nil {
+			t.Fatalf("auto migrate failed: %v", err)
+		}
+	})
+	return GetService()
+}
+
+// insertRecords insert a record with each of statuses and rtts every interval from start
+func insertRecords(t *testing.T, s *Service, id uuid.UUID, start time.Time, interval time.Duration, statuses []string, rtts []int64) {
+	for i := range statuses {
+		r := &Record{ParentId: id, Status: statuses[i], Rtt: rtts[i]}
+		r.CreatedAt = start.Add(time.Duration(i) * interval)
+		if err := s.healthRecordDb.Insert(r); err != nil {
+			t.Fatalf("insert record failed: %v", err)
+		}
+	}
+}
+
+func repeat(v string, n int) []string {
+	res := make([]string, n)
+	for i := range res {
+		res[i] = v
+	}
+	return res
+}
+
+func TestService_GetHealthSeriesRaw(t *testing.T) {
+	s := setupTest(t)
+	id := uuid.New()
+	start := time.Now().Add(-time.Hour).Truncate(time.Minute)
+
+	statuses := append(append(repeat("up", 6), repeat("down", 3)...), "up")
+	rtts := []int64{10, 20, 30, 40, 50, 60, 0, 0, 0, 100}
+	insertRecords(t, s, id, start, 10*time.Second, statuses, rtts)
+
+	series, err := s.GetHealthSeries(id, start, start.Add(time.Hour), "")
+	if err != nil {
+		t.Fatalf("get series failed: %v", err)
+	}
+	if series.Resolution != ResolutionRaw {
+		t.Errorf("expected raw resolution, got %s", series.Resolution)
+	}
+	if series.Total != 10 || series.Up != 7 || series.Down != 3 {
+		t.Errorf("unexpected counts: %+v", series)
+	}
+	want := RTTStats{Count: 7, Min: 10, Max: 100, Avg: 44, P50: 40, P90: 100, P95: 100, P99: 100}
+	if series.RTT != want {
+		t.Errorf("expected rtt %+v, got %+v", want, series.RTT)
+	}
+
+	if len(series.Timeline) != 3 {
+		t.Fatalf("expected 3 segments, got %d", len(series.Timeline))
+	}
+	down := series.Timeline[1]
+	if down.Status != "down" || down.Count != 3 || !down.StartTime.Equal(start.Add(60*time.Second)) || !down.EndTime.Equal(start.Add(90*time.Second)) {
+		t.Errorf("unexpected down segment: %+v", down)
+	}
+
+	if _, err := s.GetHealthSeries(id, start, start, ""); err == nil {
+		t.Errorf("expected error of empty time range")
+	}
+	if _, err := s.GetHealthSeries(id, start, start.Add(time.Hour), "day"); err == nil {
+		t.Errorf("expected error of unknown resolution")
+	}
+}
+
+func TestService_DownsampleHistory(t *testing.T) {
+	s := setupTest(t)
+	id := uuid.New()
+	now := time.Now().Truncate(time.Hour).Add(30 * time.Minute)
+	start := now.Add(-2*time.Hour - 30*time.Minute)
+
+	// a result every 30 seconds for two hours, down for ten minutes in the second hour
+	n := 240
+	statuses, rtts := repeat("up", n), make([]int64, n)
+	for i := range rtts {
+		rtts[i] = int64(i%10+1) * 10
+		if i >= 150 && i < 170 {
+			statuses[i], rtts[i] = "down", 0
+		}
+	}
+	insertRecords(t, s, id, start, 30*time.Second, statuses, rtts)
+
+	for i := 0; i < 2; i++ {
+		if err := s.downsample(now); err != nil {
+			t.Fatalf("downsample failed: %v", err)
+		}
+	}
+	var minutes, hours int64
+	s.aggregateDb.DB.Model(&RecordAggregate{}).Where("parent_id = ? AND resolution = ?", id, ResolutionMinute).Count(&minutes)
+	s.aggregateDb.DB.Model(&RecordAggregate{}).Where("parent_id = ? AND resolution = ?", id, ResolutionHour).Count(&hours)
+	if minutes != 120 || hours != 2 {
+		t.Errorf("expected 120 minutes and 2 hours, got %d and %d", minutes, hours)
+	}
+
+	for _, resolution := range []string{ResolutionMinute, ResolutionHour} {
+		series, err := s.GetHealthSeries(id, start, now, resolution)
+		if err != nil {
+			t.Fatalf("get %s series failed: %v", resolution, err)
+		}
+		if series.Total != int64(n) || series.Up != 220 || series.Down != 20 {
+			t.Errorf("unexpected %s counts: %+v", resolution, series)
+		}
+		if series.RTT.Min != 10 || series.RTT.Max != 100 || series.RTT.Avg != 55 || series.RTT.P50 < 40 || series.RTT.P50 > 60 {
+			t.Errorf("unexpected %s rtt: %+v", resolution, series.RTT)
+		}
+	}
+
+	series, _ := s.GetHealthSeries(id, start, now, ResolutionMinute)
+	if len(series.Timeline) != 3 || series.Timeline[1].Status != "down" ||
+		!series.Timeline[1].StartTime.Equal(start.Add(75*time.Minute)) || !series.Timeline[1].EndTime.Equal(start.Add(85*time.Minute)) {
+		t.Errorf("unexpected minute timeline: %+v", series.Timeline)
+	}
+
+	// results after the last downsampling are read from raw records
+	insertRecords(t, s, id, now.Add(-30*time.Minute), time.Minute, repeat("up", 10), make([]int64, 10))
+	if series, _ = s.GetHealthSeries(id, start, now, ResolutionHour); series.Total != int64(n)+10 {
+		t.Errorf("expected %d results, got %d", n+10, series.Total)
+	}
+
+	cfg := config.Current().Health
+	defer func() { config.Current().Health = cfg }()
+	config.Current().Health.RawRetention = 1
+	if err := s.cleanHistory(now.AddDate(0, 0, 1).Add(time.Hour)); err != nil {
+		t.Fatalf("clean history failed: %v", err)
+	}
+	var raw int64
+	s.healthRecordDb.DB.Model(&Record{}).Where("parent_id = ?", id).Count(&raw)
+	if raw != 0 {
+		t.Errorf("expected raw records cleaned, got %d", raw)
+	}
+	if series, _ = s.GetHealthSeries(id, start, now, ResolutionMinute); series.Total != int64(n) {
+		t.Errorf("expected %d results in aggregates, got %d", n, series.Total)
+	}
+
+	if err := s.deleteHistory(id); err != nil {
+		t.Fatalf("delete history failed: %v", err)
+	}
+	s.aggregateDb.DB.Model(&RecordAggregate{}).Where("parent_id = ?", id).Count(&minutes)
+	if minutes != 0 {
+		t.Errorf("expected aggregates deleted, got %d", minutes)
+	}
+}
+
+func TestChecker_Record(t *testing.T) {
+	s := setupTest(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	h := &Health{Title: "record", Type: "http", Enabled: true, Params: `[{"key":"url","value":"` + server.URL + `"}]`}
+	if err := s.healthDb.Insert(h); err != nil {
+		t.Fatalf("insert health failed: %v", err)
+	}
+	(&Checker{health: h, service: s}).Run()
+
+	records, err := s.GetTimeRangeRecord(h.ID, time.Now().Add(-time.Minute), time.Now())
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d, error: %v", len(records), err)
+	}
+	if records[0].Status != "up" {
+		t.Errorf("expected up record, got %s", records[0].Status)
+	}
+}
+
+func TestHistogram_Percentile(t *testing.T) {
+	a := newAggregate(uuid.New(), ResolutionMinute, time.Now())
+	for _, rtt := range []int64{3, 4, 30, 40, 300, 20000} {
+		a.add("up", rtt)
+	}
+	h := a.Histogram
+	tests := []struct {
+		p    float64
+		want int64
+	}{
+		{0, 3},
+		{50, 35},
+		{100, 20000},
+	}
+	for _, tt := range tests {
+		if got := h.percentile(tt.p, 6, 3, 20000); got != tt.want {
+			t.Errorf("percentile(%v) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+}
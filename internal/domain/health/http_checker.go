@@ -0,0 +1,310 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/MR5356/health"
+	"io"
+	"k8s.io/client-go/util/jsonpath"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultExpectedStatus = "200-299"
+	defaultMaxRedirects   = 10
+	defaultCertExpiryDays = 14
+
+	// maxBodySize most bytes of response body read for assertions
+	maxBodySize = 1 << 20
+)
+
+// warner is result of check with a warning, e.g. certificate expires soon
+type warner interface {
+	warning() string
+}
+
+// HTTPResult is result of http check
+type HTTPResult struct {
+	Code          int        `json:"code"`
+	Error         string     `json:"error,omitempty"`
+	CertExpiresAt *time.Time `json:"certExpiresAt,omitempty"`
+	CertDaysLeft  *int       `json:"certDaysLeft,omitempty"`
+	Warning       string     `json:"warning,omitempty"`
+}
+
+func (r *HTTPResult) warning() string {
+	return r.Warning
+}
+
+type statusRange struct {
+	min, max int
+}
+
+// httpChecker sends request and checks status code and body of response
+type httpChecker struct {
+	url     string
+	method  string
+	headers http.Header
+	body    string
+	timeout time.Duration
+
+	followRedirects bool
+	maxRedirects    int
+	tlsConfig       *tls.Config
+	certExpiryDays  int
+
+	expectedStatus []statusRange
+	bodyContains   string
+	bodyRegex      *regexp.Regexp
+	jsonPath       *jsonpath.JSONPath
+	jsonPathExpr   string
+	jsonPathValue  string
+}
+
+// newHTTPChecker parse params of http check, ErrParam is returned if any of them is invalid
+func newHTTPChecker(params Params, timeout time.Duration) (*httpChecker, error) {
+	c := &httpChecker{headers: make(http.Header), timeout: timeout}
+	var err error
+	str := func(key, def string) string {
+		var v string
+		if err == nil {
+			if v, err = params.GetString(key, def); err != nil {
+				err = fmt.Errorf("%w: %s", ErrParam, key)
+			}
+		}
+		return v
+	}
+
+	c.url = str("url", "")
+	c.method = strings.ToUpper(str("method", http.MethodGet))
+	c.body = str("body", "")
+	headers := str("headers", "")
+	expectedStatus := str("expectedStatus", defaultExpectedStatus)
+	c.bodyContains = str("bodyContains", "")
+	bodyRegex := str("bodyRegex", "")
+	c.jsonPathExpr = str("jsonPath", "")
+	c.jsonPathValue = str("jsonPathValue", "")
+	caCert, clientCert, clientKey := str("caCert", ""), str("clientCert", ""), str("clientKey", "")
+	serverName := str("serverName", "")
+	if err != nil {
+		return nil, err
+	}
+
+	if c.url == "" {
+		return nil, fmt.Errorf("%w: url is empty", ErrParam)
+	}
+	if !strings.HasPrefix(c.url, "https://") && !strings.HasPrefix(c.url, "http://") {
+		c.url = "http://" + c.url
+	}
+	for _, line := range strings.Split(headers, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: header %q is not key: value", ErrParam, line)
+		}
+		c.headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	if c.expectedStatus, err = parseStatusRanges(expectedStatus); err != nil {
+		return nil, err
+	}
+	if bodyRegex != "" {
+		if c.bodyRegex, err = regexp.Compile(bodyRegex); err != nil {
+			return nil, fmt.Errorf("%w: bodyRegex %v", ErrParam, err)
+		}
+	}
+	if c.jsonPathExpr != "" {
+		expr := c.jsonPathExpr
+		if !strings.HasPrefix(expr, "{") {
+			expr = "{" + expr + "}"
+		}
+		c.jsonPath = jsonpath.New("http")
+		if err = c.jsonPath.Parse(expr); err != nil {
+			return nil, fmt.Errorf("%w: jsonPath %v", ErrParam, err)
+		}
+	}
+
+	if c.followRedirects, err = params.GetBool("followRedirects", true); err != nil {
+		return nil, fmt.Errorf("%w: followRedirects", ErrParam)
+	}
+	if c.maxRedirects, err = params.GetInt("maxRedirects", defaultMaxRedirects); err != nil {
+		return nil, fmt.Errorf("%w: maxRedirects", ErrParam)
+	}
+	if c.certExpiryDays, err = params.GetInt("certExpiryDays", defaultCertExpiryDays); err != nil {
+		return nil, fmt.Errorf("%w: certExpiryDays", ErrParam)
+	}
+	insecure, err := params.GetBool("insecureSkipVerify", false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: insecureSkipVerify", ErrParam)
+	}
+	if c.tlsConfig, err = newTLSConfig(insecure, serverName, caCert, clientCert, clientKey); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newTLSConfig tls config with custom root certificate and client certificate of mTLS, both in PEM
+func newTLSConfig(insecure bool, serverName, caCert, clientCert, clientKey string) (*tls.Config, error) {
+	res := &tls.Config{InsecureSkipVerify: insecure, ServerName: serverName}
+	if caCert != "" {
+		res.RootCAs = x509.NewCertPool()
+		if !res.RootCAs.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, fmt.Errorf("%w: caCert is not a PEM certificate", ErrParam)
+		}
+	}
+	if clientCert != "" || clientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, fmt.Errorf("%w: client certificate %v", ErrParam, err)
+		}
+		res.Certificates = []tls.Certificate{cert}
+	}
+	return res, nil
+}
+
+// parseStatusRanges parse status codes and ranges like 200,201,300-399
+func parseStatusRanges(str string) ([]statusRange, error) {
+	res := make([]statusRange, 0)
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(lo))
+		to := from
+		if err == nil && isRange {
+			to, err = strconv.Atoi(strings.TrimSpace(hi))
+		}
+		if err != nil || from > to {
+			return nil, fmt.Errorf("%w: expected status %q", ErrParam, part)
+		}
+		res = append(res, statusRange{min: from, max: to})
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("%w: expected status is empty", ErrParam)
+	}
+	return res, nil
+}
+
+func (c *httpChecker) Check() *health.Health {
+	res := health.NewHealth()
+	result := &HTTPResult{}
+	res.SetResult(result)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c.tlsConfig
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !c.followRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= c.maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", c.maxRedirects)
+			}
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, c.method, c.url, strings.NewReader(c.body))
+	if err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+	req.Header = c.headers.Clone()
+	if host := c.headers.Get("Host"); host != "" {
+		req.Host = host
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.SetRTT(time.Since(start).Milliseconds())
+		result.Error = err.Error()
+		return res.Down()
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	res.SetRTT(time.Since(start).Milliseconds())
+	result.Code = resp.StatusCode
+	if err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		result.CertExpiresAt, result.CertDaysLeft, result.Warning = certExpiry(resp.TLS.PeerCertificates[0], c.certExpiryDays)
+	}
+
+	if err := c.assert(resp.StatusCode, body); err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+	return res.Up()
+}
+
+// certExpiry expiry of certificate, with a warning if it expires in warnDays days
+func certExpiry(cert *x509.Certificate, warnDays int) (*time.Time, *int, string) {
+	notAfter := cert.NotAfter
+	daysLeft := int(time.Until(notAfter).Hours() / 24)
+	warning := ""
+	if warnDays > 0 && daysLeft < warnDays {
+		warning = fmt.Sprintf("certificate of %s expires in %d days at %s", cert.Subject.CommonName, daysLeft, notAfter.Format(time.RFC3339))
+	}
+	return &notAfter, &daysLeft, warning
+}
+
+// assert check status code and body of response
+func (c *httpChecker) assert(code int, body []byte) error {
+	expected := false
+	for _, r := range c.expectedStatus {
+		if code >= r.min && code <= r.max {
+			expected = true
+			break
+		}
+	}
+	if !expected {
+		return fmt.Errorf("unexpected status code %d", code)
+	}
+	if c.bodyContains != "" && !bytes.Contains(body, []byte(c.bodyContains)) {
+		return fmt.Errorf("body does not contain %q", c.bodyContains)
+	}
+	if c.bodyRegex != nil && !c.bodyRegex.Match(body) {
+		return fmt.Errorf("body does not match %q", c.bodyRegex.String())
+	}
+	if c.jsonPath != nil {
+		var data any
+		if err := json.Unmarshal(body, &data); err != nil {
+			return fmt.Errorf("body is not json: %v", err)
+		}
+		results, err := c.jsonPath.FindResults(data)
+		if err != nil {
+			return fmt.Errorf("json path %s: %v", c.jsonPathExpr, err)
+		}
+		if len(results) == 0 || len(results[0]) == 0 {
+			return fmt.Errorf("json path %s not found", c.jsonPathExpr)
+		}
+		if c.jsonPathValue != "" {
+			for _, v := range results[0] {
+				if fmt.Sprint(v.Interface()) == c.jsonPathValue {
+					return nil
+				}
+			}
+			return fmt.Errorf("json path %s is %v, expected %s", c.jsonPathExpr, results[0][0].Interface(), c.jsonPathValue)
+		}
+	}
+	return nil
+}
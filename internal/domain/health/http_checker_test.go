@@ -0,0 +1,208 @@
+package health
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/MR5356/health"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func params(kv ...any) Params {
+	res := make(Params, 0)
+	for i := 0; i+1 < len(kv); i += 2 {
+		res = append(res, Param{Key: kv[i].(string), Value: kv[i+1]})
+	}
+	return res
+}
+
+func checkHTTP(t *testing.T, ps Params) (*health.Health, *HTTPResult) {
+	t.Helper()
+	c, err := newHTTPChecker(ps, time.Second)
+	if err != nil {
+		t.Fatalf("new http checker failed: %v", err)
+	}
+	res := c.Check()
+	return res, res.Result.(*HTTPResult)
+}
+
+func TestHTTPChecker_Assertions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || r.Header.Get("X-Token") != "secret" || string(body) != "ping" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case "/redirect":
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(`{"status":"error","data":{"ok":false,"items":[1,2]}}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		params Params
+		status health.Status
+		code   int
+	}{
+		{"default", params("url", server.URL), health.StatusUp, 200},
+		{"url without scheme", params("url", server.Listener.Addr().String()), health.StatusUp, 200},
+		{"unexpected status", params("url", server.URL, "expectedStatus", "500-599"), health.StatusDown, 200},
+		{"status list", params("url", server.URL, "expectedStatus", "201, 200"), health.StatusUp, 200},
+		{"body contains", params("url", server.URL, "bodyContains", `"ok":false`), health.StatusUp, 200},
+		{"body not contains", params("url", server.URL, "bodyContains", `"ok":true`), health.StatusDown, 200},
+		{"body regex", params("url", server.URL, "bodyRegex", `"status":\s*"error"`), health.StatusUp, 200},
+		{"body not match", params("url", server.URL, "bodyRegex", `^ok$`), health.StatusDown, 200},
+		{"json path", params("url", server.URL, "jsonPath", "$.data.ok"), health.StatusUp, 200},
+		{"json path value", params("url", server.URL, "jsonPath", "{.status}", "jsonPathValue", "ok"), health.StatusDown, 200},
+		{"json path number", params("url", server.URL, "jsonPath", ".data.items[1]", "jsonPathValue", "2"), health.StatusUp, 200},
+		{"json path missing", params("url", server.URL, "jsonPath", "$.data.missing"), health.StatusDown, 200},
+		{"method headers body", params("url", server.URL+"/echo", "method", "post", "headers", "X-Token: secret\nX-Other: 1", "body", "ping", "expectedStatus", "204"), health.StatusUp, 204},
+		{"bad request", params("url", server.URL+"/echo"), health.StatusDown, 400},
+		{"follow redirects", params("url", server.URL+"/redirect"), health.StatusUp, 200},
+		{"no redirects", params("url", server.URL+"/redirect", "followRedirects", false, "expectedStatus", "302"), health.StatusUp, 302},
+		{"redirect loop", params("url", server.URL+"/loop", "maxRedirects", 3), health.StatusDown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, result := checkHTTP(t, tt.params)
+			if res.Status != tt.status || result.Code != tt.code {
+				t.Errorf("expected %s with %d, got %s with %d: %s", tt.status, tt.code, res.Status, result.Code, result.Error)
+			}
+		})
+	}
+}
+
+func TestHTTPChecker_InvalidParams(t *testing.T) {
+	tests := []Params{
+		params(),
+		params("url", "http://localhost", "expectedStatus", "abc"),
+		params("url", "http://localhost", "expectedStatus", "299-200"),
+		params("url", "http://localhost", "headers", "no colon"),
+		params("url", "http://localhost", "bodyRegex", "("),
+		params("url", "http://localhost", "jsonPath", "{.a"),
+		params("url", "http://localhost", "maxRedirects", "many"),
+		params("url", "http://localhost", "caCert", "not pem"),
+		params("url", "http://localhost", "clientCert", "not pem", "clientKey", "not pem"),
+	}
+	for i, ps := range tests {
+		if _, err := newHTTPChecker(ps, time.Second); !errors.Is(err, ErrParam) {
+			t.Errorf("case %d: expected ErrParam, got %v", i, err)
+		}
+	}
+}
+
+// newClientCert self-signed certificate and key of client in PEM
+func newClientCert(t *testing.T) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate failed: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestHTTPChecker_TLS(t *testing.T) {
+	clientCert, certPEM, keyPEM := newClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.VerifyClientCertIfGiven, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	mtls := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	mtls.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	mtls.StartTLS()
+	defer mtls.Close()
+
+	if res, result := checkHTTP(t, params("url", server.URL)); res.Status != health.StatusDown {
+		t.Errorf("expected down of unknown authority, got %s", res.Status)
+	} else if result.Error == "" {
+		t.Errorf("expected error of unknown authority")
+	}
+
+	res, result := checkHTTP(t, params("url", server.URL, "caCert", caPEM))
+	if res.Status != health.StatusUp {
+		t.Fatalf("expected up with ca certificate, got %s: %s", res.Status, result.Error)
+	}
+	if result.CertExpiresAt == nil || !result.CertExpiresAt.Equal(server.Certificate().NotAfter) || *result.CertDaysLeft <= 0 {
+		t.Errorf("unexpected certificate expiry: %v %v", result.CertExpiresAt, result.CertDaysLeft)
+	}
+	if result.Warning != "" {
+		t.Errorf("unexpected warning: %s", result.Warning)
+	}
+
+	// certificate of httptest expires in decades
+	if _, result = checkHTTP(t, params("url", server.URL, "insecureSkipVerify", true, "certExpiryDays", 365*100)); result.Warning == "" {
+		t.Errorf("expected warning of certificate expiry")
+	}
+
+	if res, _ = checkHTTP(t, params("url", mtls.URL, "insecureSkipVerify", true)); res.Status != health.StatusDown {
+		t.Errorf("expected down without client certificate, got %s", res.Status)
+	}
+	if res, result = checkHTTP(t, params("url", mtls.URL, "insecureSkipVerify", true, "clientCert", certPEM, "clientKey", keyPEM)); res.Status != health.StatusUp {
+		t.Errorf("expected up with client certificate, got %s: %s", res.Status, result.Error)
+	}
+}
+
+func TestChecker_HTTPWarning(t *testing.T) {
+	s := setupTest(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	h := &Health{Title: "warning", Type: "http", Enabled: true,
+		Params: `[{"key":"url","value":"` + server.URL + `"},{"key":"insecureSkipVerify","value":true},{"key":"certExpiryDays","value":36500}]`}
+	if err := s.healthDb.Insert(h); err != nil {
+		t.Fatalf("insert health failed: %v", err)
+	}
+	(&Checker{health: h, service: s}).Run()
+
+	res, err := s.DetailHealth(h.ID)
+	if err != nil {
+		t.Fatalf("detail health failed: %v", err)
+	}
+	if res.Status != "up" || res.Warning == "" {
+		t.Errorf("expected up with warning, got %s %q", res.Status, res.Warning)
+	}
+	statistics, _ := s.HealthStatistics()
+	found := false
+	for _, w := range statistics.WarningList {
+		found = found || w.ID == h.ID
+	}
+	if !found {
+		t.Errorf("expected health in warning list")
+	}
+}
@@ -1,20 +1,32 @@
 package health
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"github.com/MR5356/aurora/internal/infrastructure/database"
 	"github.com/google/uuid"
+	"github.com/spf13/cast"
 	"gorm.io/gorm"
+	"time"
 )
 
 type Health struct {
 	ID      uuid.UUID `json:"id" gorm:"type:uuid;primaryKey" swaggerignore:"true" example:"00000000-0000-0000-0000-000000000000"`
 	Title   string    `json:"title" gorm:"not null" validate:"required"`
 	Desc    string    `json:"desc"`
-	Type    string    `json:"type" gorm:"length:32" validate:"oneof=ping ssh http database"`
+	Type    string    `json:"type" gorm:"length:32" validate:"oneof=ping ssh http database tcp dns grpc tls"`
 	Enabled bool      `json:"enabled"`
 	Params  string    `json:"params" validate:"required"`
-	Status  string    `json:"status"` // last result
-	RTT     int64     `json:"rtt"`    // last result
+	Status  string    `json:"status"`  // last result
+	RTT     int64     `json:"rtt"`     // last result
+	Warning string    `json:"warning"` // warning of last result, e.g. certificate expires soon
+
+	Interval int64 `json:"interval" validate:"gte=0" example:"10"`  // seconds between checks, 0 means default interval of type
+	Timeout  int64 `json:"timeout" validate:"gte=0" example:"5000"` // milliseconds, 0 means 5 seconds
+	// FailuresBeforeDown and SuccessesBeforeUp consecutive results needed to change status, 0 means 1
+	FailuresBeforeDown int   `json:"failuresBeforeDown" validate:"gte=0" example:"3"`
+	SuccessesBeforeUp  int   `json:"successesBeforeUp" validate:"gte=0" example:"1"`
+	SlowThreshold      int64 `json:"slowThreshold" validate:"gte=0" example:"460"` // milliseconds of rtt a up health is slow over, 0 means 460
 
 	database.BaseModel
 }
@@ -29,8 +41,14 @@ type Statistics struct {
 	SSH       int64                 `json:"ssh"`
 	HTTP      int64                 `json:"http"`
 	Database  int64                 `json:"database"`
+	TCP       int64                 `json:"tcp"`
+	DNS       int64                 `json:"dns"`
+	GRPC      int64                 `json:"grpc"`
+	TLS       int64                 `json:"tls"`
 	ErrorList []*HealthListResponse `json:"errorList"`
-	SlowList  []*HealthListResponse `json:"slowList"` // rtt > 460
+	SlowList  []*HealthListResponse `json:"slowList"` // rtt over slow threshold of health
+
+	WarningList []*HealthListResponse `json:"warningList"` // e.g. certificate expires soon
 }
 
 type Count struct {
@@ -45,10 +63,11 @@ type HealthListResponse struct {
 	ID      uuid.UUID `json:"id" gorm:"type:uuid;primaryKey" swaggerignore:"true" example:"00000000-0000-0000-0000-000000000000"`
 	Title   string    `json:"title" gorm:"not null" validate:"required"`
 	Desc    string    `json:"desc"`
-	Type    string    `json:"type" gorm:"length:32" validate:"oneof=ping ssh http database"`
+	Type    string    `json:"type" gorm:"length:32" validate:"oneof=ping ssh http database tcp dns grpc tls"`
 	Enabled bool      `json:"enabled"`
-	Status  string    `json:"status"` // last result
-	RTT     int64     `json:"rtt"`    // last result
+	Status  string    `json:"status"`  // last result
+	RTT     int64     `json:"rtt"`     // last result
+	Warning string    `json:"warning"` // last result
 }
 
 func (h *Health) TableName() string {
@@ -89,11 +108,38 @@ func (ps *Params) GetKey(key string) any {
 	return ""
 }
 
+// GetString value of key as string, def is returned if it is empty
+func (ps *Params) GetString(key, def string) (string, error) {
+	v := ps.GetKey(key)
+	if v == nil || v == "" {
+		return def, nil
+	}
+	return cast.ToStringE(v)
+}
+
+// GetInt value of key as int, def is returned if it is empty
+func (ps *Params) GetInt(key string, def int) (int, error) {
+	v := ps.GetKey(key)
+	if v == nil || v == "" {
+		return def, nil
+	}
+	return cast.ToIntE(v)
+}
+
+// GetBool value of key as bool, def is returned if it is empty
+func (ps *Params) GetBool(key string, def bool) (bool, error) {
+	v := ps.GetKey(key)
+	if v == nil || v == "" {
+		return def, nil
+	}
+	return cast.ToBoolE(v)
+}
+
 type Record struct {
 	ID       uuid.UUID `json:"id" gorm:"type:uuid;primaryKey" swaggerignore:"true" example:"00000000-0000-0000-0000-000000000000"`
-	ParentId uuid.UUID `json:"parentId" gorm:"type:uuid;" swaggerignore:"true" example:"00000000-0000-0000-0000-000000000000"`
+	ParentId uuid.UUID `json:"parentId" gorm:"type:uuid;index" swaggerignore:"true" example:"00000000-0000-0000-0000-000000000000"`
 
-	Status string `json:"status"`
+	Status string `json:"status"` // result of check, status of health may not follow it before thresholds are reached
 	Rtt    int64  `json:"rtt"`
 	Result string `json:"result"` // json string
 
@@ -110,3 +156,108 @@ func (h *Record) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// RecordAggregate is results of a health check in a minute or an hour, downsampled from raw records
+type RecordAggregate struct {
+	ParentId   uuid.UUID `json:"parentId" gorm:"type:uuid;primaryKey" example:"00000000-0000-0000-0000-000000000000"`
+	Resolution string    `json:"resolution" gorm:"size:8;primaryKey" example:"minute"`
+	BucketTime time.Time `json:"bucketTime" gorm:"primaryKey"` // start of minute or hour
+
+	Total   int64 `json:"total"`
+	Up      int64 `json:"up"`
+	Down    int64 `json:"down"`
+	Unknown int64 `json:"unknown"`
+	Error   int64 `json:"error"`
+
+	// rtt of up results
+	RttMin    int64     `json:"rttMin"`
+	RttMax    int64     `json:"rttMax"`
+	RttSum    int64     `json:"rttSum"`
+	Histogram Histogram `json:"histogram" gorm:"type:text"` // count of rtt of up results in each of rttBuckets
+}
+
+func (a *RecordAggregate) TableName() string {
+	return "health_check_record_aggregate"
+}
+
+type Histogram []int64
+
+func (h *Histogram) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	case []byte:
+		return json.Unmarshal(v, h)
+	default:
+		return nil
+	}
+}
+
+func (h Histogram) Value() (driver.Value, error) {
+	s, err := json.Marshal(h)
+	return string(s), err
+}
+
+// Series is history of a health check in a time range
+type Series struct {
+	Resolution string    `json:"resolution" example:"minute"` // raw, minute or hour
+	StartTime  time.Time `json:"startTime"`
+	EndTime    time.Time `json:"endTime"`
+
+	Total   int64 `json:"total"`
+	Up      int64 `json:"up"`
+	Down    int64 `json:"down"`
+	Unknown int64 `json:"unknown"`
+	Error   int64 `json:"error"`
+
+	RTT      RTTStats   `json:"rtt"`
+	Timeline []*Segment `json:"timeline"`
+}
+
+// RTTStats statistics of rtt of up results in milliseconds,
+// percentiles are exact with raw resolution and estimated from histograms otherwise
+type RTTStats struct {
+	Count int64 `json:"count"`
+	Min   int64 `json:"min"`
+	Max   int64 `json:"max"`
+	Avg   int64 `json:"avg"`
+	P50   int64 `json:"p50"`
+	P90   int64 `json:"p90"`
+	P95   int64 `json:"p95"`
+	P99   int64 `json:"p99"`
+}
+
+// Segment is a period in which a health check kept the same status
+type Segment struct {
+	Status    string    `json:"status" example:"up"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+	Count     int64     `json:"count"` // results in segment
+}
+
+// UptimeReport is availability of a health check in rolling windows and calendar months
+type UptimeReport struct {
+	ID      uuid.UUID       `json:"id" example:"00000000-0000-0000-0000-000000000000"`
+	Title   string          `json:"title"`
+	Type    string          `json:"type"`
+	Windows []*Availability `json:"windows"` // last 24h, 7d and 30d
+	Months  []*Availability `json:"months"`
+}
+
+// Availability is availability of a health check in a period, durations are in seconds
+type Availability struct {
+	Period     string    `json:"period" example:"2024-01"`
+	Resolution string    `json:"resolution" example:"minute"` // resolution of history incidents are found in
+	StartTime  time.Time `json:"startTime"`
+	EndTime    time.Time `json:"endTime"`
+
+	Total        int64    `json:"total"`
+	Up           int64    `json:"up"`
+	Down         int64    `json:"down"`
+	Availability *float64 `json:"availability" example:"99.95"` // percentage, null if there is no up or down result
+
+	Incidents     int64 `json:"incidents"`
+	MTTR          int64 `json:"mttr"` // mean time to recovery
+	LongestOutage int64 `json:"longestOutage"`
+	Downtime      int64 `json:"downtime"`
+}
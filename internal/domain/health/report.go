@@ -0,0 +1,202 @@
+package health
+
+import (
+	"encoding/csv"
+	"errors"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/health"
+	"github.com/google/uuid"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// maxReportMonths most calendar months in a report
+const maxReportMonths = 12
+
+var reportWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// GetUptimeReport availability of health check in rolling windows and the last months calendar months, current month included
+func (s *Service) GetUptimeReport(id uuid.UUID, months int) (*UptimeReport, error) {
+	if id == uuid.Nil {
+		return nil, errors.New("health not found")
+	}
+	h, err := s.DetailHealth(id)
+	if err != nil {
+		return nil, err
+	}
+	if months < 1 || months > maxReportMonths {
+		months = 3
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	periods := make([]time.Time, months)
+	for i := range periods {
+		periods[i] = start.AddDate(0, -i, 0)
+	}
+	return s.uptimeReport(h, periods, now)
+}
+
+// ExportUptimeReport availability of all health checks in rolling windows and calendar month
+func (s *Service) ExportUptimeReport(month time.Time) ([]*UptimeReport, error) {
+	healths, err := s.healthDb.List(&Health{})
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, now.Location())
+	res := make([]*UptimeReport, 0, len(healths))
+	for _, h := range healths {
+		report, err := s.uptimeReport(h, []time.Time{month}, now)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, report)
+	}
+	return res, nil
+}
+
+func (s *Service) uptimeReport(h *Health, months []time.Time, now time.Time) (*UptimeReport, error) {
+	report := &UptimeReport{
+		ID:      h.ID,
+		Title:   h.Title,
+		Type:    h.Type,
+		Windows: make([]*Availability, 0, len(reportWindows)),
+		Months:  make([]*Availability, 0, len(months)),
+	}
+	for _, w := range reportWindows {
+		a, err := s.availability(h, w.name, now.Add(-w.duration), now, now)
+		if err != nil {
+			return nil, err
+		}
+		report.Windows = append(report.Windows, a)
+	}
+	for _, m := range months {
+		end := m.AddDate(0, 1, 0)
+		if end.After(now) {
+			end = now
+		}
+		if !end.After(m) {
+			continue
+		}
+		a, err := s.availability(h, m.Format("2006-01"), m, end, now)
+		if err != nil {
+			return nil, err
+		}
+		report.Months = append(report.Months, a)
+	}
+	return report, nil
+}
+
+// availability of health check in period, availability is percentage of up results in up and down results,
+// incidents are found from down results, so they are as precise as resolution of history
+func (s *Service) availability(h *Health, period string, startTime, endTime, now time.Time) (*Availability, error) {
+	resolution := ResolutionHour
+	if !startTime.Before(now.AddDate(0, 0, -config.Current().Health.MinuteRetention)) {
+		resolution = ResolutionMinute
+	}
+	series, samples, err := s.series(h.ID, startTime, endTime, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Availability{
+		Period:     period,
+		Resolution: resolution,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Total:      series.Total,
+		Up:         series.Up,
+		Down:       series.Down,
+	}
+	if series.Up+series.Down > 0 {
+		v := math.Round(float64(series.Up)/float64(series.Up+series.Down)*100*1000) / 1000
+		res.Availability = &v
+	}
+
+	// outage which is not recovered yet lasts until now if check is still running
+	ongoing := h.Enabled && !endTime.Before(now) && len(samples) > 0 && samples[len(samples)-1].status == string(health.StatusDown)
+	found := outages(samples)
+	var downtime time.Duration
+	for i, o := range found {
+		d := o.downtime
+		if ongoing && i == len(found)-1 {
+			d += now.Sub(o.end)
+		}
+		res.Incidents++
+		downtime += d
+		if int64(d.Seconds()) > res.LongestOutage {
+			res.LongestOutage = int64(d.Seconds())
+		}
+	}
+	res.Downtime = int64(downtime.Seconds())
+	if res.Incidents > 0 {
+		res.MTTR = int64((downtime / time.Duration(res.Incidents)).Seconds())
+	}
+	return res, nil
+}
+
+// outage is consecutive samples with down results
+type outage struct {
+	end      time.Time
+	downtime time.Duration
+}
+
+// outages find outages in samples, an aggregate with any down result is part of an outage even if it is mostly up,
+// and it is down for share of its down results
+func outages(samples []*sample) []*outage {
+	res := make([]*outage, 0)
+	var last *outage
+	for i, smp := range samples {
+		if smp.down == 0 {
+			last = nil
+			continue
+		}
+		end := endOf(samples, i)
+		d := time.Duration(float64(end.Sub(smp.start)) * float64(smp.down) / float64(smp.count))
+		if last != nil && !smp.start.After(last.end) {
+			last.end = end
+			last.downtime += d
+			continue
+		}
+		last = &outage{end: end, downtime: d}
+		res = append(res, last)
+	}
+	return res
+}
+
+// WriteUptimeCSV write reports as csv, a row for each period of each health check
+func WriteUptimeCSV(w io.Writer, reports []*UptimeReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "title", "type", "period", "startTime", "endTime", "total", "up", "down",
+		"availability", "incidents", "mttr", "longestOutage", "downtime"}); err != nil {
+		return err
+	}
+	for _, r := range reports {
+		for _, a := range append(append([]*Availability{}, r.Windows...), r.Months...) {
+			availability := ""
+			if a.Availability != nil {
+				availability = strconv.FormatFloat(*a.Availability, 'f', -1, 64)
+			}
+			if err := cw.Write([]string{
+				r.ID.String(), r.Title, r.Type, a.Period, a.StartTime.Format(time.RFC3339), a.EndTime.Format(time.RFC3339),
+				strconv.FormatInt(a.Total, 10), strconv.FormatInt(a.Up, 10), strconv.FormatInt(a.Down, 10), availability,
+				strconv.FormatInt(a.Incidents, 10), strconv.FormatInt(a.MTTR, 10), strconv.FormatInt(a.LongestOutage, 10),
+				strconv.FormatInt(a.Downtime, 10),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"encoding/csv"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestService_GetUptimeReport(t *testing.T) {
+	s := setupTest(t)
+	h := &Health{Title: "report", Type: "http", Params: "[]", Enabled: true}
+	if err := s.healthDb.Insert(h); err != nil {
+		t.Fatalf("insert health failed: %v", err)
+	}
+
+	// a result every minute in the last hour, down for 5 minutes and then for 10 minutes
+	statuses := repeat("up", 60)
+	for i := 10; i < 15; i++ {
+		statuses[i] = "down"
+	}
+	for i := 30; i < 40; i++ {
+		statuses[i] = "down"
+	}
+	insertRecords(t, s, h.ID, time.Now().Add(-time.Hour), time.Minute, statuses, make([]int64, 60))
+
+	report, err := s.GetUptimeReport(h.ID, 2)
+	if err != nil {
+		t.Fatalf("get report failed: %v", err)
+	}
+	if len(report.Windows) != 3 || len(report.Months) != 2 {
+		t.Fatalf("expected 3 windows and 2 months, got %d and %d", len(report.Windows), len(report.Months))
+	}
+	for _, a := range report.Windows {
+		if a.Total != 60 || a.Availability == nil || *a.Availability != 75 {
+			t.Errorf("unexpected availability of %s: %+v", a.Period, a)
+		}
+		if a.Incidents != 2 || a.MTTR != 450 || a.LongestOutage != 600 || a.Downtime != 900 {
+			t.Errorf("unexpected incidents of %s: %+v", a.Period, a)
+		}
+	}
+	if report.Months[1].Period != time.Now().AddDate(0, 0, -time.Now().Day()).Format("2006-01") {
+		t.Errorf("unexpected period of last month: %s", report.Months[1].Period)
+	}
+
+	// outage which is not recovered yet lasts until now
+	insertRecords(t, s, h.ID, time.Now().Add(-30*time.Second), time.Second, []string{"down"}, []int64{0})
+	report, _ = s.GetUptimeReport(h.ID, 1)
+	if a := report.Windows[0]; a.Incidents != 3 || a.LongestOutage != 600 || a.Downtime < 929 {
+		t.Errorf("unexpected ongoing incident: %+v", a)
+	}
+
+	// outage is not extended after the last result of disabled check or in period before now
+	h.Enabled = false
+	if a, _ := s.availability(h, "24h", time.Now().Add(-24*time.Hour), time.Now(), time.Now()); a.Incidents != 3 || a.Downtime > 910 {
+		t.Errorf("unexpected incident of disabled check: %+v", a)
+	}
+	h.Enabled = true
+	if a, _ := s.availability(h, "24h", time.Now().Add(-24*time.Hour), time.Now().Add(-time.Second), time.Now()); a.Incidents != 3 || a.Downtime > 910 {
+		t.Errorf("unexpected incident of past period: %+v", a)
+	}
+
+	if report, _ = s.GetUptimeReport(h.ID, maxReportMonths+1); len(report.Months) != 3 {
+		t.Errorf("expected 3 months by default, got %d", len(report.Months))
+	}
+	if _, err := s.GetUptimeReport(uuid.Nil, 1); err == nil {
+		t.Errorf("expected error of nil id")
+	}
+}
+
+func TestService_AvailabilityOfAggregates(t *testing.T) {
+	s := setupTest(t)
+	h := &Health{Title: "aggregates", Type: "http", Params: "[]", Enabled: true}
+	if err := s.healthDb.Insert(h); err != nil {
+		t.Fatalf("insert health failed: %v", err)
+	}
+
+	// hours out of minute retention, short outages in mostly up hours and a down hour right after one of them
+	start := time.Now().AddDate(0, -2, 0).Truncate(time.Hour)
+	aggregates := []*RecordAggregate{
+		{Up: 57, Down: 3},
+		{Up: 60},
+		{Up: 45, Down: 15},
+		{Down: 60},
+		{Up: 60},
+	}
+	for i, a := range aggregates {
+		a.ParentId, a.Resolution, a.BucketTime, a.Total = h.ID, ResolutionHour, start.Add(time.Duration(i)*time.Hour), a.Up+a.Down
+		if err := s.aggregateDb.Insert(a); err != nil {
+			t.Fatalf("insert aggregate failed: %v", err)
+		}
+	}
+
+	a, err := s.availability(h, "hours", start, start.Add(5*time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("get availability failed: %v", err)
+	}
+	if a.Resolution != ResolutionHour || a.Incidents != 2 || a.Downtime != 4680 || a.LongestOutage != 4500 || a.MTTR != 2340 {
+		t.Errorf("unexpected incidents of hours: %+v", a)
+	}
+}
+
+func TestController_ExportUptimeReport(t *testing.T) {
+	s := setupTest(t)
+	h := &Health{Title: "export", Type: "ping", Params: "[]"}
+	if err := s.healthDb.Insert(h); err != nil {
+		t.Fatalf("insert health failed: %v", err)
+	}
+	insertRecords(t, s, h.ID, time.Now().Add(-10*time.Minute), time.Minute, []string{"up", "down", "up", "up"}, []int64{1, 0, 1, 1})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	(&Controller{service: s}).RegisterRoute(&engine.RouterGroup)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/report/export?format=csv&month="+time.Now().Format("2006-01"), nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+	}
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv failed: %v", err)
+	}
+	periods := make(map[string]bool)
+	for _, row := range rows[1:] {
+		if row[0] == h.ID.String() {
+			periods[row[3]] = true
+			// rolling windows only, results may cross the beginning of month
+			isWindow := row[3] == "24h" || row[3] == "7d" || row[3] == "30d"
+			if isWindow && (row[9] != "75" || row[10] != "1") {
+				t.Errorf("unexpected row: %v", row)
+			}
+		}
+	}
+	if len(periods) != 4 || !periods[time.Now().Format("2006-01")] {
+		t.Errorf("expected rows of 3 windows and month, got %v", periods)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/report/export?format=xml", nil))
+	if !strings.Contains(w.Body.String(), `"code":"`) || strings.Contains(w.Body.String(), `"code":"00000"`) {
+		t.Errorf("expected params error, got %s", w.Body.String())
+	}
+}
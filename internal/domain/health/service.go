@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +17,10 @@ const (
 	typeHttp = "http"
 	typeSSH  = "ssh"
 	typeDB   = "database"
+	typeTCP  = "tcp"
+	typeDNS  = "dns"
+	typeGRPC = "grpc"
+	typeTLS  = "tls"
 )
 
 var (
@@ -26,8 +31,10 @@ var (
 type Service struct {
 	healthDb       database2.Mapper[*Health]
 	healthRecordDb *database2.BaseMapper[*Record]
+	aggregateDb    *database2.BaseMapper[*RecordAggregate]
 	cron           *cron.Cron
 	cronJobMap     sync.Map
+	downsampling   atomic.Bool
 }
 
 func GetService() *Service {
@@ -37,6 +44,7 @@ func GetService() *Service {
 		service = &Service{
 			healthDb:       database2.NewCachedMapper(database2.GetDB(), &Health{}, cache.GetCache()),
 			healthRecordDb: database2.NewMapper(database2.GetDB(), &Record{}),
+			aggregateDb:    database2.NewMapper(database2.GetDB(), &RecordAggregate{}),
 			cron:           c,
 			cronJobMap:     sync.Map{},
 		}
@@ -55,8 +63,13 @@ func (s *Service) HealthStatistics() (*Statistics, error) {
 	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("type = ?", "ssh").Count(&statistics.SSH)
 	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("type = ?", "http").Count(&statistics.HTTP)
 	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("type = ?", "database").Count(&statistics.Database)
+	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("type = ?", "tcp").Count(&statistics.TCP)
+	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("type = ?", "dns").Count(&statistics.DNS)
+	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("type = ?", "grpc").Count(&statistics.GRPC)
+	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("type = ?", "tls").Count(&statistics.TLS)
 	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("status = ?", "down").Scan(&statistics.ErrorList)
-	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("rtt > ?", 460).Where("status = ?", "up").Order("rtt desc").Limit(10).Scan(&statistics.SlowList)
+	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("rtt > CASE WHEN slow_threshold > 0 THEN slow_threshold ELSE ? END", defaultSlowThreshold).Where("status = ?", "up").Order("rtt desc").Limit(10).Scan(&statistics.SlowList)
+	s.healthDb.GetDB().Model(&Health{}).Where("enabled = ?", true).Where("warning <> ?", "").Scan(&statistics.WarningList)
 
 	return statistics, nil
 }
@@ -75,6 +88,7 @@ func (s *Service) ListHealth(health *Health) ([]*HealthListResponse, error) {
 				Enabled: item.Enabled,
 				Status:  item.Status,
 				RTT:     item.RTT,
+				Warning: item.Warning,
 			})
 		}
 		return result, err
@@ -112,6 +126,9 @@ func (s *Service) DeleteHealth(health *Health) error {
 	if err := s.stopChecker(health); err != nil {
 		return err
 	}
+	if err := s.deleteHistory(health.ID); err != nil {
+		return err
+	}
 	return s.healthDb.Delete(health)
 }
 
@@ -133,7 +150,7 @@ func (s *Service) GetHealthCheckTypes() []CheckType {
 		{
 			Title: "http",
 			Type:  "http",
-			Desc:  "http check, support http and https GET method",
+			Desc:  "http check, support http and https with assertions of status code and body",
 			Params: []Param{
 				{
 					Key:      "url",
@@ -143,6 +160,134 @@ func (s *Service) GetHealthCheckTypes() []CheckType {
 					Required: true,
 					Desc:     "url for check",
 				},
+				{
+					Key:      "method",
+					Value:    "GET",
+					Title:    "Method",
+					Type:     "string",
+					Required: false,
+					Desc:     "request method",
+				},
+				{
+					Key:      "headers",
+					Value:    "",
+					Title:    "Headers",
+					Type:     "string",
+					Required: false,
+					Desc:     "request headers, one \"key: value\" each line",
+				},
+				{
+					Key:      "body",
+					Value:    "",
+					Title:    "Body",
+					Type:     "string",
+					Required: false,
+					Desc:     "request body",
+				},
+				{
+					Key:      "expectedStatus",
+					Value:    defaultExpectedStatus,
+					Title:    "Expected Status",
+					Type:     "string",
+					Required: false,
+					Desc:     "expected status codes and ranges, e.g. 200,201,300-399",
+				},
+				{
+					Key:      "bodyContains",
+					Value:    "",
+					Title:    "Body Contains",
+					Type:     "string",
+					Required: false,
+					Desc:     "substring response body must contain",
+				},
+				{
+					Key:      "bodyRegex",
+					Value:    "",
+					Title:    "Body Regex",
+					Type:     "string",
+					Required: false,
+					Desc:     "regular expression response body must match",
+				},
+				{
+					Key:      "jsonPath",
+					Value:    "",
+					Title:    "JSONPath",
+					Type:     "string",
+					Required: false,
+					Desc:     "JSONPath which must exist in response body, e.g. $.status",
+				},
+				{
+					Key:      "jsonPathValue",
+					Value:    "",
+					Title:    "JSONPath Value",
+					Type:     "string",
+					Required: false,
+					Desc:     "expected value of JSONPath, any value if empty",
+				},
+				{
+					Key:      "followRedirects",
+					Value:    true,
+					Title:    "Follow Redirects",
+					Type:     "boolean",
+					Required: false,
+					Desc:     "follow redirects, response of redirect is checked otherwise",
+				},
+				{
+					Key:      "maxRedirects",
+					Value:    defaultMaxRedirects,
+					Title:    "Max Redirects",
+					Type:     "number",
+					Required: false,
+					Desc:     "most redirects followed",
+				},
+				{
+					Key:      "insecureSkipVerify",
+					Value:    false,
+					Title:    "Insecure Skip Verify",
+					Type:     "boolean",
+					Required: false,
+					Desc:     "skip verifying certificate of server",
+				},
+				{
+					Key:      "serverName",
+					Value:    "",
+					Title:    "Server Name",
+					Type:     "string",
+					Required: false,
+					Desc:     "server name of TLS, host of url if empty",
+				},
+				{
+					Key:      "caCert",
+					Value:    "",
+					Title:    "CA Certificate",
+					Type:     "string",
+					Required: false,
+					Desc:     "PEM certificate of CA verifying server, system CAs if empty",
+				},
+				{
+					Key:      "clientCert",
+					Value:    "",
+					Title:    "Client Certificate",
+					Type:     "string",
+					Required: false,
+					Desc:     "PEM certificate of client for mTLS",
+				},
+				{
+					Key:      "clientKey",
+					Value:    "",
+					Title:    "Client Key",
+					Type:     "string",
+					Required: false,
+					Desc:     "PEM private key of client for mTLS",
+				},
+				{
+					Key:      "certExpiryDays",
+					Value:    defaultCertExpiryDays,
+					Title:    "Certificate Expiry Days",
+					Type:     "number",
+					Required: false,
+					Desc:     "warn if certificate of server expires in days, 0 means no warning",
+				},
 			},
 		},
 		{
@@ -238,15 +383,182 @@ func (s *Service) GetHealthCheckTypes() []CheckType {
 				},
 			},
 		},
+		{
+			Title: "tcp",
+			Type:  "tcp",
+			Desc:  "tcp check, connect to port of host",
+			Params: []Param{
+				{
+					Key:      "host",
+					Value:    "",
+					Title:    "Host",
+					Type:     "string",
+					Required: true,
+					Desc:     "host or ip",
+				},
+				{
+					Key:      "port",
+					Value:    0,
+					Title:    "Port",
+					Type:     "number",
+					Required: true,
+					Desc:     "tcp port",
+				},
+			},
+		},
+		{
+			Title: "dns",
+			Type:  "dns",
+			Desc:  "dns check, resolve record of name and check answers",
+			Params: []Param{
+				{
+					Key:      "name",
+					Value:    "",
+					Title:    "Name",
+					Type:     "string",
+					Required: true,
+					Desc:     "domain name to resolve",
+				},
+				{
+					Key:      "recordType",
+					Value:    "A",
+					Title:    "Record Type",
+					Type:     "string",
+					Required: false,
+					Desc:     "record type, support A AAAA CNAME MX NS and TXT",
+				},
+				{
+					Key:      "server",
+					Value:    "",
+					Title:    "Server",
+					Type:     "string",
+					Required: false,
+					Desc:     "dns server as host:port, port 53 if omitted, system resolver if empty",
+				},
+				{
+					Key:      "expected",
+					Value:    "",
+					Title:    "Expected Answers",
+					Type:     "string",
+					Required: false,
+					Desc:     "comma separated answers which must all be resolved, any answer if empty",
+				},
+			},
+		},
+		{
+			Title: "grpc",
+			Type:  "grpc",
+			Desc:  "grpc check with grpc.health.v1 health checking protocol, up if service is serving",
+			Params: []Param{
+				{
+					Key:      "address",
+					Value:    "",
+					Title:    "Address",
+					Type:     "string",
+					Required: true,
+					Desc:     "grpc server as host:port",
+				},
+				{
+					Key:      "service",
+					Value:    "",
+					Title:    "Service",
+					Type:     "string",
+					Required: false,
+					Desc:     "service name, overall health of server if empty",
+				},
+				{
+					Key:      "tls",
+					Value:    false,
+					Title:    "TLS",
+					Type:     "boolean",
+					Required: false,
+					Desc:     "connect with tls",
+				},
+				{
+					Key:      "insecureSkipVerify",
+					Value:    false,
+					Title:    "Insecure Skip Verify",
+					Type:     "boolean",
+					Required: false,
+					Desc:     "skip verification of server certificate",
+				},
+				{
+					Key:      "caCert",
+					Value:    "",
+					Title:    "CA Certificate",
+					Type:     "string",
+					Required: false,
+					Desc:     "PEM root certificate for verifying server certificate",
+				},
+			},
+		},
+		{
+			Title: "tls",
+			Type:  "tls",
+			Desc:  "tls certificate check, down if handshake fails or certificate expired",
+			Params: []Param{
+				{
+					Key:      "host",
+					Value:    "",
+					Title:    "Host",
+					Type:     "string",
+					Required: true,
+					Desc:     "host or ip",
+				},
+				{
+					Key:      "port",
+					Value:    443,
+					Title:    "Port",
+					Type:     "number",
+					Required: false,
+					Desc:     "tls port",
+				},
+				{
+					Key:      "serverName",
+					Value:    "",
+					Title:    "Server Name",
+					Type:     "string",
+					Required: false,
+					Desc:     "server name for SNI and verification, host if empty",
+				},
+				{
+					Key:      "insecureSkipVerify",
+					Value:    false,
+					Title:    "Insecure Skip Verify",
+					Type:     "boolean",
+					Required: false,
+					Desc:     "skip verification of certificate chain, expiry is still checked",
+				},
+				{
+					Key:      "caCert",
+					Value:    "",
+					Title:    "CA Certificate",
+					Type:     "string",
+					Required: false,
+					Desc:     "PEM root certificate for verifying server certificate",
+				},
+				{
+					Key:      "certExpiryDays",
+					Value:    defaultCertExpiryDays,
+					Title:    "Certificate Expiry Days",
+					Type:     "number",
+					Required: false,
+					Desc:     "warn if certificate expires in these days, 0 to disable",
+				},
+			},
+		},
 	}
 }
 
 func (s *Service) Initialize() error {
-	if err := database2.GetDB().AutoMigrate(&Health{}, &Record{}); err != nil {
+	if err := database2.GetDB().AutoMigrate(&Health{}, &Record{}, &RecordAggregate{}); err != nil {
 		return err
 	}
 	if err := s.initChecker(); err != nil {
 		return err
 	}
+	if _, err := s.cron.AddFunc(downsampleSpec, s.downsampleHistory); err != nil {
+		return err
+	}
 	return nil
 }
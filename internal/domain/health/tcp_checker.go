@@ -0,0 +1,58 @@
+package health
+
+import (
+	"fmt"
+	"github.com/MR5356/health"
+	"net"
+	"strconv"
+	"time"
+)
+
+// TCPResult is result of tcp check
+type TCPResult struct {
+	Address string `json:"address"`
+	Error   string `json:"error,omitempty"`
+}
+
+// tcpChecker connects to port of host
+type tcpChecker struct {
+	address string
+	timeout time.Duration
+}
+
+func newTCPChecker(params Params, timeout time.Duration) (health.Checker, error) {
+	address, err := hostPort(params, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &tcpChecker{address: address, timeout: timeout}, nil
+}
+
+// hostPort address of host and port params, defPort is used if port is empty
+func hostPort(params Params, defPort int) (string, error) {
+	host, err := params.GetString("host", "")
+	if err != nil || host == "" {
+		return "", fmt.Errorf("%w: host is empty", ErrParam)
+	}
+	port, err := params.GetInt("port", defPort)
+	if err != nil || port <= 0 || port > 65535 {
+		return "", fmt.Errorf("%w: port must be in 1-65535", ErrParam)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+func (c *tcpChecker) Check() *health.Health {
+	res := health.NewHealth()
+	result := &TCPResult{Address: c.address}
+	res.SetResult(result)
+
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", c.address, c.timeout)
+	res.SetRTT(time.Since(start).Milliseconds())
+	if err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+	_ = conn.Close()
+	return res.Up()
+}
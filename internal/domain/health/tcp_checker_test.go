@@ -0,0 +1,72 @@
+package health
+
+import (
+	"errors"
+	"github.com/MR5356/health"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTCPChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	c, err := newTCPChecker(params("host", "127.0.0.1", "port", port), time.Second)
+	if err != nil {
+		t.Fatalf("new tcp checker failed: %v", err)
+	}
+	if res := c.Check(); res.Status != health.StatusUp {
+		t.Errorf("expected up, got %s: %s", res.Status, res.Result.(*TCPResult).Error)
+	}
+
+	_ = ln.Close()
+	res := c.Check()
+	if res.Status != health.StatusDown || res.Result.(*TCPResult).Error == "" {
+		t.Errorf("expected down with error after listener closed, got %s", res.Status)
+	}
+}
+
+func TestTCPChecker_InvalidParams(t *testing.T) {
+	tests := []Params{
+		params(),
+		params("host", "127.0.0.1"),
+		params("host", "127.0.0.1", "port", 70000),
+		params("host", "127.0.0.1", "port", "http"),
+	}
+	for i, ps := range tests {
+		if _, err := newTCPChecker(ps, time.Second); !errors.Is(err, ErrParam) {
+			t.Errorf("case %d: expected ErrParam, got %v", i, err)
+		}
+	}
+}
+
+func TestChecker_TCP(t *testing.T) {
+	s := setupTest(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	up := &Health{Title: "tcp", Type: "tcp", Enabled: true,
+		Params: `[{"key":"host","value":"127.0.0.1"},{"key":"port","value":` + strconv.Itoa(ln.Addr().(*net.TCPAddr).Port) + `}]`}
+	invalid := &Health{Title: "invalid tcp", Type: "tcp", Enabled: true, Params: `[{"key":"host","value":"127.0.0.1"}]`}
+	for _, h := range []*Health{up, invalid} {
+		if err := s.healthDb.Insert(h); err != nil {
+			t.Fatalf("insert health failed: %v", err)
+		}
+		(&Checker{health: h, service: s}).Run()
+	}
+
+	if res, _ := s.DetailHealth(up.ID); res.Status != "up" {
+		t.Errorf("expected up, got %s", res.Status)
+	}
+	if res, _ := s.DetailHealth(invalid.ID); res.Status != string(StatusError) {
+		t.Errorf("expected error of invalid params, got %s", res.Status)
+	}
+}
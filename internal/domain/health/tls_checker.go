@@ -0,0 +1,116 @@
+package health
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/MR5356/health"
+	"net"
+	"time"
+)
+
+// TLSResult is result of tls certificate check
+type TLSResult struct {
+	Address       string     `json:"address"`
+	Subject       string     `json:"subject,omitempty"`
+	Issuer        string     `json:"issuer,omitempty"`
+	DNSNames      []string   `json:"dnsNames,omitempty"`
+	CertExpiresAt *time.Time `json:"certExpiresAt,omitempty"`
+	CertDaysLeft  *int       `json:"certDaysLeft,omitempty"`
+	Warning       string     `json:"warning,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+func (r *TLSResult) warning() string {
+	return r.Warning
+}
+
+// tlsChecker checks certificate of tls server, it is down if handshake fails, certificate expired or is not trusted.
+// certificate is verified after handshake, so that its expiry is always reported
+type tlsChecker struct {
+	address        string
+	tlsConfig      *tls.Config
+	verify         bool
+	certExpiryDays int
+	timeout        time.Duration
+}
+
+func newTLSChecker(params Params, timeout time.Duration) (health.Checker, error) {
+	address, err := hostPort(params, 443)
+	if err != nil {
+		return nil, err
+	}
+	c := &tlsChecker{address: address, timeout: timeout}
+	if c.certExpiryDays, err = params.GetInt("certExpiryDays", defaultCertExpiryDays); err != nil {
+		return nil, fmt.Errorf("%w: certExpiryDays", ErrParam)
+	}
+	insecure, err := params.GetBool("insecureSkipVerify", false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: insecureSkipVerify", ErrParam)
+	}
+	serverName, err := params.GetString("serverName", "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: serverName", ErrParam)
+	}
+	caCert, err := params.GetString("caCert", "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: caCert", ErrParam)
+	}
+	if c.tlsConfig, err = newTLSConfig(true, serverName, caCert, "", ""); err != nil {
+		return nil, err
+	}
+	c.verify = !insecure
+	return c, nil
+}
+
+func (c *tlsChecker) Check() *health.Health {
+	res := health.NewHealth()
+	result := &TLSResult{Address: c.address}
+	res.SetResult(result)
+
+	start := time.Now()
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: c.timeout}, "tcp", c.address, c.tlsConfig)
+	res.SetRTT(time.Since(start).Milliseconds())
+	if err != nil {
+		result.Error = err.Error()
+		return res.Down()
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		result.Error = "no certificate"
+		return res.Down()
+	}
+	cert := certs[0]
+	result.Subject, result.Issuer, result.DNSNames = cert.Subject.String(), cert.Issuer.String(), cert.DNSNames
+	result.CertExpiresAt, result.CertDaysLeft, result.Warning = certExpiry(cert, c.certExpiryDays)
+	if now := time.Now(); now.After(cert.NotAfter) || now.Before(cert.NotBefore) {
+		result.Error = fmt.Sprintf("certificate is valid from %s to %s", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+		return res.Down()
+	}
+	if c.verify {
+		if err := c.verifyChain(certs); err != nil {
+			result.Error = err.Error()
+			return res.Down()
+		}
+	}
+	return res.Up()
+}
+
+// verifyChain verify certificates of server as handshake does, with configured roots and server name
+func (c *tlsChecker) verifyChain(certs []*x509.Certificate) error {
+	opts := x509.VerifyOptions{
+		Roots:         c.tlsConfig.RootCAs,
+		DNSName:       c.tlsConfig.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	if opts.DNSName == "" {
+		opts.DNSName, _, _ = net.SplitHostPort(c.address)
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"github.com/MR5356/health"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkTLS(t *testing.T, ps Params) (*health.Health, *TLSResult) {
+	t.Helper()
+	c, err := newTLSChecker(ps, time.Second)
+	if err != nil {
+		t.Fatalf("new tls checker failed: %v", err)
+	}
+	res := c.Check()
+	return res, res.Result.(*TLSResult)
+}
+
+// listenTLS tls listener with a self-signed certificate valid from notBefore to notAfter, handshakes are served until it is closed
+func listenTLS(t *testing.T, notBefore, notAfter time.Time) net.Listener {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "server"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate failed: %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = conn.(*tls.Conn).Handshake()
+				_ = conn.Close()
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestTLSChecker(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	if res, result := checkTLS(t, params("host", "127.0.0.1", "port", addr.Port)); res.Status != health.StatusDown || result.Error == "" || result.CertExpiresAt == nil {
+		t.Errorf("expected down of unknown authority with certificate expiry, got %s: %+v", res.Status, result)
+	}
+	if res, result := checkTLS(t, params("host", "127.0.0.1", "port", addr.Port, "caCert", caPEM, "serverName", "server.invalid")); res.Status != health.StatusDown || result.Error == "" {
+		t.Errorf("expected down of mismatched server name, got %s", res.Status)
+	}
+
+	res, result := checkTLS(t, params("host", "127.0.0.1", "port", addr.Port, "caCert", caPEM))
+	if res.Status != health.StatusUp {
+		t.Fatalf("expected up with ca certificate, got %s: %s", res.Status, result.Error)
+	}
+	if result.CertExpiresAt == nil || !result.CertExpiresAt.Equal(server.Certificate().NotAfter) || *result.CertDaysLeft <= 0 {
+		t.Errorf("unexpected certificate expiry: %v %v", result.CertExpiresAt, result.CertDaysLeft)
+	}
+	if result.Warning != "" || result.Issuer == "" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// certificate of httptest expires in decades
+	if _, result = checkTLS(t, params("host", "127.0.0.1", "port", addr.Port, "insecureSkipVerify", true, "certExpiryDays", 365*100)); result.Warning == "" {
+		t.Errorf("expected warning of certificate expiry")
+	}
+}
+
+func TestTLSChecker_Expired(t *testing.T) {
+	ln := listenTLS(t, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour))
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// expiry is reported even if certificate is not trusted
+	res, result := checkTLS(t, params("host", "127.0.0.1", "port", port))
+	if res.Status != health.StatusDown || !strings.Contains(result.Error, "certificate is valid from") {
+		t.Errorf("expected down of expired certificate, got %s: %s", res.Status, result.Error)
+	}
+	if result.CertDaysLeft == nil || *result.CertDaysLeft >= 0 {
+		t.Errorf("expected negative days left, got %v", result.CertDaysLeft)
+	}
+
+	// expiry is checked even if verification is skipped
+	res, result = checkTLS(t, params("host", "127.0.0.1", "port", port, "insecureSkipVerify", true))
+	if res.Status != health.StatusDown || result.Error == "" {
+		t.Errorf("expected down of expired certificate, got %s", res.Status)
+	}
+	if result.CertDaysLeft == nil || *result.CertDaysLeft >= 0 || result.Warning == "" {
+		t.Errorf("expected negative days left with warning, got %v %q", result.CertDaysLeft, result.Warning)
+	}
+}
+
+func TestTLSChecker_InvalidParams(t *testing.T) {
+	tests := []Params{
+		params(),
+		params("host", "localhost", "port", 0),
+		params("host", "localhost", "caCert", "not pem"),
+		params("host", "localhost", "certExpiryDays", "soon"),
+	}
+	for i, ps := range tests {
+		if _, err := newTLSChecker(ps, time.Second); !errors.Is(err, ErrParam) {
+			t.Errorf("case %d: expected ErrParam, got %v", i, err)
+		}
+	}
+}
@@ -59,6 +59,15 @@ func (s *Service) ExecContainer(ctx *gin.Context, id uuid.UUID, containerId, dri
 	}
 }
 
+// ExecContainerCommand run command in container of host and write its output to output, returns the exit code
+func (s *Service) ExecContainerCommand(ctx context.Context, id uuid.UUID, containerId, driver, user string, cmd []string, output io.Writer) (int, error) {
+	if client, err := s.getContainerClient(id, driver); err != nil {
+		return 0, err
+	} else {
+		return client.Exec(ctx, containerId, user, cmd, output)
+	}
+}
+
 func (s *Service) getContainerClient(id uuid.UUID, driver string) (container.Client, error) {
 	// 优先在缓存中取客户端
 	key := fmt.Sprintf("%s-%s", id.String(), driver)
@@ -1,14 +1,18 @@
 package module
 
 import (
+	"errors"
 	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/user"
 	"github.com/MR5356/aurora/internal/response"
 	"github.com/MR5356/aurora/pkg/util/ginutil"
 	"github.com/gin-gonic/gin"
 	"github.com/google/go-github/v61/github"
 	"github.com/sirupsen/logrus"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type Controller struct {
@@ -22,71 +26,77 @@ func NewController() *Controller {
 }
 
 func (c *Controller) handleGithubAppInstall(ctx *gin.Context) {
-	payload, err := github.ValidatePayload(ctx.Request, []byte(""))
+	body, err := io.ReadAll(ctx.Request.Body)
 	if err != nil {
-		logrus.Errorf("github.ValidatePayload: %v", err)
 		response.ErrorWithMsg(ctx, response.CodeParamsError, "invalid payload")
 		return
 	}
 
-	eventType := github.WebHookType(ctx.Request)
-	event, err := github.ParseWebHook(eventType, payload)
+	res, err := c.service.ReceiveGithubWebhook(ctx, ctx.Request.Header, body)
 	if err != nil {
-		logrus.Errorf("github.ParseWebHook: %v", err)
-		response.ErrorWithMsg(ctx, response.CodeParamsError, "invalid webhook event")
+		webhookErrorResponse(ctx, err)
 		return
 	}
+	response.Success(ctx, res)
+}
 
-	var installationID int64 = 0
-	var action string = ""
-	switch e := event.(type) {
-	case *github.InstallationEvent:
-		installationID = e.Installation.GetID()
-		action = e.GetAction()
-	case *github.InstallationRepositoriesEvent:
-		installationID = e.Installation.GetID()
-		action = e.GetAction()
-	}
-
-	logrus.Infof("installation: %+v", installationID)
-	logrus.Infof("action: %s", action)
-	if installationID == 0 {
-		response.Success(ctx, nil)
+// webhookErrorResponse respond error of processing delivery with its code and message
+func webhookErrorResponse(ctx *gin.Context, err error) {
+	var we *webhookError
+	if errors.As(err, &we) {
+		response.ErrorWithMsg(ctx, we.code, we.msg)
 		return
 	}
+	response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+}
 
-	if err = c.service.UpdateGithubModule(ctx, action, installationID); err != nil {
-		logrus.Errorf("UpdateGithubModule failed: %v", err)
-		response.ErrorWithMsg(ctx, response.CodeServerError, "failed to update module")
-		return
-	} else {
-		logrus.Infof("UpdateGithubModule success, event: %s, installationID: %d", eventType, installationID)
-		response.Success(ctx, nil)
+// toGitEvent convert push and pull_request events to GitEvent, deleted branches, tags and closed pull requests are skipped
+func toGitEvent(event any) (*GitEvent, bool) {
+	switch e := event.(type) {
+	case *github.PushEvent:
+		branch, ok := strings.CutPrefix(e.GetRef(), "refs/heads/")
+		if !ok || e.GetDeleted() {
+			return nil, false
+		}
+		return &GitEvent{
+			Event:          EventPush,
+			InstallationID: e.GetInstallation().GetID(),
+			Owner:          e.GetRepo().GetOwner().GetLogin(),
+			Repo:           e.GetRepo().GetName(),
+			Branch:         branch,
+			SHA:            e.GetAfter(),
+		}, true
+	case *github.PullRequestEvent:
+		switch e.GetAction() {
+		case "opened", "synchronize", "reopened":
+		default:
+			return nil, false
+		}
+		return &GitEvent{
+			Event:          EventPullRequest,
+			InstallationID: e.GetInstallation().GetID(),
+			Owner:          e.GetRepo().GetOwner().GetLogin(),
+			Repo:           e.GetRepo().GetName(),
+			Branch:         e.GetPullRequest().GetHead().GetRef(),
+			SHA:            e.GetPullRequest().GetHead().GetSHA(),
+			PullRequest:    e.GetNumber(),
+		}, true
 	}
+	return nil, false
 }
 
 func (c *Controller) handleGithubAppCallback(ctx *gin.Context) {
-	installationIDStr := ctx.Query("installation_id")
 	userID, ok := ctx.Get(config.ContextUserIDKey)
 	if !ok {
 		logrus.Error("user ID not found in context")
 		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
 		return
 	}
-	installationID, err := strconv.ParseInt(installationIDStr, 10, 64)
-	if err != nil {
-		logrus.Errorf("strconv.ParseInt: %v", err)
-		response.ErrorWithMsg(ctx, response.CodeParamsError, "invalid installation ID")
+	if err := c.service.HandleGithubAppCallback(ctx, ctx.Request.Header, ctx.Request.URL.Query(), userID.(string)); err != nil {
+		webhookErrorResponse(ctx, err)
 		return
 	}
-	if c.service.RegisterInstallationID(ctx, installationID, userID.(string)) != nil {
-		logrus.Errorf("RegisterInstallationID failed: %v", err)
-		response.ErrorWithMsg(ctx, response.CodeServerError, "failed to register installation ID")
-		return
-	} else {
-		logrus.Infof("RegisterInstallationID success, installationID: %d, user: %v", installationID, userID)
-		ctx.Redirect(http.StatusFound, "/repository")
-	}
+	ctx.Redirect(http.StatusFound, "/repository")
 }
 
 func (c *Controller) handleListModules(ctx *gin.Context) {
@@ -106,9 +116,371 @@ func (c *Controller) handleListModules(ctx *gin.Context) {
 	response.Success(ctx, res)
 }
 
+// @Summary	detail module
+// @Description	module with its metadata of branches, tags, languages and open pull requests
+// @Tags		module
+// @Param		id	path		int	true	"module id"
+// @Success	200	{object}	response.Response{data=ModuleDetail}
+// @Router		/module/{id} [get]
+// @Produce	json
+func (c *Controller) handleDetailModule(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	res, err := c.service.GetModuleDetail(id, userID.(string))
+	if err != nil {
+		response.ErrorWithMsg(ctx, response.CodeNotFound, err.Error())
+		return
+	}
+	response.Success(ctx, res)
+}
+
+// @Summary	sync module metadata
+// @Description	fetch branches, tags, languages and open pull requests of module from its SCM now
+// @Tags		module
+// @Param		id	path		int	true	"module id"
+// @Success	200	{object}	response.Response{data=ModuleMetadata}
+// @Router		/module/{id}/metadata/sync [post]
+// @Produce	json
+func (c *Controller) handleSyncModuleMetadata(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	res, err := c.service.SyncModuleMetadataOfOwner(ctx, id, userID.(string))
+	if err != nil {
+		logrus.Errorf("SyncModuleMetadata failed: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+		return
+	}
+	response.Success(ctx, res)
+}
+
+// @Summary	list workflow triggers
+// @Tags		module
+// @Param		id	path		int	true	"module id"
+// @Success	200	{object}	response.Response{data=[]WorkflowTrigger}
+// @Router		/module/{id}/triggers [get]
+// @Produce	json
+func (c *Controller) handleListWorkflowTrigger(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	res, err := c.service.ListWorkflowTrigger(id, userID.(string))
+	if err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		return
+	}
+	response.Success(ctx, res)
+}
+
+// @Summary	add workflow trigger
+// @Description	start workflow on push or pull_request events of module
+// @Tags		module
+// @Param		id		path		int				true	"module id"
+// @Param		trigger	body		WorkflowTrigger	true	"workflow trigger"
+// @Success	200		{object}	response.Response
+// @Router		/module/{id}/trigger [post]
+// @Produce	json
+func (c *Controller) handleAddWorkflowTrigger(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	trigger := new(WorkflowTrigger)
+	if err := ctx.ShouldBindJSON(trigger); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	trigger.ModuleID = id
+	if err := c.service.AddWorkflowTrigger(trigger, userID.(string)); err != nil {
+		logrus.Errorf("AddWorkflowTrigger failed: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// @Summary	delete workflow trigger
+// @Tags		module
+// @Param		id	path		int	true	"trigger id"
+// @Success	200	{object}	response.Response
+// @Router		/module/trigger/{id} [delete]
+// @Produce	json
+func (c *Controller) handleDeleteWorkflowTrigger(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	if err := c.service.DeleteWorkflowTrigger(id, userID.(string)); err != nil {
+		logrus.Errorf("DeleteWorkflowTrigger failed: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// @Summary	list scm connections
+// @Tags		module
+// @Success	200	{object}	response.Response{data=[]SCMConnection}
+// @Router		/module/scm/connections [get]
+// @Produce	json
+func (c *Controller) handleListSCMConnection(ctx *gin.Context) {
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	res, err := c.service.ListSCMConnection(userID.(string))
+	if err != nil {
+		logrus.Errorf("ListSCMConnection failed: %v", err)
+		response.Error(ctx, response.CodeServerError)
+		return
+	}
+	response.Success(ctx, res)
+}
+
+// @Summary	add scm connection
+// @Description	add GitLab or Gitea connection by personal access token, repositories of it are synced to modules by syncCron
+// @Tags		module
+// @Param		connection	body		SCMConnection	true	"scm connection"
+// @Success	200			{object}	response.Response{data=SCMConnection}
+// @Router		/module/scm/connection [post]
+// @Produce	json
+func (c *Controller) handleAddSCMConnection(ctx *gin.Context) {
+	conn := new(SCMConnection)
+	if err := ctx.ShouldBindJSON(conn); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	conn.Owner = userID.(string)
+	if err := c.service.AddSCMConnection(ctx, conn); err != nil {
+		logrus.Errorf("AddSCMConnection failed: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		return
+	}
+	response.Success(ctx, conn)
+}
+
+// @Summary	delete scm connection
+// @Description	delete scm connection with its modules
+// @Tags		module
+// @Param		id	path		int	true	"connection id"
+// @Success	200	{object}	response.Response
+// @Router		/module/scm/connection/{id} [delete]
+// @Produce	json
+func (c *Controller) handleDeleteSCMConnection(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	if err := c.service.DeleteSCMConnection(id, userID.(string)); err != nil {
+		logrus.Errorf("DeleteSCMConnection failed: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// @Summary	sync scm connection
+// @Description	sync repositories of scm connection to modules now
+// @Tags		module
+// @Param		id	path		int	true	"connection id"
+// @Success	200	{object}	response.Response
+// @Router		/module/scm/connection/{id}/sync [post]
+// @Produce	json
+func (c *Controller) handleSyncSCMConnection(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	if err := c.service.SyncSCMConnectionOfOwner(ctx, id, userID.(string)); err != nil {
+		logrus.Errorf("SyncSCMConnection failed: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// @Summary	authorize scm connection by oauth
+// @Description	redirect to authorization page of oauth, a connection is added after authorized
+// @Tags		module
+// @Param		oauth	path	string	true	"name of oauth config"
+// @Router		/module/scm/oauth/{oauth} [get]
+func (c *Controller) handleSCMOAuth(ctx *gin.Context) {
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	authURL, err := c.service.SCMOAuthURL(ctx.Param("oauth"), userID.(string))
+	if err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		return
+	}
+	ctx.Redirect(http.StatusTemporaryRedirect, authURL)
+}
+
+func (c *Controller) handleSCMOAuthCallback(ctx *gin.Context) {
+	userID, ok := ctx.Get(config.ContextUserIDKey)
+	if !ok {
+		response.ErrorWithMsg(ctx, response.CodeNoPermission, "user not authenticated")
+		return
+	}
+
+	if _, err := c.service.SCMOAuthCallback(ctx, ctx.Query("state"), ctx.Query("code"), userID.(string)); err != nil {
+		logrus.Errorf("SCMOAuthCallback failed: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		return
+	}
+	ctx.Redirect(http.StatusFound, "/repository")
+}
+
+// @Summary	page webhook deliveries
+// @Description	page received webhooks and app installation callbacks, payloads are returned by detail
+// @Tags		module
+// @Param		page			query		int		false	"page number"
+// @Param		size			query		int		false	"size number"
+// @Param		event			query		string	false	"event"
+// @Param		status			query		string	false	"status, success or failure"
+// @Param		installationID	query		int		false	"installation id"
+// @Success	200				{object}	response.Response
+// @Router		/module/webhook/deliveries [get]
+// @Produce	json
+func (c *Controller) handlePageDelivery(ctx *gin.Context) {
+	page, size := ginutil.GetPageParams(ctx)
+	installationID, _ := strconv.ParseInt(ctx.Query("installationID"), 10, 64)
+
+	filter := &WebhookDelivery{Event: ctx.Query("event"), Status: ctx.Query("status"), InstallationID: installationID}
+	if res, err := c.service.PageDelivery(page, size, filter); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	detail webhook delivery
+// @Tags		module
+// @Param		id	path		int	true	"delivery id"
+// @Success	200	{object}	response.Response{data=WebhookDelivery}
+// @Router		/module/webhook/delivery/{id} [get]
+// @Produce	json
+func (c *Controller) handleDetailDelivery(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	if res, err := c.service.DetailDelivery(id); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeNotFound, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	redeliver webhook delivery
+// @Description	process payload of delivery again, the redelivery is recorded as a new delivery
+// @Tags		module
+// @Param		id	path		int	true	"delivery id"
+// @Success	200	{object}	response.Response{data=WebhookDelivery}
+// @Router		/module/webhook/delivery/{id}/redeliver [post]
+// @Produce	json
+func (c *Controller) handleRedeliverDelivery(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	if res, err := c.service.RedeliverWebhook(ctx, id); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
 func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
 	api := group.Group("/module")
 	api.POST("/github/app/install", c.handleGithubAppInstall)
 	api.GET("/github/app/callback", c.handleGithubAppCallback)
 	api.GET("/list", c.handleListModules)
+
+	api.GET("/:id", c.handleDetailModule)
+	api.POST("/:id/metadata/sync", c.handleSyncModuleMetadata)
+	api.GET("/:id/triggers", c.handleListWorkflowTrigger)
+	api.POST("/:id/trigger", c.handleAddWorkflowTrigger)
+	api.DELETE("/trigger/:id", c.handleDeleteWorkflowTrigger)
+
+	api.GET("/scm/connections", c.handleListSCMConnection)
+	api.POST("/scm/connection", c.handleAddSCMConnection)
+	api.DELETE("/scm/connection/:id", c.handleDeleteSCMConnection)
+	api.POST("/scm/connection/:id/sync", c.handleSyncSCMConnection)
+	api.GET("/scm/oauth/:oauth", c.handleSCMOAuth)
+	api.GET("/scm/callback", c.handleSCMOAuthCallback)
+
+	admin := api.Group("/webhook")
+	admin.Use(user.MustAdmin())
+	admin.GET("/deliveries", c.handlePageDelivery)
+	admin.GET("/delivery/:id", c.handleDetailDelivery)
+	admin.POST("/delivery/:id/redeliver", c.handleRedeliverDelivery)
 }
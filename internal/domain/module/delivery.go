@@ -0,0 +1,252 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/response"
+	"github.com/google/go-github/v61/github"
+	"github.com/sirupsen/logrus"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	DeliveryStatusSuccess = "success"
+	DeliveryStatusFailure = "failure"
+
+	SignatureValid   = "valid"
+	SignatureInvalid = "invalid"
+	// SignatureSkipped signature is not verified as webhook secret is not configured
+	SignatureSkipped = "skipped"
+
+	// EventInstallationCallback event of delivery of callback after GitHub App is installed
+	EventInstallationCallback = "installation_callback"
+
+	// maxDeliveryErrorLength length of column Error of WebhookDelivery
+	maxDeliveryErrorLength = 1024
+)
+
+// sensitiveHeaders headers not recorded in deliveries
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Token":       true,
+}
+
+// webhookError is error of processing delivery with code and message of response
+type webhookError struct {
+	code string
+	msg  string
+	err  error
+}
+
+func (e *webhookError) Error() string {
+	if e.err != nil {
+		return e.msg + ": " + e.err.Error()
+	}
+	return e.msg
+}
+
+func newDelivery(event string, header http.Header) *WebhookDelivery {
+	headers := make(Headers)
+	for key := range header {
+		if !sensitiveHeaders[http.CanonicalHeaderKey(key)] {
+			headers[key] = header.Get(key)
+		}
+	}
+	return &WebhookDelivery{SCMType: SCMTypeGithub, Event: event, Headers: headers}
+}
+
+// ReceiveGithubWebhook verify signature of webhook of GitHub App and process it, the delivery is recorded with its outcome
+func (s *Service) ReceiveGithubWebhook(ctx context.Context, header http.Header, body []byte) (any, error) {
+	start := time.Now()
+	d := newDelivery(header.Get(github.EventTypeHeader), header)
+	d.DeliveryID = header.Get(github.DeliveryIDHeader)
+	d.Payload = string(body)
+
+	res, err := func() (any, error) {
+		secret := config.Current().GithubApp.WebhookSecret
+		signature := header.Get(github.SHA256SignatureHeader)
+		if signature == "" {
+			signature = header.Get(github.SHA1SignatureHeader)
+		}
+		contentType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+		var payload []byte
+		if err == nil {
+			payload, err = github.ValidatePayloadFromBody(contentType, bytes.NewReader(body), signature, []byte(secret))
+		}
+		if err != nil {
+			logrus.Errorf("github.ValidatePayload: %v", err)
+			d.Signature = SignatureInvalid
+			return nil, &webhookError{code: response.CodeParamsError, msg: "invalid payload", err: err}
+		}
+		d.Signature = SignatureValid
+		if secret == "" {
+			d.Signature = SignatureSkipped
+		}
+		d.Payload = string(payload)
+		return s.processGithubWebhook(ctx, d)
+	}()
+
+	s.finishDelivery(d, start, err)
+	return res, err
+}
+
+// RedeliverWebhook process payload of delivery again, a new delivery is recorded for it
+func (s *Service) RedeliverWebhook(ctx context.Context, id int64) (*WebhookDelivery, error) {
+	origin, err := s.DetailDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	if origin.Event == EventInstallationCallback {
+		return nil, errors.New("installation callback can not be redelivered")
+	}
+	if origin.Signature != SignatureValid && origin.Signature != SignatureSkipped {
+		return nil, errors.New("delivery with invalid signature can not be redelivered")
+	}
+
+	start := time.Now()
+	d := &WebhookDelivery{
+		SCMType:      origin.SCMType,
+		Event:        origin.Event,
+		DeliveryID:   origin.DeliveryID,
+		Headers:      origin.Headers,
+		Payload:      origin.Payload,
+		Signature:    origin.Signature,
+		RedeliveryOf: origin.ID,
+	}
+	_, err = s.processGithubWebhook(ctx, d)
+	s.finishDelivery(d, start, err)
+	return d, nil
+}
+
+// processGithubWebhook process verified payload of delivery, installations update modules and git events trigger workflows
+func (s *Service) processGithubWebhook(ctx context.Context, d *WebhookDelivery) (any, error) {
+	payload := []byte(d.Payload)
+	meta := new(struct {
+		Action       string `json:"action"`
+		Installation struct {
+			ID int64 `json:"id"`
+		} `json:"installation"`
+	})
+	_ = json.Unmarshal(payload, meta)
+	d.Action, d.InstallationID = meta.Action, meta.Installation.ID
+
+	event, err := github.ParseWebHook(d.Event, payload)
+	if err != nil {
+		logrus.Errorf("github.ParseWebHook: %v", err)
+		return nil, &webhookError{code: response.CodeParamsError, msg: "invalid webhook event", err: err}
+	}
+
+	if gitEvent, ok := toGitEvent(event); ok {
+		// workflows are started by git events only if their signatures are verified
+		if d.Signature != SignatureValid {
+			logrus.Warnf("webhook secret of github app is not configured, %s event is ignored", d.Event)
+			return nil, &webhookError{code: response.CodeParamsError, msg: "webhook secret is not configured"}
+		}
+		runs, err := s.TriggerWorkflows(ctx, gitEvent)
+		if err != nil {
+			logrus.Errorf("TriggerWorkflows failed: %v", err)
+			return nil, &webhookError{code: response.CodeServerError, msg: "failed to trigger workflows", err: err}
+		}
+		return runs, nil
+	}
+
+	var installationID int64 = 0
+	var action string = ""
+	switch e := event.(type) {
+	case *github.InstallationEvent:
+		installationID = e.Installation.GetID()
+		action = e.GetAction()
+	case *github.InstallationRepositoriesEvent:
+		installationID = e.Installation.GetID()
+		action = e.GetAction()
+	}
+
+	logrus.Infof("installation: %+v", installationID)
+	logrus.Infof("action: %s", action)
+	if installationID == 0 {
+		return nil, nil
+	}
+
+	if err := s.UpdateGithubModule(ctx, action, installationID); err != nil {
+		logrus.Errorf("UpdateGithubModule failed: %v", err)
+		return nil, &webhookError{code: response.CodeServerError, msg: "failed to update module", err: err}
+	}
+	logrus.Infof("UpdateGithubModule success, event: %s, installationID: %d", d.Event, installationID)
+	return nil, nil
+}
+
+// HandleGithubAppCallback register installation of callback to owner, the callback is recorded as delivery
+func (s *Service) HandleGithubAppCallback(ctx context.Context, header http.Header, query url.Values, owner string) error {
+	start := time.Now()
+	d := newDelivery(EventInstallationCallback, header)
+	d.Action = query.Get("setup_action")
+	d.Payload = query.Encode()
+
+	err := func() error {
+		installationID, err := strconv.ParseInt(query.Get("installation_id"), 10, 64)
+		if err != nil {
+			logrus.Errorf("strconv.ParseInt: %v", err)
+			return &webhookError{code: response.CodeParamsError, msg: "invalid installation ID", err: err}
+		}
+		d.InstallationID = installationID
+		if err := s.RegisterInstallationID(ctx, installationID, owner); err != nil {
+			logrus.Errorf("RegisterInstallationID failed: %v", err)
+			return &webhookError{code: response.CodeServerError, msg: "failed to register installation ID", err: err}
+		}
+		logrus.Infof("RegisterInstallationID success, installationID: %d, user: %v", installationID, owner)
+		return nil
+	}()
+
+	s.finishDelivery(d, start, err)
+	return err
+}
+
+// finishDelivery record outcome and latency of delivery
+func (s *Service) finishDelivery(d *WebhookDelivery, start time.Time, err error) {
+	d.Latency = time.Since(start).Milliseconds()
+	d.Status = DeliveryStatusSuccess
+	if err != nil {
+		d.Status = DeliveryStatusFailure
+		d.Error = err.Error()
+		if len(d.Error) > maxDeliveryErrorLength {
+			d.Error = d.Error[:maxDeliveryErrorLength]
+		}
+	}
+	if err := s.deliveryDB.Insert(d); err != nil {
+		logrus.Errorf("record delivery of %s failed, error: %v", d.Event, err)
+	}
+}
+
+// PageDelivery page deliveries matched by filter, payloads are not returned
+func (s *Service) PageDelivery(page, size int, filter *WebhookDelivery) (*database.Pager[*WebhookDelivery], error) {
+	res, err := s.deliveryDB.Page(filter, int64(page), int64(size))
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range res.Data {
+		d.Payload = ""
+	}
+	return res, nil
+}
+
+// DetailDelivery get delivery with payload
+func (s *Service) DetailDelivery(id int64) (*WebhookDelivery, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("delivery %d not found", id)
+	}
+	d, err := s.deliveryDB.Detail(&WebhookDelivery{ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("delivery %d not found", id)
+	}
+	return d, nil
+}
@@ -0,0 +1,109 @@
+package module
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func deliveryHeader(deliveryID string, body []byte, secret string) http.Header {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("X-GitHub-Event", "push")
+	header.Set("X-GitHub-Delivery", deliveryID)
+	header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	header.Set("Authorization", "Bearer secret")
+	return header
+}
+
+func TestService_WebhookDelivery(t *testing.T) {
+	_, s := setupGithubTest(t)
+	body, _ := json.Marshal(map[string]any{
+		"ref":          "refs/heads/main",
+		"after":        "abc123",
+		"repository":   map[string]any{"name": "delivery", "owner": map[string]any{"login": "octo"}},
+		"installation": map[string]any{"id": 42},
+	})
+
+	if _, err := s.ReceiveGithubWebhook(context.Background(), deliveryHeader("d-invalid", body, "wrong-secret"), body); err == nil {
+		t.Errorf("expected error of invalid signature")
+	}
+	if _, err := s.ReceiveGithubWebhook(context.Background(), deliveryHeader("d-valid", body, testWebhookSecret), body); err != nil {
+		t.Fatalf("receive webhook failed: %v", err)
+	}
+
+	invalid, err := s.PageDelivery(1, 10, &WebhookDelivery{DeliveryID: "d-invalid"})
+	if err != nil || invalid.Total != 1 {
+		t.Fatalf("expected 1 delivery of invalid signature, got %+v, %v", invalid, err)
+	}
+	if d := invalid.Data[0]; d.Signature != SignatureInvalid || d.Status != DeliveryStatusFailure || d.Error == "" || d.Payload != "" {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+
+	valid, err := s.PageDelivery(1, 10, &WebhookDelivery{InstallationID: 42, Status: DeliveryStatusSuccess})
+	if err != nil || valid.Total != 1 {
+		t.Fatalf("expected 1 delivery of installation 42, got %+v, %v", valid, err)
+	}
+	d, err := s.DetailDelivery(valid.Data[0].ID)
+	if err != nil {
+		t.Fatalf("detail delivery failed: %v", err)
+	}
+	if d.DeliveryID != "d-valid" || d.Signature != SignatureValid || d.Action != "" || d.Payload != string(body) {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	if _, ok := d.Headers["Authorization"]; ok || d.Headers["X-Github-Event"] != "push" {
+		t.Errorf("unexpected headers %v", d.Headers)
+	}
+
+	redelivery, err := s.RedeliverWebhook(context.Background(), d.ID)
+	if err != nil {
+		t.Fatalf("redeliver failed: %v", err)
+	}
+	if redelivery.ID == d.ID || redelivery.RedeliveryOf != d.ID || redelivery.Status != DeliveryStatusSuccess || redelivery.InstallationID != 42 {
+		t.Errorf("unexpected redelivery %+v", redelivery)
+	}
+	if _, err := s.RedeliverWebhook(context.Background(), invalid.Data[0].ID); err == nil {
+		t.Errorf("expected error of redelivering delivery with invalid signature")
+	}
+	if _, err := s.RedeliverWebhook(context.Background(), 0); err == nil {
+		t.Errorf("expected error of redelivering delivery not found")
+	}
+}
+
+func TestService_GithubAppCallbackDelivery(t *testing.T) {
+	s := setupTest(t)
+	if err := s.HandleGithubAppCallback(context.Background(), http.Header{}, url.Values{"installation_id": {"x"}, "setup_action": {"install"}}, "user-6"); err == nil {
+		t.Fatalf("expected error of invalid installation id")
+	}
+	if err := s.HandleGithubAppCallback(context.Background(), http.Header{}, url.Values{"installation_id": {"43"}, "setup_action": {"install"}}, "user-6"); err != nil {
+		t.Fatalf("handle callback failed: %v", err)
+	}
+
+	res, err := s.PageDelivery(1, 10, &WebhookDelivery{Event: EventInstallationCallback})
+	if err != nil || res.Total != 2 {
+		t.Fatalf("expected 2 callback deliveries, got %+v, %v", res, err)
+	}
+	statuses := map[string]int64{}
+	for _, d := range res.Data {
+		statuses[d.Status] = d.InstallationID
+	}
+	if id, ok := statuses[DeliveryStatusSuccess]; !ok || id != 43 {
+		t.Errorf("expected successful callback of installation 43, got %v", statuses)
+	}
+	if _, ok := statuses[DeliveryStatusFailure]; !ok {
+		t.Errorf("expected failed callback recorded, got %v", statuses)
+	}
+	if _, err := s.RedeliverWebhook(context.Background(), res.Data[0].ID); err == nil {
+		t.Errorf("expected error of redelivering callback")
+	}
+	if _, err := s.installationIDRelationDB.Detail(&InstallationIDRelation{InstallationID: 43, Owner: "user-6"}); err != nil {
+		t.Errorf("expected installation registered, got %v", err)
+	}
+}
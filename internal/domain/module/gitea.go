@@ -0,0 +1,154 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const giteaPageSize = 50
+
+// giteaProvider lists repositories which the account of connection can reach by api of gitea
+type giteaProvider struct {
+	baseURL       string
+	authorization string
+	client        *http.Client
+}
+
+type giteaRepo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+	Language    string `json:"language"`
+	HtmlURL     string `json:"html_url"`
+	CloneURL    string `json:"clone_url"`
+	SSHURL      string `json:"ssh_url"`
+	Owner       struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+func newGiteaProvider(conn *SCMConnection, accessToken string) (SCMProvider, error) {
+	p := &giteaProvider{
+		baseURL:       strings.TrimSuffix(conn.BaseURL, "/"),
+		authorization: "token " + accessToken,
+		client:        scmHTTPClient,
+	}
+	if conn.AuthType == AuthTypeOAuth {
+		p.authorization = "Bearer " + accessToken
+	}
+	return p, nil
+}
+
+func (p *giteaProvider) ListRepos(ctx context.Context) ([]*Module, error) {
+	res := make([]*Module, 0)
+	for page := 1; ; page++ {
+		var repos []*giteaRepo
+		query := url.Values{"page": {fmt.Sprint(page)}, "limit": {fmt.Sprint(giteaPageSize)}}
+		if err := p.get(ctx, "/api/v1/user/repos?"+query.Encode(), &repos); err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			res = append(res, &Module{
+				Name:        repo.Name,
+				Owner:       repo.Owner.Login,
+				OwnerID:     repo.Owner.ID,
+				SCMType:     SCMTypeGitea,
+				Description: repo.Description,
+				Language:    repo.Language,
+				Private:     repo.Private,
+				HtmlURL:     repo.HtmlURL,
+				CloneURL:    repo.CloneURL,
+				SSHURL:      repo.SSHURL,
+			})
+		}
+		if len(repos) < giteaPageSize {
+			break
+		}
+	}
+	return res, nil
+}
+
+func (p *giteaProvider) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", p.authorization)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("GET %s: %s %s", path, resp.Status, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (p *giteaProvider) GetMetadata(ctx context.Context, m *Module) (*ModuleMetadata, error) {
+	repoPath := "/api/v1/repos/" + url.PathEscape(m.Owner) + "/" + url.PathEscape(m.Name)
+	repo := new(struct {
+		DefaultBranch string `json:"default_branch"`
+		OpenPRCounter int    `json:"open_pr_counter"`
+	})
+	if err := p.get(ctx, repoPath, repo); err != nil {
+		return nil, err
+	}
+	meta := &ModuleMetadata{DefaultBranch: repo.DefaultBranch, OpenPRCount: repo.OpenPRCounter, Branches: make(Branches, 0), Tags: make(Tags, 0)}
+
+	var branches []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			ID        string    `json:"id"`
+			Message   string    `json:"message"`
+			Timestamp time.Time `json:"timestamp"`
+			Author    struct {
+				Name string `json:"name"`
+			} `json:"author"`
+		} `json:"commit"`
+	}
+	if err := p.get(ctx, fmt.Sprintf("%s/branches?limit=%d", repoPath, maxBranches), &branches); err != nil {
+		return nil, err
+	}
+	for _, branch := range branches {
+		meta.Branches = append(meta.Branches, &Branch{
+			Name: branch.Name,
+			Commit: &Commit{
+				SHA:     branch.Commit.ID,
+				Message: firstLine(branch.Commit.Message),
+				Author:  branch.Commit.Author.Name,
+				Date:    branch.Commit.Timestamp,
+			},
+		})
+	}
+
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+	if err := p.get(ctx, fmt.Sprintf("%s/tags?limit=%d", repoPath, maxTags), &tags); err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		meta.Tags = append(meta.Tags, &Tag{Name: tag.Name, SHA: tag.Commit.SHA})
+	}
+
+	languages := make(map[string]int64)
+	if err := p.get(ctx, repoPath+"/languages", &languages); err != nil {
+		return nil, err
+	}
+	meta.Languages = languagePercentages(languages)
+	return meta, nil
+}
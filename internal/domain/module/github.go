@@ -0,0 +1,341 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/pipeline"
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v72/github"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+
+	// statusContextPrefix prefix of context of commit statuses, it is followed by title of workflow
+	statusContextPrefix = "aurora/"
+)
+
+// newGithubClient returns client of GitHub App installation
+func newGithubClient(installationID int64) (*github.Client, error) {
+	cfg := config.Current().GithubApp
+	itr, err := ghinstallation.NewKeyFromFile(http.DefaultTransport, cfg.AppID, installationID, cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.APIURL == "" {
+		return github.NewClient(&http.Client{Transport: itr, Timeout: scmRequestTimeout}), nil
+	}
+	baseURL, err := url.Parse(strings.TrimSuffix(cfg.APIURL, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	itr.BaseURL = strings.TrimSuffix(baseURL.String(), "/")
+	client := github.NewClient(&http.Client{Transport: itr, Timeout: scmRequestTimeout})
+	client.BaseURL = baseURL
+	return client, nil
+}
+
+// githubProvider lists repositories of GitHub App installation
+type githubProvider struct {
+	client         *github.Client
+	installationID int64
+}
+
+func newGithubProvider(installationID int64) (SCMProvider, error) {
+	client, err := newGithubClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+	return &githubProvider{client: client, installationID: installationID}, nil
+}
+
+func (p *githubProvider) ListRepos(ctx context.Context) ([]*Module, error) {
+	res := make([]*Module, 0)
+	opts := &github.ListOptions{PerPage: 100} // 每页最多100个
+
+	for {
+		repos, resp, err := p.client.Apps.ListRepos(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos.Repositories {
+			res = append(res, &Module{
+				Name:           repo.GetName(),
+				SCMType:        SCMTypeGithub,
+				Owner:          repo.GetOwner().GetLogin(),
+				OwnerID:        repo.GetOwner().GetID(),
+				Description:    repo.GetDescription(),
+				Language:       repo.GetLanguage(),
+				Private:        repo.GetPrivate(),
+				HtmlURL:        repo.GetHTMLURL(),
+				CloneURL:       repo.GetCloneURL(),
+				SSHURL:         repo.GetSSHURL(),
+				SVNURL:         repo.GetSVNURL(),
+				InstallationID: p.installationID,
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break // 没有更多页
+		}
+		opts.Page = resp.NextPage
+	}
+	return res, nil
+}
+
+func (p *githubProvider) GetMetadata(ctx context.Context, m *Module) (*ModuleMetadata, error) {
+	repo, _, err := p.client.Repositories.Get(ctx, m.Owner, m.Name)
+	if err != nil {
+		return nil, err
+	}
+	meta := &ModuleMetadata{DefaultBranch: repo.GetDefaultBranch(), Branches: make(Branches, 0), Tags: make(Tags, 0)}
+
+	branches, _, err := p.client.Repositories.ListBranches(ctx, m.Owner, m.Name, &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: maxBranches}})
+	if err != nil {
+		return nil, err
+	}
+	for _, branch := range branches {
+		// commits of listed branches contain sha only
+		commit, _, err := p.client.Repositories.GetCommit(ctx, m.Owner, m.Name, branch.GetCommit().GetSHA(), nil)
+		if err != nil {
+			return nil, err
+		}
+		meta.Branches = append(meta.Branches, &Branch{
+			Name: branch.GetName(),
+			Commit: &Commit{
+				SHA:     commit.GetSHA(),
+				Message: firstLine(commit.GetCommit().GetMessage()),
+				Author:  commit.GetCommit().GetAuthor().GetName(),
+				Date:    commit.GetCommit().GetAuthor().GetDate().Time,
+			},
+		})
+	}
+
+	tags, _, err := p.client.Repositories.ListTags(ctx, m.Owner, m.Name, &github.ListOptions{PerPage: maxTags})
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		meta.Tags = append(meta.Tags, &Tag{Name: tag.GetName(), SHA: tag.GetCommit().GetSHA()})
+	}
+
+	languages, _, err := p.client.Repositories.ListLanguages(ctx, m.Owner, m.Name)
+	if err != nil {
+		return nil, err
+	}
+	bytes := make(map[string]int64, len(languages))
+	for lang, b := range languages {
+		bytes[lang] = int64(b)
+	}
+	meta.Languages = languagePercentages(bytes)
+
+	// one pull request per page, so the last page is count of open pull requests
+	pulls, resp, err := p.client.PullRequests.List(ctx, m.Owner, m.Name, &github.PullRequestListOptions{State: "open", ListOptions: github.ListOptions{PerPage: 1}})
+	if err != nil {
+		return nil, err
+	}
+	meta.OpenPRCount = len(pulls)
+	if resp.LastPage > 0 {
+		meta.OpenPRCount = resp.LastPage
+	}
+	return meta, nil
+}
+
+// variables of workflow run triggered by event
+func (e *GitEvent) variables() map[string]string {
+	res := map[string]string{
+		"event":          e.Event,
+		"owner":          e.Owner,
+		"repo":           e.Owner + "/" + e.Repo,
+		"branch":         e.Branch,
+		"commitSha":      e.SHA,
+		"installationId": strconv.FormatInt(e.InstallationID, 10),
+	}
+	if e.PullRequest > 0 {
+		res["pullRequest"] = strconv.Itoa(e.PullRequest)
+	}
+	return res
+}
+
+// match returns whether trigger is fired by event
+func (t *WorkflowTrigger) match(e *GitEvent) bool {
+	return matchList(t.Events, e.Event) && matchList(t.Branches, e.Branch)
+}
+
+// matchList returns whether value is in comma separated list, empty list matches all values
+func matchList(list, value string) bool {
+	if strings.TrimSpace(list) == "" {
+		return true
+	}
+	items := strings.Split(list, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return slices.Contains(items, value)
+}
+
+// TriggerWorkflows start workflows linked to repository of event, pending commit statuses are reported for started runs
+func (s *Service) TriggerWorkflows(ctx context.Context, event *GitEvent) ([]*pipeline.Run, error) {
+	var modules []*Module
+	if err := s.moduleDB.DB.Where(&Module{Owner: event.Owner, Name: event.Repo, InstallationID: event.InstallationID}).Find(&modules).Error; err != nil {
+		return nil, err
+	}
+
+	runs := make([]*pipeline.Run, 0)
+	for _, m := range modules {
+		triggers, err := s.triggerDB.List(&WorkflowTrigger{ModuleID: m.ID})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range triggers {
+			if !t.match(event) {
+				continue
+			}
+			wf, err := pipeline.GetService().GetWorkflow(&pipeline.Workflow{ID: t.WorkflowID})
+			if err != nil {
+				logrus.Errorf("get workflow %s of module %s failed, error: %v", t.WorkflowID, m.Name, err)
+				continue
+			}
+
+			// pending status is reported before run starts, otherwise it may overwrite status of a finished run
+			pending := &pipeline.Run{Title: wf.Title, Status: pipeline.RunStatusRunning, Trigger: pipeline.TriggerGit, Variables: event.variables()}
+			if err := reportCommitStatus(ctx, pending); err != nil {
+				logrus.Errorf("report pending commit status of workflow %s failed, error: %v", t.WorkflowID, err)
+			}
+
+			run, err := pipeline.GetService().StartRunWithVariables(t.WorkflowID, pipeline.TriggerGit, event.Event, event.variables())
+			if err != nil {
+				logrus.Errorf("start workflow %s of module %s failed, error: %v", t.WorkflowID, m.Name, err)
+				pending.Status, pending.Error = pipeline.RunStatusCanceled, err.Error()
+				if err := reportCommitStatus(ctx, pending); err != nil {
+					logrus.Errorf("report commit status of workflow %s failed, error: %v", t.WorkflowID, err)
+				}
+				continue
+			}
+			logrus.Infof("workflow %s started by %s of %s/%s, run: %s", t.WorkflowID, event.Event, event.Owner, event.Repo, run.ID)
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// onRunFinished reports commit status of finished run triggered by git event
+func (s *Service) onRunFinished(run *pipeline.Run) {
+	if run.Trigger != pipeline.TriggerGit {
+		return
+	}
+	if err := reportCommitStatus(context.Background(), run); err != nil {
+		logrus.Errorf("report commit status of run %s failed, error: %v", run.ID, err)
+	}
+}
+
+// reportCommitStatus create commit status of run by the installation client
+func reportCommitStatus(ctx context.Context, run *pipeline.Run) error {
+	installationID, err := strconv.ParseInt(run.Variables["installationId"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid installation id %s", run.Variables["installationId"])
+	}
+	owner, repo, ok := strings.Cut(run.Variables["repo"], "/")
+	if !ok || run.Variables["commitSha"] == "" {
+		return fmt.Errorf("repo or commit of run %s not found", run.ID)
+	}
+
+	client, err := newGithubClient(installationID)
+	if err != nil {
+		return err
+	}
+	state, description := commitState(run)
+	_, _, err = client.Repositories.CreateStatus(ctx, owner, repo, run.Variables["commitSha"], &github.RepoStatus{
+		State:       github.Ptr(state),
+		Context:     github.Ptr(statusContextPrefix + run.Title),
+		Description: github.Ptr(description),
+	})
+	return err
+}
+
+// commitState state and description of commit status of run
+func commitState(run *pipeline.Run) (string, string) {
+	switch run.Status {
+	case pipeline.RunStatusSuccess:
+		return "success", "workflow succeeded"
+	case pipeline.RunStatusFailure:
+		return "failure", "workflow failed"
+	case pipeline.RunStatusCanceled:
+		if run.ID == "" {
+			return "error", "workflow failed to start"
+		}
+		return "error", "workflow canceled"
+	default:
+		return "pending", "workflow is running"
+	}
+}
+
+// ListWorkflowTrigger list workflow triggers of module owned by owner
+func (s *Service) ListWorkflowTrigger(moduleID int64, owner string) ([]*WorkflowTrigger, error) {
+	if _, err := s.getOwnedModule(moduleID, owner); err != nil {
+		return nil, err
+	}
+	return s.triggerDB.List(&WorkflowTrigger{ModuleID: moduleID})
+}
+
+// AddWorkflowTrigger link workflow to module owned by owner
+func (s *Service) AddWorkflowTrigger(trigger *WorkflowTrigger, owner string) error {
+	if _, err := s.getOwnedModule(trigger.ModuleID, owner); err != nil {
+		return err
+	}
+	for _, e := range strings.Split(trigger.Events, ",") {
+		if e = strings.TrimSpace(e); e != "" && e != EventPush && e != EventPullRequest {
+			return fmt.Errorf("unsupported event %s", e)
+		}
+	}
+	if trigger.WorkflowID == "" {
+		return fmt.Errorf("workflow of trigger is required")
+	}
+	if _, err := pipeline.GetService().GetWorkflow(&pipeline.Workflow{ID: trigger.WorkflowID}); err != nil {
+		return fmt.Errorf("workflow %s not found", trigger.WorkflowID)
+	}
+	trigger.ID = 0
+	return s.triggerDB.Insert(trigger)
+}
+
+// DeleteWorkflowTrigger delete workflow trigger of module owned by owner
+func (s *Service) DeleteWorkflowTrigger(id int64, owner string) error {
+	trigger, err := s.triggerDB.Detail(&WorkflowTrigger{ID: id})
+	if err != nil {
+		return err
+	}
+	if _, err := s.getOwnedModule(trigger.ModuleID, owner); err != nil {
+		return err
+	}
+	return s.triggerDB.Delete(&WorkflowTrigger{ID: id})
+}
+
+// getOwnedModule get module whose installation or scm connection is registered by owner
+func (s *Service) getOwnedModule(id int64, owner string) (*Module, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("module %d not found", id)
+	}
+	m, err := s.moduleDB.Detail(&Module{ID: id})
+	if err != nil {
+		return nil, err
+	}
+	if m.ConnectionID != 0 {
+		if _, err := s.getOwnedConnection(m.ConnectionID, owner); err != nil {
+			return nil, fmt.Errorf("module %d not found", id)
+		}
+		return m, nil
+	}
+	if _, err := s.installationIDRelationDB.Detail(&InstallationIDRelation{InstallationID: m.InstallationID, Owner: owner}); err != nil {
+		return nil, fmt.Errorf("module %d not found", id)
+	}
+	return m, nil
+}
@@ -0,0 +1,345 @@
+package module
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/pipeline"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
+	"github.com/MR5356/aurora/internal/response"
+	"github.com/MR5356/go-workflow"
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v61/github"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "test-secret"
+
+// fakeGithub is a local GitHub API server which issues installation tokens and records commit statuses
+type fakeGithub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []string
+	pings    []string
+}
+
+func newFakeGithub(t *testing.T) *fakeGithub {
+	f := &fakeGithub{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /app/installations/1/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":"ghs_test","expires_at":"2099-01-01T00:00:00Z"}`))
+	})
+	mux.HandleFunc("POST /repos/octo/hello/statuses/{sha}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token ghs_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		status := make(map[string]string)
+		json.NewDecoder(r.Body).Decode(&status)
+		f.mu.Lock()
+		f.statuses = append(f.statuses, r.PathValue("sha")+" "+status["state"]+" "+status["context"])
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("GET /installation/repositories", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"total_count":1,"repositories":[{"name":"hello","owner":{"login":"octo","id":1},"html_url":"https://github.com/octo/hello"}]}`))
+	})
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.pings = append(f.pings, r.URL.RawQuery)
+		f.mu.Unlock()
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeGithub) waitStatuses(t *testing.T, n int) []string {
+	for i := 0; i < 250; i++ {
+		f.mu.Lock()
+		res := append([]string{}, f.statuses...)
+		f.mu.Unlock()
+		if len(res) >= n {
+			return res
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("expected %d commit statuses", n)
+	return nil
+}
+
+var setupOnce sync.Once
+
+// setupTest initialize database, event bus and services once, as subscriptions of services can not be repeated
+func setupTest(t *testing.T) *Service {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	setupOnce.Do(func() {
+		database.NewDatabase(cfg)
+		eventbus.NewEventBus(cfg)
+		if err := pipeline.GetService().Initialize(); err != nil {
+			t.Fatalf("initialize pipeline failed: %v", err)
+		}
+		if err := GetService().Initialize(); err != nil {
+			t.Fatalf("initialize module failed: %v", err)
+		}
+	})
+	return GetService()
+}
+
+func setupGithubTest(t *testing.T) (*fakeGithub, *Service) {
+	s := setupTest(t)
+	cfg := config.Current()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600); err != nil {
+		t.Fatalf("write key failed: %v", err)
+	}
+
+	fake := newFakeGithub(t)
+	cfg.GithubApp.PrivateKey = keyFile
+	cfg.GithubApp.APIURL = fake.URL
+	cfg.GithubApp.WebhookSecret = testWebhookSecret
+	return fake, s
+}
+
+func sendWebhook(t *testing.T, engine *gin.Engine, event string, payload any, secret string) *response.Response {
+	body, _ := json.Marshal(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/module/github/app/install", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	res := new(response.Response)
+	if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("invalid response %s", w.Body.String())
+	}
+	return res
+}
+
+func TestGithubWebhookTriggerWorkflow(t *testing.T) {
+	fake, s := setupGithubTest(t)
+
+	m := &Module{Name: "hello", Owner: "octo", OwnerID: 1, SCMType: "GitHub", InstallationID: 1}
+	if err := s.moduleDB.Insert(m); err != nil {
+		t.Fatalf("insert module failed: %v", err)
+	}
+	if err := s.RegisterInstallationID(context.Background(), 1, "user-1"); err != nil {
+		t.Fatalf("register installation failed: %v", err)
+	}
+
+	wfr := &pipeline.WorkflowRequest{
+		Workflow: &pipeline.Workflow{Title: "ci", Owner: "user-1"},
+		WorkflowDAG: &workflow.WorkflowDAG{Nodes: []*workflow.Node{{
+			Id:     "github-ping",
+			Uses:   pipeline.NodeTypeHTTP,
+			Params: []*workflow.TaskParam{{Key: "url", Value: fake.URL + "/ping?sha={{ .Variables.commitSha }}&branch={{ .Variables.branch }}"}},
+		}}},
+	}
+	if err := pipeline.GetService().AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+	if err := s.AddWorkflowTrigger(&WorkflowTrigger{ModuleID: m.ID, WorkflowID: wfr.ID, Events: EventPush, Branches: "main"}, "user-2"); err == nil {
+		t.Errorf("expected error of adding trigger to module not owned")
+	}
+	if err := s.AddWorkflowTrigger(&WorkflowTrigger{ModuleID: m.ID, WorkflowID: wfr.ID, Events: EventPush, Branches: "main"}, "user-1"); err != nil {
+		t.Fatalf("add trigger failed: %v", err)
+	}
+
+	engine := gin.New()
+	NewController().RegisterRoute(engine.Group(""))
+	push := func(ref string) map[string]any {
+		return map[string]any{
+			"ref":          ref,
+			"after":        "abc123",
+			"repository":   map[string]any{"name": "hello", "owner": map[string]any{"login": "octo"}},
+			"installation": map[string]any{"id": 1},
+		}
+	}
+
+	if res := sendWebhook(t, engine, "push", push("refs/heads/main"), "wrong-secret"); res.Code == response.CodeSuccess {
+		t.Errorf("expected error of invalid signature")
+	}
+
+	res := sendWebhook(t, engine, "push", push("refs/heads/dev"), testWebhookSecret)
+	if runs, ok := res.Data.([]any); res.Code != response.CodeSuccess || !ok || len(runs) != 0 {
+		t.Errorf("expected no run of branch not matched, got %+v", res)
+	}
+
+	res = sendWebhook(t, engine, "push", push("refs/heads/main"), testWebhookSecret)
+	if runs, ok := res.Data.([]any); res.Code != response.CodeSuccess || !ok || len(runs) != 1 {
+		t.Fatalf("expected 1 run started, got %+v", res)
+	}
+
+	statuses := fake.waitStatuses(t, 2)
+	want := []string{"abc123 pending aurora/ci", "abc123 success aurora/ci"}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("expected commit statuses %v, got %v", want, statuses)
+			break
+		}
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.pings) != 1 || fake.pings[0] != "sha=abc123&branch=main" {
+		t.Errorf("expected run variables rendered in params, got %v", fake.pings)
+	}
+}
+
+func TestService_UpdateGithubModule(t *testing.T) {
+	fake, s := setupGithubTest(t)
+	ctx := context.Background()
+	if err := s.RegisterInstallationID(ctx, 1, "user-1"); err != nil {
+		t.Fatalf("register installation failed: %v", err)
+	}
+	if err := s.UpdateGithubModule(ctx, "created", 1); err != nil {
+		t.Fatalf("update github module failed: %v", err)
+	}
+	m, err := s.moduleDB.Detail(&Module{InstallationID: 1, Owner: "octo", Name: "hello"})
+	if err != nil {
+		t.Fatalf("expected module of installation repository, error: %v", err)
+	}
+
+	// module of repository removed from installation
+	gone := &Module{Name: "gone", Owner: "octo", OwnerID: 1, SCMType: SCMTypeGithub, InstallationID: 1}
+	if err := s.moduleDB.Insert(gone); err != nil {
+		t.Fatalf("insert module failed: %v", err)
+	}
+
+	wfr := &pipeline.WorkflowRequest{
+		Workflow: &pipeline.Workflow{Title: "reinstall", Owner: "user-1"},
+		WorkflowDAG: &workflow.WorkflowDAG{Nodes: []*workflow.Node{{
+			Id:     "github-ping",
+			Uses:   pipeline.NodeTypeHTTP,
+			Params: []*workflow.TaskParam{{Key: "url", Value: fake.URL + "/ping"}},
+		}}},
+	}
+	if err := pipeline.GetService().AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+	for _, id := range []int64{m.ID, gone.ID} {
+		if err := s.AddWorkflowTrigger(&WorkflowTrigger{ModuleID: id, WorkflowID: wfr.ID, Events: EventPush}, "user-1"); err != nil {
+			t.Fatalf("add trigger failed: %v", err)
+		}
+	}
+
+	if err := s.UpdateGithubModule(ctx, "added", 1); err != nil {
+		t.Fatalf("update github module again failed: %v", err)
+	}
+	if again, err := s.moduleDB.Detail(&Module{InstallationID: 1, Owner: "octo", Name: "hello"}); err != nil || again.ID != m.ID {
+		t.Errorf("expected module %d kept, got %v, error: %v", m.ID, again, err)
+	}
+	if _, err := s.moduleDB.Detail(&Module{ID: gone.ID}); err == nil {
+		t.Errorf("expected module of removed repository deleted")
+	}
+	if triggers, _ := s.triggerDB.List(&WorkflowTrigger{ModuleID: gone.ID}); len(triggers) != 0 {
+		t.Errorf("expected triggers of removed module deleted, got %d", len(triggers))
+	}
+
+	engine := gin.New()
+	NewController().RegisterRoute(engine.Group(""))
+	res := sendWebhook(t, engine, "push", map[string]any{
+		"ref":          "refs/heads/main",
+		"after":        "def456",
+		"repository":   map[string]any{"name": "hello", "owner": map[string]any{"login": "octo"}},
+		"installation": map[string]any{"id": 1},
+	}, testWebhookSecret)
+	runs, _ := res.Data.([]any)
+	triggered := false
+	for _, run := range runs {
+		if r, ok := run.(map[string]any); ok && r["workflowId"] == wfr.ID {
+			triggered = true
+		}
+	}
+	if res.Code != response.CodeSuccess || !triggered {
+		t.Errorf("expected workflow triggered after installation event, got %+v", res)
+	}
+}
+
+func mustParse(t *testing.T, eventType, payload string) any {
+	event, err := github.ParseWebHook(eventType, []byte(payload))
+	if err != nil {
+		t.Fatalf("parse %s event failed: %v", eventType, err)
+	}
+	return event
+}
+
+func TestToGitEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event any
+		want  *GitEvent
+	}{
+		{
+			name:  "pull request opened",
+			event: mustParse(t, "pull_request", `{"action":"opened","number":7,"pull_request":{"head":{"ref":"feature","sha":"def456"}},"repository":{"name":"hello","owner":{"login":"octo"}},"installation":{"id":1}}`),
+			want:  &GitEvent{Event: EventPullRequest, InstallationID: 1, Owner: "octo", Repo: "hello", Branch: "feature", SHA: "def456", PullRequest: 7},
+		},
+		{
+			name:  "pull request closed",
+			event: mustParse(t, "pull_request", `{"action":"closed","number":7}`),
+		},
+		{
+			name:  "tag pushed",
+			event: mustParse(t, "push", `{"ref":"refs/tags/v1.0.0","after":"abc123"}`),
+		},
+		{
+			name:  "branch deleted",
+			event: mustParse(t, "push", `{"ref":"refs/heads/main","deleted":true}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := toGitEvent(tt.event)
+			if (tt.want != nil) != ok {
+				t.Fatalf("toGitEvent() ok = %v, want %v", ok, tt.want != nil)
+			}
+			if tt.want != nil && *got != *tt.want {
+				t.Errorf("toGitEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowTriggerMatch(t *testing.T) {
+	trigger := &WorkflowTrigger{Events: "push, pull_request", Branches: "main,release"}
+	if !trigger.match(&GitEvent{Event: EventPullRequest, Branch: "release"}) {
+		t.Errorf("expected trigger matched")
+	}
+	if trigger.match(&GitEvent{Event: EventPush, Branch: "dev"}) {
+		t.Errorf("expected trigger not matched of branch dev")
+	}
+	if !(&WorkflowTrigger{}).match(&GitEvent{Event: EventPush, Branch: "dev"}) {
+		t.Errorf("expected empty trigger matched all events")
+	}
+	if got := (&GitEvent{Owner: "octo", Repo: "hello"}).variables()["repo"]; got != "octo/hello" {
+		t.Errorf("expected repo octo/hello, got %s", got)
+	}
+}
@@ -0,0 +1,122 @@
+package module
+
+import (
+	"context"
+	"github.com/xanzy/go-gitlab"
+)
+
+// gitlabProvider lists projects which the account of connection is a member of
+type gitlabProvider struct {
+	client *gitlab.Client
+}
+
+func newGitlabProvider(conn *SCMConnection, accessToken string) (SCMProvider, error) {
+	var client *gitlab.Client
+	var err error
+	if conn.AuthType == AuthTypeOAuth {
+		client, err = gitlab.NewOAuthClient(accessToken, gitlab.WithBaseURL(conn.BaseURL), gitlab.WithHTTPClient(scmHTTPClient))
+	} else {
+		client, err = gitlab.NewClient(accessToken, gitlab.WithBaseURL(conn.BaseURL), gitlab.WithHTTPClient(scmHTTPClient))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &gitlabProvider{client: client}, nil
+}
+
+func (p *gitlabProvider) ListRepos(ctx context.Context) ([]*Module, error) {
+	res := make([]*Module, 0)
+	opts := &gitlab.ListProjectsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		Membership:  gitlab.Ptr(true),
+		Archived:    gitlab.Ptr(false),
+	}
+
+	for {
+		projects, resp, err := p.client.Projects.ListProjects(opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			m := &Module{
+				Name:        project.Path,
+				SCMType:     SCMTypeGitlab,
+				Description: project.Description,
+				Private:     project.Visibility != gitlab.PublicVisibility,
+				HtmlURL:     project.WebURL,
+				CloneURL:    project.HTTPURLToRepo,
+				SSHURL:      project.SSHURLToRepo,
+			}
+			if project.Namespace != nil {
+				m.Owner = project.Namespace.FullPath
+				m.OwnerID = int64(project.Namespace.ID)
+			}
+			res = append(res, m)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return res, nil
+}
+
+func (p *gitlabProvider) GetMetadata(ctx context.Context, m *Module) (*ModuleMetadata, error) {
+	pid := m.Owner + "/" + m.Name
+	project, _, err := p.client.Projects.GetProject(pid, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	meta := &ModuleMetadata{DefaultBranch: project.DefaultBranch, Branches: make(Branches, 0), Tags: make(Tags, 0), Languages: make(Languages)}
+
+	branches, _, err := p.client.Branches.ListBranches(pid, &gitlab.ListBranchesOptions{ListOptions: gitlab.ListOptions{PerPage: maxBranches}}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, branch := range branches {
+		b := &Branch{Name: branch.Name}
+		if c := branch.Commit; c != nil {
+			b.Commit = &Commit{SHA: c.ID, Message: firstLine(c.Message), Author: c.AuthorName}
+			if c.CommittedDate != nil {
+				b.Commit.Date = *c.CommittedDate
+			}
+		}
+		meta.Branches = append(meta.Branches, b)
+	}
+
+	tags, _, err := p.client.Tags.ListTags(pid, &gitlab.ListTagsOptions{ListOptions: gitlab.ListOptions{PerPage: maxTags}}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		t := &Tag{Name: tag.Name}
+		if tag.Commit != nil {
+			t.SHA = tag.Commit.ID
+		}
+		meta.Tags = append(meta.Tags, t)
+	}
+
+	languages, _, err := p.client.Projects.GetProjectLanguages(pid, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if languages != nil {
+		for lang, percentage := range *languages {
+			meta.Languages[lang] = roundPercentage(float64(percentage))
+		}
+	}
+
+	mrs, resp, err := p.client.MergeRequests.ListProjectMergeRequests(pid, &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 1},
+		State:       gitlab.Ptr("opened"),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	meta.OpenPRCount = len(mrs)
+	if resp.TotalItems > 0 {
+		meta.OpenPRCount = resp.TotalItems
+	}
+	return meta, nil
+}
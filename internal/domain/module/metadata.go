@@ -0,0 +1,153 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// metadataSyncSpec cron of background sync of metadata of all modules
+	metadataSyncSpec = "0 0 * * * *"
+
+	// maxBranches and maxTags are the most branches and tags kept in metadata,
+	// latest commit of each branch costs a request of GitHub api
+	maxBranches = 30
+	maxTags     = 30
+)
+
+// providerOf returns provider of SCM which module is synced from
+func (s *Service) providerOf(ctx context.Context, m *Module) (SCMProvider, error) {
+	if m.ConnectionID == 0 {
+		return newGithubProvider(m.InstallationID)
+	}
+	conn, err := s.scmConnectionDB.Detail(&SCMConnection{ID: m.ConnectionID})
+	if err != nil {
+		return nil, fmt.Errorf("connection %d of module %d not found", m.ConnectionID, m.ID)
+	}
+	return s.connectionProvider(ctx, conn)
+}
+
+// GetModuleDetail get module owned by owner with its metadata
+func (s *Service) GetModuleDetail(id int64, owner string) (*ModuleDetail, error) {
+	if _, err := s.getOwnedModule(id, owner); err != nil {
+		return nil, err
+	}
+	return s.DetailModule(id)
+}
+
+// DetailModule get module with its metadata
+func (s *Service) DetailModule(id int64) (*ModuleDetail, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("module %d not found", id)
+	}
+	m, err := s.moduleDB.Detail(&Module{ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("module %d not found", id)
+	}
+	res := &ModuleDetail{Module: m}
+	meta, err := s.metadataDB.Detail(&ModuleMetadata{ModuleID: id})
+	if err == nil {
+		res.Metadata = meta
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SyncModuleMetadataOfOwner sync metadata of module owned by owner now
+func (s *Service) SyncModuleMetadataOfOwner(ctx context.Context, id int64, owner string) (*ModuleMetadata, error) {
+	m, err := s.getOwnedModule(id, owner)
+	if err != nil {
+		return nil, err
+	}
+	return s.SyncModuleMetadata(ctx, m)
+}
+
+// SyncModuleMetadata fetch metadata of module from its SCM and save it, metadata synced before is kept if fetching fails
+func (s *Service) SyncModuleMetadata(ctx context.Context, m *Module) (*ModuleMetadata, error) {
+	meta, err := func() (*ModuleMetadata, error) {
+		provider, err := s.providerOf(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		return provider.GetMetadata(ctx, m)
+	}()
+
+	if err != nil {
+		old, derr := s.metadataDB.Detail(&ModuleMetadata{ModuleID: m.ID})
+		if derr != nil {
+			old = &ModuleMetadata{}
+		}
+		meta = old
+		meta.SyncError = err.Error()
+		if len(meta.SyncError) > maxSyncErrorLength {
+			meta.SyncError = meta.SyncError[:maxSyncErrorLength]
+		}
+	}
+	meta.ModuleID = m.ID
+	meta.SyncedAt = time.Now()
+
+	if serr := s.metadataDB.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(meta).Error; serr != nil {
+		return nil, serr
+	}
+	return meta, err
+}
+
+// syncAllMetadata sync metadata of all modules, metadata of deleted modules is removed
+func (s *Service) syncAllMetadata() {
+	if !s.metadataSyncing.CompareAndSwap(false, true) {
+		logrus.Warnf("metadata of modules is syncing, skip")
+		return
+	}
+	defer s.metadataSyncing.Store(false)
+
+	db := s.metadataDB.DB
+	if err := db.Where("module_id NOT IN (?)", db.Model(&Module{}).Select("id")).Delete(&ModuleMetadata{}).Error; err != nil {
+		logrus.Errorf("delete metadata of deleted modules failed, error: %v", err)
+	}
+
+	modules, err := s.moduleDB.List(&Module{})
+	if err != nil {
+		logrus.Errorf("list modules failed, error: %v", err)
+		return
+	}
+	for _, m := range modules {
+		ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+		if _, err := s.SyncModuleMetadata(ctx, m); err != nil {
+			logrus.Errorf("sync metadata of module %s/%s failed, error: %v", m.Owner, m.Name, err)
+		}
+		cancel()
+	}
+}
+
+// languagePercentages convert bytes of each language to percentages
+func languagePercentages(bytes map[string]int64) Languages {
+	var total int64
+	for _, b := range bytes {
+		total += b
+	}
+	res := make(Languages, len(bytes))
+	for lang, b := range bytes {
+		if total > 0 {
+			res[lang] = roundPercentage(float64(b) * 100 / float64(total))
+		}
+	}
+	return res
+}
+
+func roundPercentage(p float64) float64 {
+	return math.Round(p*10) / 10
+}
+
+// firstLine returns first line of commit message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(message, "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,115 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/response"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestService_SyncModuleMetadata(t *testing.T) {
+	s := setupTest(t)
+	fake := newFakeSCM(t)
+	fake.projects = []map[string]any{gitlabProject("gl-meta", "")}
+	fake.repos = []map[string]any{{"name": "gt-meta", "owner": map[string]any{"id": 5, "login": "dev"}}}
+
+	gitlabConn := &SCMConnection{Owner: "user-7", SCMType: SCMTypeGitlab, BaseURL: fake.URL, AuthType: AuthTypeToken, Token: "glpat-test"}
+	giteaConn := &SCMConnection{Owner: "user-7", SCMType: SCMTypeGitea, BaseURL: fake.URL, AuthType: AuthTypeToken, Token: "gitea-test"}
+	for _, conn := range []*SCMConnection{gitlabConn, giteaConn} {
+		if err := s.AddSCMConnection(context.Background(), conn); err != nil || conn.LastSyncError != "" {
+			t.Fatalf("add connection failed: %v, %s", err, conn.LastSyncError)
+		}
+	}
+	glModule, _ := s.moduleDB.Detail(&Module{Name: "gl-meta"})
+	gtModule, _ := s.moduleDB.Detail(&Module{Name: "gt-meta"})
+	date := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	meta, err := s.SyncModuleMetadataOfOwner(context.Background(), glModule.ID, "user-7")
+	if err != nil {
+		t.Fatalf("sync metadata of gitlab module failed: %v", err)
+	}
+	if meta.DefaultBranch != "main" || meta.OpenPRCount != 2 || len(meta.Tags) != 1 || meta.Tags[0].SHA != "g1" || meta.Languages["Go"] != 75 {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+	if len(meta.Branches) != 1 || *meta.Branches[0].Commit != (Commit{SHA: "g1", Message: "feat: gitlab", Author: "gl", Date: date}) {
+		t.Errorf("unexpected branches %+v", meta.Branches)
+	}
+
+	if _, err := s.SyncModuleMetadataOfOwner(context.Background(), gtModule.ID, "user-8"); err == nil {
+		t.Errorf("expected error of syncing module not owned")
+	}
+	meta, err = s.SyncModuleMetadataOfOwner(context.Background(), gtModule.ID, "user-7")
+	if err != nil {
+		t.Fatalf("sync metadata of gitea module failed: %v", err)
+	}
+	if meta.OpenPRCount != 3 || meta.Languages["Go"] != 75 || meta.Languages["Shell"] != 25 || len(meta.Branches) != 1 || meta.Branches[0].Commit.Date != date {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+
+	// metadata synced before is kept if SCM is not available
+	fake.mu.Lock()
+	fake.broken = true
+	fake.mu.Unlock()
+	if _, err := s.SyncModuleMetadata(context.Background(), gtModule); err == nil {
+		t.Errorf("expected error of syncing metadata from broken SCM")
+	}
+	detail, err := s.GetModuleDetail(gtModule.ID, "user-7")
+	if err != nil || detail.Metadata == nil {
+		t.Fatalf("get module detail failed: %+v, %v", detail, err)
+	}
+	if detail.Metadata.SyncError == "" || detail.Metadata.OpenPRCount != 3 || len(detail.Metadata.Tags) != 1 || detail.Metadata.Tags[0].Name != "v1" {
+		t.Errorf("expected metadata kept with sync error, got %+v", detail.Metadata)
+	}
+
+	if err := s.DeleteSCMConnection(gitlabConn.ID, "user-7"); err != nil {
+		t.Fatalf("delete connection failed: %v", err)
+	}
+	s.syncAllMetadata()
+	if _, err := s.metadataDB.Detail(&ModuleMetadata{ModuleID: glModule.ID}); err == nil {
+		t.Errorf("expected metadata of deleted module removed")
+	}
+}
+
+func TestController_DetailModule(t *testing.T) {
+	s := setupTest(t)
+	fake := newFakeSCM(t)
+	fake.repos = []map[string]any{{"name": "gt-detail", "owner": map[string]any{"id": 5, "login": "dev"}}}
+	if err := s.AddSCMConnection(context.Background(), &SCMConnection{Owner: "user-9", SCMType: SCMTypeGitea, BaseURL: fake.URL, AuthType: AuthTypeToken, Token: "gitea-test"}); err != nil {
+		t.Fatalf("add connection failed: %v", err)
+	}
+	m, _ := s.moduleDB.Detail(&Module{Name: "gt-detail"})
+
+	engine := gin.New()
+	engine.Use(func(ctx *gin.Context) {
+		ctx.Set(config.ContextUserIDKey, ctx.GetHeader("X-User"))
+	})
+	NewController().RegisterRoute(engine.Group(""))
+	get := func(path, userID string) *response.Response {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User", userID)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		res := new(response.Response)
+		if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+			t.Fatalf("invalid response %s", w.Body.String())
+		}
+		return res
+	}
+
+	res := get("/module/"+strconv.FormatInt(m.ID, 10), "user-9")
+	if data, ok := res.Data.(map[string]any); res.Code != response.CodeSuccess || !ok || data["name"] != "gt-detail" || data["metadata"] != nil {
+		t.Errorf("unexpected module detail %+v", res)
+	}
+	if res := get("/module/"+strconv.FormatInt(m.ID, 10), "user-10"); res.Code != response.CodeNotFound {
+		t.Errorf("expected module of other user not found, got %+v", res)
+	}
+	if res := get("/module/list", "user-9"); res.Code != response.CodeSuccess {
+		t.Errorf("expected module list, got %+v", res)
+	}
+}
@@ -1,15 +1,28 @@
 package module
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 )
 
+const (
+	SCMTypeGithub = "GitHub"
+	SCMTypeGitlab = "GitLab"
+	SCMTypeGitea  = "Gitea"
+
+	AuthTypeToken = "token"
+	AuthTypeOAuth = "oauth"
+)
+
+// Module is a repository synced from GitHub App installation or SCMConnection,
+// name is only unique in owner of repository of installation or connection
 type Module struct {
 	ID             int64  `gorm:"primaryKey" json:"id"`
-	Name           string `gorm:"unique;not null;length:64" json:"name"`
-	Owner          string `gorm:"not null;length:64" json:"owner"`
+	Name           string `gorm:"not null;length:64;uniqueIndex:idx_module_repo" json:"name"`
+	Owner          string `gorm:"not null;length:64;uniqueIndex:idx_module_repo" json:"owner"`
 	OwnerID        int64  `gorm:"not null" json:"ownerID"`
-	SCMType        string `gorm:"not null;length:32" json:"scmType"`
+	SCMType        string `gorm:"not null;length:32;uniqueIndex:idx_module_repo" json:"scmType"`
 	Description    string `gorm:"length:1024" json:"description"`
 	Language       string `gorm:"length:128" json:"language"`
 	Private        bool   `json:"private"`
@@ -17,7 +30,8 @@ type Module struct {
 	CloneURL       string `gorm:"length:256" json:"cloneURL"`
 	SSHURL         string `gorm:"length:256" json:"sshURL"`
 	SVNURL         string `gorm:"length:256" json:"svnURL"`
-	InstallationID int64  `gorm:"not null" json:"installationID"`
+	InstallationID int64  `gorm:"not null;uniqueIndex:idx_module_repo" json:"installationID"`
+	ConnectionID   int64  `gorm:"index;uniqueIndex:idx_module_repo" json:"connectionID"` // id of SCMConnection of GitLab and Gitea modules
 
 	CreatedAt time.Time `json:"createdAt" swaggerignore:"true"`
 	UpdatedAt time.Time `json:"updatedAt" swaggerignore:"true"`
@@ -27,3 +41,161 @@ type InstallationIDRelation struct {
 	InstallationID int64  `gorm:"primaryKey;not null" json:"installationID"`
 	Owner          string `gorm:"not null;length:64" json:"owner"`
 }
+
+// WorkflowTrigger links a pipeline workflow to module, the workflow runs on matched git events of module
+type WorkflowTrigger struct {
+	ID         int64  `gorm:"primaryKey" json:"id"`
+	ModuleID   int64  `gorm:"not null;index" json:"moduleID"`
+	WorkflowID string `gorm:"not null;length:64" json:"workflowID" validate:"required"`
+	Events     string `gorm:"length:64" json:"events" example:"push,pull_request"` // comma separated events, empty means all
+	Branches   string `gorm:"length:256" json:"branches" example:"main"`           // comma separated branches, empty means all
+
+	CreatedAt time.Time `json:"createdAt" swaggerignore:"true"`
+	UpdatedAt time.Time `json:"updatedAt" swaggerignore:"true"`
+}
+
+// GitEvent is a push or pull_request event of repository
+type GitEvent struct {
+	Event          string
+	InstallationID int64
+	Owner          string
+	Repo           string
+	Branch         string
+	SHA            string
+	PullRequest    int
+}
+
+// SCMConnection is a GitLab or Gitea account of user, repositories reachable by it are synced to modules
+type SCMConnection struct {
+	ID       int64  `gorm:"primaryKey" json:"id"`
+	Owner    string `gorm:"not null;length:64;index" json:"owner"`
+	SCMType  string `gorm:"not null;length:32" json:"scmType" validate:"oneof=GitLab Gitea"`
+	BaseURL  string `gorm:"not null;length:256" json:"baseURL" validate:"required,url" example:"https://gitlab.com"`
+	AuthType string `gorm:"not null;length:32" json:"authType" validate:"oneof=token oauth"`
+	OAuth    string `gorm:"length:64" json:"oauth"` // name of oauth config of oauth connection
+	SyncCron string `gorm:"length:64" json:"syncCron" example:"0 */30 * * * *"`
+
+	Token        string    `gorm:"length:2048" json:"token,omitempty"` // personal access token or oauth access token, it is never returned
+	RefreshToken string    `gorm:"length:2048" json:"-"`
+	TokenExpiry  time.Time `json:"-"`
+
+	LastSyncAt    time.Time `json:"lastSyncAt"`
+	LastSyncError string    `gorm:"length:1024" json:"lastSyncError"`
+
+	CreatedAt time.Time `json:"createdAt" swaggerignore:"true"`
+	UpdatedAt time.Time `json:"updatedAt" swaggerignore:"true"`
+}
+
+// WebhookDelivery is a received webhook or app installation callback of SCM, it is kept for debugging and redelivery
+type WebhookDelivery struct {
+	ID             int64   `gorm:"primaryKey" json:"id"`
+	SCMType        string  `gorm:"not null;length:32" json:"scmType"`
+	Event          string  `gorm:"length:64;index" json:"event" example:"push"`
+	Action         string  `gorm:"length:64" json:"action" example:"created"`
+	DeliveryID     string  `gorm:"length:64;index" json:"deliveryID"` // delivery id of SCM, it is empty for callbacks
+	InstallationID int64   `gorm:"index" json:"installationID"`
+	Headers        Headers `gorm:"type:text" json:"headers"`
+	Payload        string  `gorm:"type:text" json:"payload,omitempty"` // it is only returned by detail
+	Signature      string  `gorm:"length:32" json:"signature" example:"valid"`
+	Status         string  `gorm:"length:32;index" json:"status" example:"success"`
+	Error          string  `gorm:"length:1024" json:"error"`
+	Latency        int64   `json:"latency"`                   // milliseconds of processing
+	RedeliveryOf   int64   `gorm:"index" json:"redeliveryOf"` // id of original delivery of redelivery
+
+	CreatedAt time.Time `json:"createdAt" swaggerignore:"true"`
+	UpdatedAt time.Time `json:"updatedAt" swaggerignore:"true"`
+}
+
+// Headers request headers of delivery
+type Headers map[string]string
+
+func (h *Headers) Scan(val interface{}) error {
+	return scanJSON(val, h)
+}
+
+func (h Headers) Value() (driver.Value, error) {
+	return jsonValue(h)
+}
+
+// ModuleMetadata is current activity of repository of module, it is synced in background
+type ModuleMetadata struct {
+	ModuleID      int64     `gorm:"primaryKey;autoIncrement:false" json:"moduleID"`
+	DefaultBranch string    `gorm:"length:256" json:"defaultBranch" example:"main"`
+	Branches      Branches  `gorm:"type:text" json:"branches"`
+	Tags          Tags      `gorm:"type:text" json:"tags"`
+	Languages     Languages `gorm:"type:text" json:"languages"` // percentage of each language
+	OpenPRCount   int       `json:"openPRCount"`
+	SyncedAt      time.Time `json:"syncedAt"`
+	SyncError     string    `gorm:"length:1024" json:"syncError"`
+}
+
+// ModuleDetail is module with its metadata, metadata is nil before it is synced
+type ModuleDetail struct {
+	*Module
+	Metadata *ModuleMetadata `json:"metadata"`
+}
+
+// Branch is a branch of repository with its latest commit
+type Branch struct {
+	Name   string  `json:"name" example:"main"`
+	Commit *Commit `json:"commit"`
+}
+
+type Commit struct {
+	SHA     string    `json:"sha"`
+	Message string    `json:"message"` // first line of commit message
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+}
+
+type Tag struct {
+	Name string `json:"name" example:"v1.0.0"`
+	SHA  string `json:"sha"`
+}
+
+type Branches []*Branch
+
+func (b *Branches) Scan(val interface{}) error {
+	return scanJSON(val, b)
+}
+
+func (b Branches) Value() (driver.Value, error) {
+	return jsonValue(b)
+}
+
+type Tags []*Tag
+
+func (t *Tags) Scan(val interface{}) error {
+	return scanJSON(val, t)
+}
+
+func (t Tags) Value() (driver.Value, error) {
+	return jsonValue(t)
+}
+
+// Languages percentage of each language of repository
+type Languages map[string]float64
+
+func (l *Languages) Scan(val interface{}) error {
+	return scanJSON(val, l)
+}
+
+func (l Languages) Value() (driver.Value, error) {
+	return jsonValue(l)
+}
+
+func scanJSON(val interface{}, v any) error {
+	switch data := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	case []byte:
+		return json.Unmarshal(data, v)
+	default:
+		return nil
+	}
+}
+
+func jsonValue(v any) (driver.Value, error) {
+	s, err := json.Marshal(v)
+	return string(s), err
+}
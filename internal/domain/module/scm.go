@@ -0,0 +1,396 @@
+package module
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/user/oauth"
+	"github.com/MR5356/aurora/pkg/util/validate"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSyncCron = "0 */30 * * * *"
+
+	// oauthTypeGitea auth type of oauth config of gitea, it is only used to access api of gitea
+	oauthTypeGitea = "gitea"
+
+	oauthStateExpiry = 10 * time.Minute
+
+	// maxSyncErrorLength length of column LastSyncError of SCMConnection
+	maxSyncErrorLength = 1024
+
+	// scmRequestTimeout timeout of each request to api of SCM
+	scmRequestTimeout = 30 * time.Second
+	// syncTimeout timeout of a sync of repositories of connection or metadata of module
+	syncTimeout = 5 * time.Minute
+)
+
+// scmHTTPClient is the http client of api of SCM, requests never hang a sync
+var scmHTTPClient = &http.Client{Timeout: scmRequestTimeout}
+
+// SCMProvider lists repositories reachable by account of SCM and fetches their metadata
+type SCMProvider interface {
+	ListRepos(ctx context.Context) ([]*Module, error)
+	GetMetadata(ctx context.Context, m *Module) (*ModuleMetadata, error)
+}
+
+var scmProviders = map[string]func(conn *SCMConnection, accessToken string) (SCMProvider, error){
+	SCMTypeGitlab: newGitlabProvider,
+	SCMTypeGitea:  newGiteaProvider,
+}
+
+// oauthStates pending oauth authorizations of connections, state -> *oauthState
+var oauthStates sync.Map
+
+type oauthState struct {
+	owner  string
+	oauth  string
+	expiry time.Time
+}
+
+// oauthConfig returns oauth2 config, scm type and base url of server of oauth config
+func oauthConfig(name string) (*oauth2.Config, string, string, error) {
+	conf, ok := config.Current().OAuthConfig[name]
+	if !ok {
+		return nil, "", "", fmt.Errorf("oauth %s not found", name)
+	}
+	redirectURL := config.Current().Server.BaseURL + config.Current().Server.Prefix + "/module/scm/callback"
+
+	switch conf.AuthType {
+	case oauth.AuthTypeGitlab:
+		auth := oauth.NewGitlabAuth(name, conf)
+		baseURL, err := auth.BaseURL()
+		return auth.APIConfig(redirectURL), SCMTypeGitlab, baseURL, err
+	case oauthTypeGitea:
+		parsedURL, err := url.Parse(conf.AuthURL)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return &oauth2.Config{
+			Endpoint:     oauth2.Endpoint{AuthURL: conf.AuthURL, TokenURL: conf.TokenURL},
+			ClientID:     conf.ClientId,
+			ClientSecret: conf.ClientSecret,
+			RedirectURL:  redirectURL,
+		}, SCMTypeGitea, (&url.URL{Scheme: parsedURL.Scheme, Host: parsedURL.Host}).String(), nil
+	default:
+		return nil, "", "", fmt.Errorf("oauth %s can not access repositories", name)
+	}
+}
+
+// SCMOAuthURL returns url to authorize access to repositories by oauth
+func (s *Service) SCMOAuthURL(name, owner string) (string, error) {
+	cfg, _, _, err := oauthConfig(name)
+	if err != nil {
+		return "", err
+	}
+	state := uuid.NewString()
+	oauthStates.Store(state, &oauthState{owner: owner, oauth: name, expiry: time.Now().Add(oauthStateExpiry)})
+	return cfg.AuthCodeURL(state, oauth2.AccessTypeOffline), nil
+}
+
+// SCMOAuthCallback exchange code of oauth authorization and add connection of it
+func (s *Service) SCMOAuthCallback(ctx context.Context, state, code, owner string) (*SCMConnection, error) {
+	v, ok := oauthStates.LoadAndDelete(state)
+	if !ok {
+		return nil, errors.New("invalid oauth state")
+	}
+	st := v.(*oauthState)
+	if st.owner != owner || time.Now().After(st.expiry) {
+		return nil, errors.New("invalid oauth state")
+	}
+
+	cfg, scmType, baseURL, err := oauthConfig(st.oauth)
+	if err != nil {
+		return nil, err
+	}
+	token, err := cfg.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &SCMConnection{
+		Owner:        owner,
+		SCMType:      scmType,
+		BaseURL:      baseURL,
+		AuthType:     AuthTypeOAuth,
+		OAuth:        st.oauth,
+		Token:        token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenExpiry:  token.Expiry,
+	}
+	if err := s.AddSCMConnection(ctx, conn); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// AddSCMConnection add connection and sync its repositories, error of sync is recorded in connection
+func (s *Service) AddSCMConnection(ctx context.Context, conn *SCMConnection) error {
+	conn.ID = 0
+	if conn.SyncCron == "" {
+		conn.SyncCron = defaultSyncCron
+	}
+	if err := validate.Validate(conn); err != nil {
+		return err
+	}
+	if conn.Token == "" {
+		return errors.New("token of connection is required")
+	}
+	if _, err := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor).Parse(conn.SyncCron); err != nil {
+		return fmt.Errorf("invalid sync cron %s: %v", conn.SyncCron, err)
+	}
+
+	if err := s.scmConnectionDB.Insert(conn); err != nil {
+		return err
+	}
+	if err := s.startSync(conn); err != nil {
+		return err
+	}
+	if err := s.SyncSCMConnection(ctx, conn.ID); err != nil {
+		logrus.Errorf("sync connection %d failed, error: %v", conn.ID, err)
+	}
+
+	res, err := s.scmConnectionDB.Detail(&SCMConnection{ID: conn.ID})
+	if err != nil {
+		return err
+	}
+	*conn = *res
+	conn.Token = ""
+	return nil
+}
+
+// ListSCMConnection list connections of owner, tokens are not returned
+func (s *Service) ListSCMConnection(owner string) ([]*SCMConnection, error) {
+	res, err := s.scmConnectionDB.List(&SCMConnection{Owner: owner})
+	if err != nil {
+		return nil, err
+	}
+	for _, conn := range res {
+		conn.Token = ""
+	}
+	return res, nil
+}
+
+// DeleteSCMConnection delete connection of owner with its modules
+func (s *Service) DeleteSCMConnection(id int64, owner string) error {
+	if _, err := s.getOwnedConnection(id, owner); err != nil {
+		return err
+	}
+	s.stopSync(id)
+
+	var ids []int64
+	if err := s.moduleDB.DB.Model(&Module{}).Where("connection_id = ?", id).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if err := s.deleteModules(ids); err != nil {
+		return err
+	}
+	return s.scmConnectionDB.Delete(&SCMConnection{ID: id})
+}
+
+// SyncSCMConnectionOfOwner sync repositories of connection owned by owner
+func (s *Service) SyncSCMConnectionOfOwner(ctx context.Context, id int64, owner string) error {
+	if _, err := s.getOwnedConnection(id, owner); err != nil {
+		return err
+	}
+	return s.SyncSCMConnection(ctx, id)
+}
+
+// SyncSCMConnection list repositories of connection and sync them to modules
+func (s *Service) SyncSCMConnection(ctx context.Context, id int64) error {
+	conn, err := s.scmConnectionDB.Detail(&SCMConnection{ID: id})
+	if err != nil {
+		return err
+	}
+
+	err = s.syncConnection(ctx, conn)
+	fields := map[string]any{"LastSyncAt": time.Now(), "LastSyncError": ""}
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > maxSyncErrorLength {
+			msg = msg[:maxSyncErrorLength]
+		}
+		fields["LastSyncError"] = msg
+	}
+	if uerr := s.scmConnectionDB.Update(&SCMConnection{ID: id}, fields); uerr != nil {
+		logrus.Errorf("update connection %d failed, error: %v", id, uerr)
+	}
+	return err
+}
+
+func (s *Service) syncConnection(ctx context.Context, conn *SCMConnection) error {
+	provider, err := s.connectionProvider(ctx, conn)
+	if err != nil {
+		return err
+	}
+	repos, err := provider.ListRepos(ctx)
+	if err != nil {
+		return err
+	}
+	return s.syncModules(conn, repos)
+}
+
+// connectionProvider returns provider of connection authorized by its access token
+func (s *Service) connectionProvider(ctx context.Context, conn *SCMConnection) (SCMProvider, error) {
+	newProvider, ok := scmProviders[conn.SCMType]
+	if !ok {
+		return nil, fmt.Errorf("scm type %s not supported", conn.SCMType)
+	}
+	accessToken, err := s.accessToken(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return newProvider(conn, accessToken)
+}
+
+// accessToken returns access token of connection, oauth token is refreshed and saved if it is expired
+func (s *Service) accessToken(ctx context.Context, conn *SCMConnection) (string, error) {
+	if conn.AuthType != AuthTypeOAuth || conn.RefreshToken == "" {
+		return conn.Token, nil
+	}
+	cfg, _, _, err := oauthConfig(conn.OAuth)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := cfg.TokenSource(ctx, &oauth2.Token{AccessToken: conn.Token, RefreshToken: conn.RefreshToken, Expiry: conn.TokenExpiry}).Token()
+	if err != nil {
+		return "", err
+	}
+	if token.AccessToken != conn.Token {
+		conn.Token, conn.RefreshToken, conn.TokenExpiry = token.AccessToken, token.RefreshToken, token.Expiry
+		if err := s.scmConnectionDB.Update(&SCMConnection{ID: conn.ID}, map[string]any{"Token": conn.Token, "RefreshToken": conn.RefreshToken, "TokenExpiry": conn.TokenExpiry}); err != nil {
+			return "", err
+		}
+	}
+	return token.AccessToken, nil
+}
+
+// syncModules upsert repositories as modules of connection and delete modules of repositories not reachable
+func (s *Service) syncModules(conn *SCMConnection, repos []*Module) error {
+	existing, err := s.moduleDB.List(&Module{ConnectionID: conn.ID})
+	if err != nil {
+		return err
+	}
+	for _, repo := range repos {
+		repo.ConnectionID = conn.ID
+	}
+	return s.upsertModules(existing, repos)
+}
+
+// upsertModules update existing modules by owner and name of repos, add modules of new repos and delete existing
+// modules not in repos with their triggers, ids of existing modules are kept as triggers, metadata and catalog
+// services reference them
+func (s *Service) upsertModules(existing, repos []*Module) error {
+	modules := make(map[string]*Module)
+	for _, m := range existing {
+		modules[m.Owner+"/"+m.Name] = m
+	}
+
+	var errs []error
+	for _, repo := range repos {
+		key := repo.Owner + "/" + repo.Name
+		if m, ok := modules[key]; ok {
+			delete(modules, key)
+			fields := map[string]any{
+				"OwnerID":     repo.OwnerID,
+				"Description": repo.Description,
+				"Private":     repo.Private,
+				"HtmlURL":     repo.HtmlURL,
+				"CloneURL":    repo.CloneURL,
+				"SSHURL":      repo.SSHURL,
+			}
+			if repo.Language != "" {
+				fields["Language"] = repo.Language
+			}
+			if err := s.moduleDB.Update(&Module{ID: m.ID}, fields); err != nil {
+				errs = append(errs, fmt.Errorf("update module %s failed: %v", key, err))
+			}
+			continue
+		}
+
+		repo.ID = 0
+		if err := s.moduleDB.Insert(repo); err != nil {
+			errs = append(errs, fmt.Errorf("add module %s failed: %v", key, err))
+		}
+	}
+
+	removed := make([]int64, 0, len(modules))
+	for _, m := range modules {
+		removed = append(removed, m.ID)
+	}
+	if err := s.deleteModules(removed); err != nil {
+		errs = append(errs, fmt.Errorf("delete modules %v failed: %v", removed, err))
+	}
+	return errors.Join(errs...)
+}
+
+// deleteModules delete modules with their triggers
+func (s *Service) deleteModules(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.moduleDB.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("module_id IN ?", ids).Delete(&WorkflowTrigger{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&Module{}).Error
+	})
+}
+
+func (s *Service) getOwnedConnection(id int64, owner string) (*SCMConnection, error) {
+	if id == 0 {
+		return nil, fmt.Errorf("connection %d not found", id)
+	}
+	conn, err := s.scmConnectionDB.Detail(&SCMConnection{ID: id})
+	if err != nil || conn.Owner != owner {
+		return nil, fmt.Errorf("connection %d not found", id)
+	}
+	return conn, nil
+}
+
+func (s *Service) startSync(conn *SCMConnection) error {
+	id := conn.ID
+	entryID, err := s.cron.AddFunc(conn.SyncCron, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+		defer cancel()
+		if err := s.SyncSCMConnection(ctx, id); err != nil {
+			logrus.Errorf("sync connection %d failed, error: %v", id, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.syncJobs.Store(id, entryID)
+	return nil
+}
+
+func (s *Service) stopSync(id int64) {
+	if entryID, ok := s.syncJobs.LoadAndDelete(id); ok {
+		s.cron.Remove(entryID.(cron.EntryID))
+	}
+}
+
+// initSync start sync of all connections
+func (s *Service) initSync() error {
+	conns, err := s.scmConnectionDB.List(&SCMConnection{})
+	if err != nil {
+		return err
+	}
+	for _, conn := range conns {
+		if err := s.startSync(conn); err != nil {
+			logrus.Errorf("start sync of connection %d failed, error: %v", conn.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package module
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSCM is a local GitLab and Gitea API server which returns configured repositories
+type fakeSCM struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	projects []map[string]any
+	repos    []map[string]any
+	broken   bool // metadata api responds errors if it is true
+}
+
+func newFakeSCM(t *testing.T) *fakeSCM {
+	f := &fakeSCM{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "glpat-test" || r.URL.Query().Get("membership") != "true" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// one project per page
+		page := 1
+		if p := r.URL.Query().Get("page"); p == "2" {
+			page = 2
+		}
+		res := make([]map[string]any, 0)
+		if page <= len(f.projects) {
+			res = append(res, f.projects[page-1])
+		}
+		if page < len(f.projects) {
+			w.Header().Set("X-Next-Page", "2")
+		}
+		json.NewEncoder(w).Encode(res)
+	})
+	mux.HandleFunc("GET /api/v1/user/repos", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token gitea-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		json.NewEncoder(w).Encode(f.repos)
+	})
+	f.handleMetadata(mux)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// handleMetadata serve metadata api of project team/gl-meta of GitLab and repository dev/gt-meta of Gitea
+func (f *fakeSCM) handleMetadata(mux *http.ServeMux) {
+	respond := func(body string, header ...string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			broken := f.broken
+			f.mu.Unlock()
+			if broken {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			for i := 0; i+1 < len(header); i += 2 {
+				w.Header().Set(header[i], header[i+1])
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		}
+	}
+
+	gitlab := "GET /api/v4/projects/team%2Fgl-meta"
+	mux.HandleFunc(gitlab, respond(`{"default_branch":"main"}`))
+	mux.HandleFunc(gitlab+"/repository/branches", respond(`[{"name":"main","commit":{"id":"g1","message":"feat: gitlab\n\nbody","author_name":"gl","committed_date":"2026-01-02T03:04:05Z"}}]`))
+	mux.HandleFunc(gitlab+"/repository/tags", respond(`[{"name":"v2","commit":{"id":"g1"}}]`))
+	mux.HandleFunc(gitlab+"/languages", respond(`{"Go":75.04,"Shell":24.96}`))
+	mux.HandleFunc(gitlab+"/merge_requests", respond(`[{"id":1}]`, "X-Total", "2"))
+
+	gitea := "GET /api/v1/repos/dev/gt-meta"
+	mux.HandleFunc(gitea, respond(`{"default_branch":"main","open_pr_counter":3}`))
+	mux.HandleFunc(gitea+"/branches", respond(`[{"name":"main","commit":{"id":"c1","message":"fix: gitea","timestamp":"2026-01-02T03:04:05Z","author":{"name":"dev"}}}]`))
+	mux.HandleFunc(gitea+"/tags", respond(`[{"name":"v1","commit":{"sha":"c1"}}]`))
+	mux.HandleFunc(gitea+"/languages", respond(`{"Go":300,"Shell":100}`))
+}
+
+func gitlabProject(path, description string) map[string]any {
+	return map[string]any{
+		"path":             path,
+		"description":      description,
+		"visibility":       "private",
+		"web_url":          "https://gitlab.example.com/team/" + path,
+		"http_url_to_repo": "https://gitlab.example.com/team/" + path + ".git",
+		"namespace":        map[string]any{"id": 9, "full_path": "team"},
+	}
+}
+
+func TestService_SCMConnectionGitlab(t *testing.T) {
+	s := setupTest(t)
+	fake := newFakeSCM(t)
+	fake.projects = []map[string]any{gitlabProject("gl-api", "api"), gitlabProject("gl-web", "web")}
+
+	if err := s.AddSCMConnection(context.Background(), &SCMConnection{SCMType: "Bitbucket", BaseURL: fake.URL, AuthType: AuthTypeToken, Token: "x"}); err == nil {
+		t.Errorf("expected error of unsupported scm type")
+	}
+	if err := s.AddSCMConnection(context.Background(), &SCMConnection{SCMType: SCMTypeGitlab, BaseURL: fake.URL, AuthType: AuthTypeToken}); err == nil {
+		t.Errorf("expected error of connection without token")
+	}
+
+	conn := &SCMConnection{Owner: "user-3", SCMType: SCMTypeGitlab, BaseURL: fake.URL, AuthType: AuthTypeToken, Token: "glpat-test"}
+	if err := s.AddSCMConnection(context.Background(), conn); err != nil {
+		t.Fatalf("add connection failed: %v", err)
+	}
+	if conn.Token != "" || conn.LastSyncError != "" || conn.LastSyncAt.IsZero() || conn.SyncCron != defaultSyncCron {
+		t.Errorf("unexpected connection %+v", conn)
+	}
+
+	pager, err := s.PageModule(context.Background(), 1, 10, "user-3")
+	if err != nil || pager.Total != 2 {
+		t.Fatalf("expected 2 modules synced, got %+v, %v", pager, err)
+	}
+	api, err := s.moduleDB.Detail(&Module{Name: "gl-api", ConnectionID: conn.ID})
+	if err != nil || api.ConnectionID != conn.ID || api.Owner != "team" || api.SCMType != SCMTypeGitlab || !api.Private {
+		t.Fatalf("unexpected module %+v, %v", api, err)
+	}
+	if pager, _ := s.PageModule(context.Background(), 1, 10, "user-4"); pager.Total != 0 {
+		t.Errorf("expected no module of other user, got %d", pager.Total)
+	}
+
+	if err := s.AddWorkflowTrigger(&WorkflowTrigger{ModuleID: api.ID, WorkflowID: "wf"}, "user-4"); err == nil {
+		t.Errorf("expected error of adding trigger to module not owned")
+	}
+	if _, err := s.ListWorkflowTrigger(api.ID, "user-3"); err != nil {
+		t.Errorf("list triggers of connection module failed: %v", err)
+	}
+
+	// repositories of same name are synced by connections of other users
+	other := &SCMConnection{Owner: "user-5", SCMType: SCMTypeGitlab, BaseURL: fake.URL, AuthType: AuthTypeToken, Token: "glpat-test"}
+	if err := s.AddSCMConnection(context.Background(), other); err != nil || other.LastSyncError != "" {
+		t.Fatalf("add connection of other user failed: %v %s", err, other.LastSyncError)
+	}
+	if pager, _ := s.PageModule(context.Background(), 1, 10, "user-5"); pager.Total != 2 {
+		t.Errorf("expected 2 modules of other user, got %d", pager.Total)
+	}
+	defer s.DeleteSCMConnection(other.ID, "user-5")
+
+	web, err := s.moduleDB.Detail(&Module{Name: "gl-web", ConnectionID: conn.ID})
+	if err != nil {
+		t.Fatalf("get module failed: %v", err)
+	}
+	if err := s.triggerDB.Insert(&WorkflowTrigger{ModuleID: web.ID, WorkflowID: "wf"}); err != nil {
+		t.Fatalf("add trigger failed: %v", err)
+	}
+
+	fake.mu.Lock()
+	fake.projects = []map[string]any{gitlabProject("gl-api", "api v2")}
+	fake.mu.Unlock()
+	if err := s.SyncSCMConnectionOfOwner(context.Background(), conn.ID, "user-4"); err == nil {
+		t.Errorf("expected error of syncing connection not owned")
+	}
+	if err := s.SyncSCMConnectionOfOwner(context.Background(), conn.ID, "user-3"); err != nil {
+		t.Fatalf("sync connection failed: %v", err)
+	}
+	synced, err := s.moduleDB.Detail(&Module{Name: "gl-api", ConnectionID: conn.ID})
+	if err != nil || synced.ID != api.ID || synced.Description != "api v2" {
+		t.Errorf("expected module %d updated in place, got %+v, %v", api.ID, synced, err)
+	}
+	if _, err := s.moduleDB.Detail(&Module{Name: "gl-web", ConnectionID: conn.ID}); err == nil {
+		t.Errorf("expected module of removed project deleted")
+	}
+	if count, _ := s.triggerDB.Count(&WorkflowTrigger{ModuleID: web.ID}); count != 0 {
+		t.Errorf("expected triggers of removed module deleted, got %d", count)
+	}
+
+	if err := s.DeleteSCMConnection(conn.ID, "user-4"); err == nil {
+		t.Errorf("expected error of deleting connection not owned")
+	}
+	if err := s.DeleteSCMConnection(conn.ID, "user-3"); err != nil {
+		t.Fatalf("delete connection failed: %v", err)
+	}
+	if count, _ := s.moduleDB.Count(&Module{ConnectionID: conn.ID}); count != 0 {
+		t.Errorf("expected modules of connection deleted, got %d", count)
+	}
+	if _, ok := s.syncJobs.Load(conn.ID); ok {
+		t.Errorf("expected sync job of connection stopped")
+	}
+}
+
+func TestService_SCMConnectionGitea(t *testing.T) {
+	s := setupTest(t)
+	fake := newFakeSCM(t)
+	fake.repos = []map[string]any{{
+		"name":      "gt-app",
+		"language":  "Go",
+		"clone_url": "https://gitea.example.com/dev/gt-app.git",
+		"owner":     map[string]any{"id": 5, "login": "dev"},
+	}}
+
+	conn := &SCMConnection{Owner: "user-5", SCMType: SCMTypeGitea, BaseURL: fake.URL, AuthType: AuthTypeToken, Token: "wrong"}
+	if err := s.AddSCMConnection(context.Background(), conn); err != nil {
+		t.Fatalf("add connection failed: %v", err)
+	}
+	if conn.LastSyncError == "" {
+		t.Errorf("expected sync error of invalid token recorded")
+	}
+	if err := s.DeleteSCMConnection(conn.ID, "user-5"); err != nil {
+		t.Fatalf("delete connection failed: %v", err)
+	}
+
+	conn = &SCMConnection{Owner: "user-5", SCMType: SCMTypeGitea, BaseURL: fake.URL + "/", AuthType: AuthTypeToken, Token: "gitea-test"}
+	if err := s.AddSCMConnection(context.Background(), conn); err != nil {
+		t.Fatalf("add connection failed: %v", err)
+	}
+	m, err := s.moduleDB.Detail(&Module{Name: "gt-app"})
+	if err != nil || m.ConnectionID != conn.ID || m.Owner != "dev" || m.OwnerID != 5 || m.Language != "Go" || m.SCMType != SCMTypeGitea {
+		t.Errorf("unexpected module %+v, %v", m, err)
+	}
+
+	conns, err := s.ListSCMConnection("user-5")
+	if err != nil || len(conns) != 1 || conns[0].Token != "" {
+		t.Errorf("expected 1 connection without token, got %+v, %v", conns, err)
+	}
+}
+
+func TestService_SCMConnectionTimeout(t *testing.T) {
+	s := setupTest(t)
+	hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hang.Close)
+
+	client := scmHTTPClient
+	scmHTTPClient = &http.Client{Timeout: 100 * time.Millisecond}
+	t.Cleanup(func() { scmHTTPClient = client })
+
+	start := time.Now()
+	conn := &SCMConnection{Owner: "user-6", SCMType: SCMTypeGitea, BaseURL: hang.URL, AuthType: AuthTypeToken, Token: "gitea-test"}
+	if err := s.AddSCMConnection(context.Background(), conn); err != nil {
+		t.Fatalf("add connection failed: %v", err)
+	}
+	defer s.DeleteSCMConnection(conn.ID, "user-6")
+	if conn.LastSyncError == "" || time.Since(start) > 5*time.Second {
+		t.Errorf("expected sync of hanging server timeout, got %q after %s", conn.LastSyncError, time.Since(start))
+	}
+}
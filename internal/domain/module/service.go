@@ -3,16 +3,16 @@ package module
 import (
 	"context"
 	"errors"
-	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/pipeline"
 	"github.com/MR5356/aurora/internal/domain/user"
 	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
 	"github.com/MR5356/aurora/pkg/util/structutil"
-	"github.com/bradleyfalzon/ghinstallation/v2"
-	"github.com/google/go-github/v72/github"
+	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
-	"net/http"
 	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -24,6 +24,14 @@ type Service struct {
 	moduleDB                 *database.BaseMapper[*Module]
 	installationIDRelationDB *database.BaseMapper[*InstallationIDRelation]
 	userDB                   *database.BaseMapper[*user.User]
+	triggerDB                *database.BaseMapper[*WorkflowTrigger]
+	scmConnectionDB          *database.BaseMapper[*SCMConnection]
+	deliveryDB               *database.BaseMapper[*WebhookDelivery]
+	metadataDB               *database.BaseMapper[*ModuleMetadata]
+
+	cron            *cron.Cron
+	syncJobs        sync.Map
+	metadataSyncing atomic.Bool
 }
 
 func GetService() *Service {
@@ -32,7 +40,13 @@ func GetService() *Service {
 			moduleDB:                 database.NewMapper(database.GetDB(), &Module{}),
 			installationIDRelationDB: database.NewMapper(database.GetDB(), &InstallationIDRelation{}),
 			userDB:                   database.NewMapper(database.GetDB(), &user.User{}),
+			triggerDB:                database.NewMapper(database.GetDB(), &WorkflowTrigger{}),
+			scmConnectionDB:          database.NewMapper(database.GetDB(), &SCMConnection{}),
+			deliveryDB:               database.NewMapper(database.GetDB(), &WebhookDelivery{}),
+			metadataDB:               database.NewMapper(database.GetDB(), &ModuleMetadata{}),
+			cron:                     cron.New(cron.WithSeconds()),
 		}
+		service.cron.Start()
 	})
 	return service
 }
@@ -46,26 +60,40 @@ func (s *Service) PageModule(ctx context.Context, page, size int, owner string)
 	for _, iid := range iids {
 		installationIDs = append(installationIDs, iid.InstallationID)
 	}
+	conns, err := s.scmConnectionDB.List(&SCMConnection{Owner: owner})
+	if err != nil {
+		return nil, err
+	}
+	var connectionIDs []int64
+	for _, conn := range conns {
+		connectionIDs = append(connectionIDs, conn.ID)
+	}
 
 	res := new(database.Pager[*Module])
 	res.CurrentPage = int64(page)
 	res.PageSize = int64(size)
-	database.GetDB().Model(&Module{}).Where("installation_id in ?", installationIDs).Count(&res.Total)
+	database.GetDB().Model(&Module{}).Where("installation_id in ? or connection_id in ?", installationIDs, connectionIDs).Count(&res.Total)
 	if res.Total == 0 {
 		res.Data = make([]*Module, 0)
 		return res, nil
 	}
 
-	if err := database.GetDB().Where("installation_id in ?", installationIDs).Order("created_at desc").Scopes(database.Pagination(res)).Find(&res.Data).Error; err != nil {
+	if err := database.GetDB().Where("installation_id in ? or connection_id in ?", installationIDs, connectionIDs).Order("created_at desc").Scopes(database.Pagination(res)).Find(&res.Data).Error; err != nil {
 		logrus.Errorf("PageModule: %v", err)
 		return nil, err
 	}
 	return res, nil
 }
 
+// UpdateGithubModule sync repositories of GitHub App installation to modules on installation events,
+// ids of existing modules are kept as triggers reference them
 func (s *Service) UpdateGithubModule(ctx context.Context, action string, installationID int64) error {
 	if action == "deleted" {
-		if err := database.GetDB().Model(&Module{}).Where("installation_id = ?", installationID).Delete(&Module{}).Error; err != nil {
+		var ids []int64
+		if err := s.moduleDB.DB.Model(&Module{}).Where("installation_id = ?", installationID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err := s.deleteModules(ids); err != nil {
 			return err
 		}
 		if err := database.GetDB().Model(&InstallationIDRelation{}).Where("installation_id = ?", installationID).Delete(&InstallationIDRelation{}).Error; err != nil {
@@ -73,46 +101,24 @@ func (s *Service) UpdateGithubModule(ctx context.Context, action string, install
 		}
 		return nil
 	}
-	return database.GetDB().Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(&Module{}).Where("installation_id = ?", installationID).Delete(&Module{}).Error; err != nil {
-			return err
-		}
-
-		itr, err := ghinstallation.NewKeyFromFile(http.DefaultTransport, config.Current().GithubApp.AppID, installationID, config.Current().GithubApp.PrivateKey)
-		if err != nil {
-			logrus.Errorf("ghinstallation.NewKeyFromFile: %v", err)
-			return err
-		}
 
-		client := github.NewClient(&http.Client{Transport: itr})
+	provider, err := newGithubProvider(installationID)
+	if err != nil {
+		logrus.Errorf("newGithubProvider: %v", err)
+		return err
+	}
 
-		all, err := listAllRepos(ctx, client)
-		if err != nil {
-			logrus.Errorf("listAllRepos: %v", err)
-			return err
-		}
+	all, err := provider.ListRepos(ctx)
+	if err != nil {
+		logrus.Errorf("ListRepos: %v", err)
+		return err
+	}
 
-		for _, repo := range all {
-			logrus.Debugf("repo: %+v", repo)
-			if err = s.moduleDB.Insert(&Module{
-				Name:           repo.GetName(),
-				SCMType:        "GitHub",
-				Owner:          repo.GetOwner().GetLogin(),
-				OwnerID:        repo.GetOwner().GetID(),
-				Description:    repo.GetDescription(),
-				Language:       repo.GetLanguage(),
-				Private:        repo.GetPrivate(),
-				HtmlURL:        repo.GetHTMLURL(),
-				CloneURL:       repo.GetCloneURL(),
-				SSHURL:         repo.GetSSHURL(),
-				SVNURL:         repo.GetSVNURL(),
-				InstallationID: installationID,
-			}, tx); err != nil {
-				return err
-			}
-		}
-		return nil
-	})
+	existing, err := s.moduleDB.List(&Module{InstallationID: installationID})
+	if err != nil {
+		return err
+	}
+	return s.upsertModules(existing, all)
 }
 
 func (s *Service) RegisterInstallationID(ctx context.Context, installationID int64, owner string) error {
@@ -132,27 +138,19 @@ func (s *Service) RegisterInstallationID(ctx context.Context, installationID int
 	}
 }
 
-func listAllRepos(ctx context.Context, client *github.Client) ([]*github.Repository, error) {
-	var allRepos []*github.Repository
-	opts := &github.ListOptions{PerPage: 100} // 每页最多100个
-
-	for {
-		repos, resp, err := client.Apps.ListRepos(ctx, opts)
-		if err != nil {
-			return nil, err
-		}
-		allRepos = append(allRepos, repos.Repositories...)
+func (s *Service) Initialize() error {
+	if err := database.GetDB().AutoMigrate(&Module{}, &InstallationIDRelation{}, &WorkflowTrigger{}, &SCMConnection{}, &WebhookDelivery{}, &ModuleMetadata{}); err != nil {
+		return err
+	}
 
-		if resp.NextPage == 0 {
-			break // 没有更多页
-		}
-		opts.Page = resp.NextPage
+	if err := s.initSync(); err != nil {
+		return err
+	}
+	if _, err := s.cron.AddFunc(metadataSyncSpec, s.syncAllMetadata); err != nil {
+		return err
 	}
-	return allRepos, nil
-}
 
-func (s *Service) Initialize() error {
-	if err := database.GetDB().AutoMigrate(&Module{}, &InstallationIDRelation{}); err != nil {
+	if err := eventbus.GetEventBus().Subscribe(pipeline.TopicRunFinished, s.onRunFinished); err != nil {
 		return err
 	}
 	return nil
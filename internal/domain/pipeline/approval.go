@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MR5356/go-workflow"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrApprovalTimeout = errors.New("approval timeout")
+
+	// pendingApprovals run id/node id -> *pendingApproval
+	pendingApprovals = sync.Map{}
+)
+
+// ApprovalDecision is the decision of an approval node
+type ApprovalDecision struct {
+	Approved bool   `json:"-"`
+	By       string `json:"-"`
+	Comment  string `json:"comment"`
+}
+
+type pendingApproval struct {
+	approvers []string
+	decision  chan *ApprovalDecision
+}
+
+func approvalKey(runId, nodeId string) string {
+	return runId + "/" + nodeId
+}
+
+// approvalExecutor blocks until the node is approved or rejected
+type approvalExecutor struct{}
+
+func (approvalExecutor) Execute(ctx context.Context, node *workflow.Node, log io.Writer) error {
+	params := newNodeParams(node)
+	pending := &pendingApproval{
+		approvers: params.List("approvers"),
+		decision:  make(chan *ApprovalDecision, 1),
+	}
+
+	var timeout <-chan time.Time
+	if t := params.Get("timeout"); t != "" {
+		seconds, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %s", t)
+		}
+		if seconds > 0 {
+			timer := time.NewTimer(time.Duration(seconds) * time.Second)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+	}
+
+	key := approvalKey(runIDFromContext(ctx), node.Id)
+	pendingApprovals.Store(key, pending)
+	defer pendingApprovals.Delete(key)
+
+	if len(pending.approvers) > 0 {
+		fmt.Fprintf(log, "waiting for approval of %s\n", strings.Join(pending.approvers, ","))
+	} else {
+		fmt.Fprintln(log, "waiting for approval")
+	}
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timeout:
+		return ErrApprovalTimeout
+	case d := <-pending.decision:
+		if !d.Approved {
+			return fmt.Errorf("rejected by %s: %s", d.By, d.Comment)
+		}
+		fmt.Fprintf(log, "approved by %s: %s\n", d.By, d.Comment)
+		outputs := OutputsFromContext(ctx)
+		outputs.Set("approvedBy", d.By)
+		outputs.Set("comment", d.Comment)
+		return nil
+	}
+}
+
+// DecideApproval approve or reject an approval node waiting in workflow run
+func (s *Service) DecideApproval(runId, nodeId string, decision *ApprovalDecision) error {
+	v, ok := pendingApprovals.Load(approvalKey(runId, nodeId))
+	if !ok {
+		return fmt.Errorf("node %s of workflow run %s is not waiting for approval", nodeId, runId)
+	}
+
+	pending := v.(*pendingApproval)
+	if len(pending.approvers) > 0 && !slices.Contains(pending.approvers, decision.By) {
+		return fmt.Errorf("user %s is not an approver of node %s", decision.By, nodeId)
+	}
+
+	select {
+	case pending.decision <- decision:
+		return nil
+	default:
+		return fmt.Errorf("node %s of workflow run %s is already decided", nodeId, runId)
+	}
+}
@@ -0,0 +1,155 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxArtifactSize max size of an artifact saved by built-in nodes
+const maxArtifactSize = 64 * 1024 * 1024
+
+var ErrArtifactTooLarge = errors.New("artifact is too large")
+
+// ArtifactStore stores content of artifacts by key
+type ArtifactStore interface {
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalArtifactStore stores artifacts as files under a directory of local disk
+type LocalArtifactStore struct {
+	dir string
+}
+
+func NewLocalArtifactStore(dir string) *LocalArtifactStore {
+	return &LocalArtifactStore{dir: dir}
+}
+
+func (s *LocalArtifactStore) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if rel, err := filepath.Rel(s.dir, path); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid artifact key %s", key)
+	}
+	return path, nil
+}
+
+func (s *LocalArtifactStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return 0, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// partial content is removed, so a failed put leaves nothing
+	size, err := io.Copy(f, r)
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return size, err
+}
+
+func (s *LocalArtifactStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalArtifactStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// artifactKey key of artifact in store, name is reduced to its base name
+func artifactKey(runId, nodeId, name string) string {
+	return strings.Join([]string{runId, nodeId, filepath.Base(filepath.Clean("/" + name))}, "/")
+}
+
+// limitedReader fails with ErrArtifactTooLarge instead of EOF when more than limit bytes are read
+type limitedReader struct {
+	r     *io.LimitedReader
+	limit int64
+}
+
+// limitArtifact limit the content read from r to limit bytes
+func limitArtifact(r io.Reader, limit int64) io.Reader {
+	return &limitedReader{r: &io.LimitedReader{R: r, N: limit + 1}, limit: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	if l.r.N <= 0 {
+		return 0, fmt.Errorf("%w, max size is %d bytes", ErrArtifactTooLarge, l.limit)
+	}
+	return n, err
+}
+
+// SetArtifactStore replace the store of artifacts, the default store is local disk
+func (s *Service) SetArtifactStore(store ArtifactStore) {
+	s.artifactStore = store
+}
+
+// saveArtifact put content of artifact to store and record it
+func (s *Service) saveArtifact(ctx context.Context, runId, nodeId, name string, r io.Reader) (*Artifact, error) {
+	artifact := &Artifact{
+		RunId:  runId,
+		NodeId: nodeId,
+		Name:   name,
+		Key:    artifactKey(runId, nodeId, name),
+	}
+
+	size, err := s.artifactStore.Put(ctx, artifact.Key, r)
+	if err != nil {
+		return nil, err
+	}
+	artifact.Size = size
+
+	if err := s.artifactDB.Insert(artifact); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// OpenArtifact returns artifact with reader of its content, reader must be closed by caller
+func (s *Service) OpenArtifact(ctx context.Context, id string) (*Artifact, io.ReadCloser, error) {
+	artifact, err := s.artifactDB.Detail(&Artifact{ID: id})
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := s.artifactStore.Get(ctx, artifact.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return artifact, reader, nil
+}
+
+// deleteArtifactContent delete content of artifacts from store, failures are only logged
+func (s *Service) deleteArtifactContent(ctx context.Context, artifacts []*Artifact) {
+	for _, artifact := range artifacts {
+		if err := s.artifactStore.Delete(ctx, artifact.Key); err != nil {
+			logrus.Errorf("delete content of artifact %s failed, error: %v", artifact.ID, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"github.com/MR5356/go-workflow"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalArtifactStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalArtifactStore(t.TempDir())
+
+	size, err := store.Put(ctx, "run/node/a.txt", strings.NewReader("hello"))
+	if err != nil || size != 5 {
+		t.Fatalf("expected 5 bytes put, got %d, error: %v", size, err)
+	}
+	reader, err := store.Get(ctx, "run/node/a.txt")
+	if err != nil {
+		t.Fatalf("get artifact failed: %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "hello" {
+		t.Errorf("expected hello, got %s", content)
+	}
+
+	if err := store.Delete(ctx, "run/node/a.txt"); err != nil {
+		t.Errorf("delete artifact failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "run/node/a.txt"); err == nil {
+		t.Errorf("expected error of deleted artifact")
+	}
+
+	for _, key := range []string{"../a.txt", "run/../../a.txt", ""} {
+		if _, err := store.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Errorf("expected error of invalid key %q", key)
+		}
+	}
+}
+
+func TestArtifactKey(t *testing.T) {
+	if got := artifactKey("run", "node", "../../a.txt"); got != "run/node/a.txt" {
+		t.Errorf("expected run/node/a.txt, got %s", got)
+	}
+}
+
+func TestService_Artifact(t *testing.T) {
+	s := newTestService(t)
+
+	wfr := newTestWorkflow(map[string]string{"artifact-a": "echo", "artifact-b": "echo"}, [][2]string{{"artifact-a", "artifact-b"}})
+	for _, n := range wfr.Nodes {
+		n.Params = []*workflow.TaskParam{{Key: "message", Value: "hello"}}
+		if n.Id == "artifact-b" {
+			n.Params[0].Value = `{{ output "artifact-a" "message" }} world`
+		}
+	}
+	wfr.ID = ""
+	wfr.Title = "artifact"
+	wfr.Owner = "test"
+	if err := s.AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+
+	run, err := s.StartRun(wfr.ID, TriggerManual, "tester")
+	if err != nil {
+		t.Fatalf("start run failed: %v", err)
+	}
+	res := waitRunFinished(t, s, run.ID)
+	if res.Status != RunStatusSuccess {
+		t.Fatalf("expected success run, got %s: %s", res.Status, res.Error)
+	}
+	for _, n := range res.Nodes {
+		if n.NodeId == "artifact-b" && n.Outputs["message"] != "hello world" {
+			t.Errorf("expected output hello world of node b, got %v", n.Outputs)
+		}
+	}
+	if len(res.Artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(res.Artifacts))
+	}
+
+	for _, a := range res.Artifacts {
+		artifact, reader, err := s.OpenArtifact(context.Background(), a.ID)
+		if err != nil {
+			t.Fatalf("open artifact failed: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		reader.Close()
+		if int64(len(content)) != artifact.Size || artifact.Name != "message.txt" {
+			t.Errorf("unexpected artifact %+v with content %s", artifact, content)
+		}
+	}
+
+	if err := s.DeleteRun(run.ID); err != nil {
+		t.Fatalf("delete run failed: %v", err)
+	}
+	if _, err := s.DetailRun(run.ID); err == nil {
+		t.Errorf("expected error of deleted run")
+	}
+	for _, a := range res.Artifacts {
+		if _, err := s.artifactStore.Get(context.Background(), a.Key); err == nil {
+			t.Errorf("expected content of artifact %s deleted", a.ID)
+		}
+	}
+	if err := s.DeleteRun(run.ID); err == nil {
+		t.Errorf("expected error of deleting run not exist")
+	}
+}
+
+func TestLimitArtifact(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalArtifactStore(t.TempDir())
+
+	if _, err := store.Put(ctx, "run/node/a.txt", limitArtifact(strings.NewReader("hello"), 5)); err != nil {
+		t.Errorf("expected artifact within limit put, got %v", err)
+	}
+	if _, err := store.Put(ctx, "run/node/b.txt", limitArtifact(strings.NewReader("hello"), 4)); !errors.Is(err, ErrArtifactTooLarge) {
+		t.Errorf("expected %v, got %v", ErrArtifactTooLarge, err)
+	}
+	if _, err := store.Get(ctx, "run/node/b.txt"); err == nil {
+		t.Errorf("expected partial artifact removed")
+	}
+}
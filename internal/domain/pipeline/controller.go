@@ -1,9 +1,15 @@
 package pipeline
 
 import (
+	"errors"
+	"fmt"
+	"github.com/MR5356/aurora/internal/domain/user"
 	"github.com/MR5356/aurora/internal/response"
+	"github.com/MR5356/aurora/pkg/util/ginutil"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
 )
 
 type Controller struct {
@@ -31,7 +37,7 @@ func (c *Controller) handleAddWorkflow(ctx *gin.Context) {
 	}
 	if err := c.service.AddWorkflow(wfr); err != nil {
 		logrus.Errorf("add workflow failed, error: %v", err)
-		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		workflowError(ctx, err)
 	} else {
 		response.Success(ctx, nil)
 	}
@@ -52,12 +58,96 @@ func (c *Controller) handleUpdateWorkflow(ctx *gin.Context) {
 	}
 	if err := c.service.UpdateWorkflow(wfr); err != nil {
 		logrus.Errorf("add workflow failed, error: %v", err)
-		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		workflowError(ctx, err)
 	} else {
 		response.Success(ctx, nil)
 	}
 }
 
+// @Summary	validate workflow
+// @Description	validate workflow without saving it, problems of workflow are returned
+// @Tags		pipeline
+// @Param		workflow	body		WorkflowRequest	true	"workflow info"
+// @Success	200			{object}	response.Response{data=[]Problem}
+// @Router		/pipeline/workflow/validate [post]
+// @Produce	json
+func (c *Controller) handleValidateWorkflow(ctx *gin.Context) {
+	wfr := new(WorkflowRequest)
+	if err := ctx.ShouldBindJSON(wfr); err != nil {
+		logrus.Errorf("bind json failed, error: %v", err)
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	var verr *ValidationError
+	if err := ValidateWorkflow(wfr.WorkflowDAG); errors.As(err, &verr) {
+		response.Success(ctx, verr.Problems)
+	} else {
+		response.Success(ctx, make([]*Problem, 0))
+	}
+}
+
+// @Summary	import workflow
+// @Description	add or update workflow by key of yaml definition, owner of new workflow defaults to current user
+// @Tags		pipeline
+// @Accept		application/x-yaml
+// @Param		definition	body		string	true	"yaml definition of workflow"
+// @Success	200			{object}	response.Response{data=Workflow}
+// @Router		/pipeline/workflow/import [post]
+// @Produce	json
+func (c *Controller) handleImportWorkflow(ctx *gin.Context) {
+	data, err := ctx.GetRawData()
+	if err != nil || len(data) == 0 {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	u, err := user.GetJWTService().ParseToken(ginutil.GetToken(ctx))
+	if err != nil {
+		response.Error(ctx, response.CodeNotLogin)
+		return
+	}
+
+	if res, err := c.service.ImportWorkflow(data, u.ID); err != nil {
+		logrus.Errorf("import workflow failed, error: %v", err)
+		workflowError(ctx, err)
+	} else {
+		response.Success(ctx, res.Workflow)
+	}
+}
+
+// @Summary	export workflow
+// @Description	export workflow as yaml definition
+// @Tags		pipeline
+// @Param		id	path		string	true	"workflow id"
+// @Success	200	{string}	string	"yaml definition of workflow"
+// @Router		/pipeline/workflow/{id}/export [get]
+// @Produce	application/x-yaml
+func (c *Controller) handleExportWorkflow(ctx *gin.Context) {
+	wfr, data, err := c.service.ExportWorkflow(ctx.Param("id"))
+	if err != nil {
+		logrus.Errorf("export workflow failed, error: %v", err)
+		response.Error(ctx, response.CodeNotFound)
+		return
+	}
+
+	name := wfr.Key
+	if name == "" {
+		name = wfr.ID
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".yaml"))
+	ctx.Data(http.StatusOK, "application/x-yaml", data)
+}
+
+// workflowError responds error of saving workflow, problems of invalid workflow are returned as data
+func workflowError(ctx *gin.Context, err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		response.New(ctx, http.StatusOK, response.CodeParamsError, verr.Error(), verr.Problems)
+		return
+	}
+	response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+}
+
 // @Summary	list workflow
 // @Tags		pipeline
 // @Success	200	{object}	response.Response{data=[]Workflow}
@@ -106,11 +196,281 @@ func (c *Controller) handleDeleteWorkflow(ctx *gin.Context) {
 	}
 }
 
+// @Summary	run workflow
+// @Tags		pipeline
+// @Param		id	path		string	true	"workflow id"
+// @Success	200	{object}	response.Response{data=Run}
+// @Router		/pipeline/workflow/{id}/run [post]
+// @Produce	json
+func (c *Controller) handleRunWorkflow(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	u, err := user.GetJWTService().ParseToken(ginutil.GetToken(ctx))
+	if err != nil {
+		response.Error(ctx, response.CodeNotLogin)
+		return
+	}
+
+	if res, err := c.service.StartRun(id, TriggerManual, u.ID); err != nil {
+		logrus.Errorf("run workflow failed, error: %v", err)
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	page workflow run
+// @Tags		pipeline
+// @Param		page		query		int		false	"page number"
+// @Param		size		query		int		false	"size number"
+// @Param		workflowId	query		string	false	"workflow id"
+// @Success	200			{object}	response.Response
+// @Router		/pipeline/run/page [get]
+// @Produce	json
+func (c *Controller) handlePageRun(ctx *gin.Context) {
+	page, size := ginutil.GetPageParams(ctx)
+
+	if res, err := c.service.PageRun(page, size, &Run{WorkflowId: ctx.Query("workflowId")}); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	detail workflow run
+// @Tags		pipeline
+// @Param		id	path		string	true	"run id"
+// @Success	200	{object}	response.Response{data=RunDetail}
+// @Router		/pipeline/run/{id}/detail [get]
+// @Produce	json
+func (c *Controller) handleDetailRun(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	if res, err := c.service.DetailRun(id); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	cancel workflow run
+// @Tags		pipeline
+// @Param		id	path		string	true	"run id"
+// @Success	200	{object}	response.Response
+// @Router		/pipeline/run/{id}/cancel [post]
+// @Produce	json
+func (c *Controller) handleCancelRun(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	if err := c.service.CancelRun(id); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, nil)
+	}
+}
+
+// @Summary	delete finished workflow run with its artifacts
+// @Tags		pipeline
+// @Param		id	path		string	true	"run id"
+// @Success	200	{object}	response.Response
+// @Router		/pipeline/run/{id} [delete]
+// @Produce	json
+func (c *Controller) handleDeleteRun(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	if err := c.service.DeleteRun(id); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, nil)
+	}
+}
+
+// @Summary	list workflow revisions
+// @Tags		pipeline
+// @Param		id	path		string	true	"workflow id"
+// @Success	200	{object}	response.Response{data=[]Revision}
+// @Router		/pipeline/workflow/{id}/revisions [get]
+// @Produce	json
+func (c *Controller) handleListRevision(ctx *gin.Context) {
+	if res, err := c.service.ListRevision(ctx.Param("id")); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	get workflow revision
+// @Tags		pipeline
+// @Param		id		path		string	true	"workflow id"
+// @Param		version	path		int		true	"revision version"
+// @Success	200		{object}	response.Response{data=Revision}
+// @Router		/pipeline/workflow/{id}/revision/{version} [get]
+// @Produce	json
+func (c *Controller) handleGetRevision(ctx *gin.Context) {
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	if res, err := c.service.GetRevision(ctx.Param("id"), version); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeNotFound, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	diff workflow revisions
+// @Tags		pipeline
+// @Param		id		path		string	true	"workflow id"
+// @Param		from	query		int		true	"from version"
+// @Param		to		query		int		true	"to version"
+// @Success	200		{object}	response.Response{data=RevisionDiff}
+// @Router		/pipeline/workflow/{id}/diff [get]
+// @Produce	json
+func (c *Controller) handleDiffRevision(ctx *gin.Context) {
+	from, err := strconv.Atoi(ctx.Query("from"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	to, err := strconv.Atoi(ctx.Query("to"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	if res, err := c.service.DiffRevision(ctx.Param("id"), from, to); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	rollback workflow
+// @Description	restore workflow to a revision, it is saved as a new revision
+// @Tags		pipeline
+// @Param		id		path		string	true	"workflow id"
+// @Param		version	path		int		true	"revision version"
+// @Success	200		{object}	response.Response{data=Workflow}
+// @Router		/pipeline/workflow/{id}/revision/{version}/rollback [post]
+// @Produce	json
+func (c *Controller) handleRollbackWorkflow(ctx *gin.Context) {
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	if res, err := c.service.RollbackWorkflow(ctx.Param("id"), version); err != nil {
+		logrus.Errorf("rollback workflow failed, error: %v", err)
+		workflowError(ctx, err)
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	download artifact
+// @Tags		pipeline
+// @Param		id	path	string	true	"artifact id"
+// @Success	200	{file}	binary
+// @Router		/pipeline/artifact/{id}/download [get]
+// @Produce	octet-stream
+func (c *Controller) handleDownloadArtifact(ctx *gin.Context) {
+	artifact, reader, err := c.service.OpenArtifact(ctx, ctx.Param("id"))
+	if err != nil {
+		logrus.Errorf("open artifact failed, error: %v", err)
+		response.Error(ctx, response.CodeNotFound)
+		return
+	}
+	defer reader.Close()
+
+	ctx.DataFromReader(http.StatusOK, artifact.Size, "application/octet-stream", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", artifact.Name),
+	})
+}
+
+// @Summary	list node types
+// @Tags		pipeline
+// @Success	200	{object}	response.Response{data=[]NodeType}
+// @Router		/pipeline/node/types [get]
+// @Produce	json
+func (c *Controller) handleListNodeTypes(ctx *gin.Context) {
+	response.Success(ctx, c.service.ListNodeTypes())
+}
+
+// @Summary	approve node
+// @Tags		pipeline
+// @Param		id			path		string				true	"run id"
+// @Param		nodeId		path		string				true	"node id"
+// @Param		decision	body		ApprovalDecision	false	"approval comment"
+// @Success	200			{object}	response.Response
+// @Router		/pipeline/run/{id}/node/{nodeId}/approve [post]
+// @Produce	json
+func (c *Controller) handleApproveNode(ctx *gin.Context) {
+	c.decideApproval(ctx, true)
+}
+
+// @Summary	reject node
+// @Tags		pipeline
+// @Param		id			path		string				true	"run id"
+// @Param		nodeId		path		string				true	"node id"
+// @Param		decision	body		ApprovalDecision	false	"reject comment"
+// @Success	200			{object}	response.Response
+// @Router		/pipeline/run/{id}/node/{nodeId}/reject [post]
+// @Produce	json
+func (c *Controller) handleRejectNode(ctx *gin.Context) {
+	c.decideApproval(ctx, false)
+}
+
+func (c *Controller) decideApproval(ctx *gin.Context, approved bool) {
+	decision := new(ApprovalDecision)
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(decision); err != nil {
+			response.Error(ctx, response.CodeParamsError)
+			return
+		}
+	}
+
+	u, err := user.GetJWTService().ParseToken(ginutil.GetToken(ctx))
+	if err != nil {
+		response.Error(ctx, response.CodeNotLogin)
+		return
+	}
+	decision.Approved = approved
+	decision.By = u.ID
+
+	if err := c.service.DecideApproval(ctx.Param("id"), ctx.Param("nodeId"), decision); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, nil)
+	}
+}
+
 func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
 	api := group.Group("/pipeline")
 	api.GET("/workflow", c.handleListWorkflow)
 	api.POST("/workflow", c.handleAddWorkflow)
 	api.PUT("/workflow", c.handleUpdateWorkflow)
+	api.POST("/workflow/validate", c.handleValidateWorkflow)
+	api.POST("/workflow/import", c.handleImportWorkflow)
 	api.GET("/workflow/:id", c.handleGetWorkflow)
 	api.DELETE("/workflow/:id", c.handleDeleteWorkflow)
+	api.POST("/workflow/:id/run", c.handleRunWorkflow)
+	api.GET("/workflow/:id/revisions", c.handleListRevision)
+	api.GET("/workflow/:id/revision/:version", c.handleGetRevision)
+	api.POST("/workflow/:id/revision/:version/rollback", c.handleRollbackWorkflow)
+	api.GET("/workflow/:id/diff", c.handleDiffRevision)
+	api.GET("/workflow/:id/export", c.handleExportWorkflow)
+
+	api.GET("/run/page", c.handlePageRun)
+	api.GET("/run/:id/detail", c.handleDetailRun)
+	api.POST("/run/:id/cancel", c.handleCancelRun)
+	api.DELETE("/run/:id", c.handleDeleteRun)
+	api.POST("/run/:id/node/:nodeId/approve", c.handleApproveNode)
+	api.POST("/run/:id/node/:nodeId/reject", c.handleRejectNode)
+
+	api.GET("/node/types", c.handleListNodeTypes)
+
+	api.GET("/artifact/:id/download", c.handleDownloadArtifact)
 }
@@ -0,0 +1,199 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/MR5356/go-workflow"
+	"gopkg.in/yaml.v3"
+	"sort"
+)
+
+// WorkflowDefinition is the yaml format of workflow, it is kept in git and imported by key
+type WorkflowDefinition struct {
+	Key   string           `yaml:"key"`
+	Title string           `yaml:"title"`
+	Owner string           `yaml:"owner,omitempty"`
+	Nodes []NodeDefinition `yaml:"nodes"`
+	Edges []EdgeDefinition `yaml:"edges,omitempty"`
+}
+
+type NodeDefinition struct {
+	Id     string            `yaml:"id"`
+	Label  string            `yaml:"label,omitempty"`
+	Uses   string            `yaml:"uses"`
+	Params map[string]string `yaml:"params,omitempty"`
+}
+
+type EdgeDefinition struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+}
+
+// ParseWorkflowDefinition parse yaml definition to workflow request, unknown fields are rejected
+func ParseWorkflowDefinition(data []byte) (*WorkflowRequest, error) {
+	def := new(WorkflowDefinition)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(def); err != nil {
+		return nil, fmt.Errorf("parse workflow definition failed: %v", err)
+	}
+	if def.Key == "" {
+		return nil, fmt.Errorf("key of workflow definition is required")
+	}
+
+	wfr := &WorkflowRequest{
+		Workflow:    &Workflow{Key: def.Key, Title: def.Title, Owner: def.Owner},
+		WorkflowDAG: &workflow.WorkflowDAG{Nodes: make([]*workflow.Node, 0), Edges: make([]*workflow.Edge, 0)},
+	}
+	for _, n := range def.Nodes {
+		wfr.Nodes = append(wfr.Nodes, &workflow.Node{
+			Id:     n.Id,
+			Label:  n.Label,
+			Uses:   n.Uses,
+			Params: definitionParams(n.Uses, n.Params),
+		})
+	}
+	for _, e := range def.Edges {
+		wfr.Edges = append(wfr.Edges, &workflow.Edge{Source: e.Source, Target: e.Target})
+	}
+	return wfr, nil
+}
+
+// definitionParams convert param values to params of node, params of registered node type are described by the type
+func definitionParams(uses string, values map[string]string) Params {
+	res := make(Params, 0, len(values))
+	described := make(map[string]bool)
+	if nodeType, ok := getNodeType(uses); ok {
+		for _, p := range nodeType.Params {
+			value, ok := values[p.Key]
+			if !ok {
+				continue
+			}
+			res = append(res, &workflow.TaskParam{
+				Title:       p.Title,
+				Placeholder: p.Placeholder,
+				Order:       p.Order,
+				Type:        p.Type,
+				Required:    p.Required,
+				Key:         p.Key,
+				Value:       value,
+				Candidate:   p.Candidate,
+			})
+			described[p.Key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if !described[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		res = append(res, &workflow.TaskParam{Key: k, Title: k, Value: values[k]})
+	}
+	return res
+}
+
+// MarshalWorkflowDefinition marshal workflow to yaml definition, id of workflow is the key if it has no key
+func MarshalWorkflowDefinition(wfr *WorkflowRequest) ([]byte, error) {
+	def := &WorkflowDefinition{
+		Key:   wfr.Key,
+		Title: wfr.Title,
+		Owner: wfr.Owner,
+		Nodes: make([]NodeDefinition, 0),
+	}
+	if def.Key == "" {
+		def.Key = wfr.ID
+	}
+	if wfr.WorkflowDAG != nil {
+		for _, n := range wfr.Nodes {
+			node := NodeDefinition{Id: n.Id, Label: n.Label, Uses: n.Uses}
+			for _, p := range n.Params {
+				if p.Value == "" {
+					continue
+				}
+				if node.Params == nil {
+					node.Params = make(map[string]string)
+				}
+				node.Params[p.Key] = p.Value
+			}
+			def.Nodes = append(def.Nodes, node)
+		}
+		for _, e := range wfr.Edges {
+			def.Edges = append(def.Edges, EdgeDefinition{Source: e.Source, Target: e.Target})
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(def); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ImportWorkflow add or update workflow by key of yaml definition,
+// owner is used for new workflow if definition has no owner
+func (s *Service) ImportWorkflow(data []byte, owner string) (*WorkflowRequest, error) {
+	wfr, err := ParseWorkflowDefinition(data)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.findWorkflowByKey(wfr.Key)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		if wfr.Owner == "" {
+			wfr.Owner = owner
+		}
+		err = s.AddWorkflow(wfr)
+	} else {
+		wfr.ID = existing.ID
+		if wfr.Owner == "" {
+			wfr.Owner = existing.Owner
+		}
+		err = s.UpdateWorkflow(wfr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return wfr, nil
+}
+
+// findWorkflowByKey find workflow by key, workflow exported without key has its id as key
+func (s *Service) findWorkflowByKey(key string) (*Workflow, error) {
+	var res []*Workflow
+	if err := s.wfDB.DB.Where(&Workflow{Key: key}).Limit(1).Find(&res).Error; err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		if err := s.wfDB.DB.Where(&Workflow{ID: key}).Limit(1).Find(&res).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return res[0], nil
+}
+
+// ExportWorkflow export workflow as yaml definition
+func (s *Service) ExportWorkflow(id string) (*WorkflowRequest, []byte, error) {
+	wfr, err := s.GetWorkflow(&Workflow{ID: id})
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := MarshalWorkflowDefinition(wfr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wfr, data, nil
+}
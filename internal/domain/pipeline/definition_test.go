@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"errors"
+	"github.com/MR5356/go-workflow"
+	"strings"
+	"testing"
+)
+
+const testDefinition = `key: deploy-test
+title: deploy
+nodes:
+  - id: def-a
+    uses: ok
+    params:
+      message: hello
+  - id: def-b
+    label: notify
+    uses: builtin/definition-test
+    params:
+      subject: deployed
+      body: '{{ output "def-a" "message" }}'
+edges:
+  - source: def-a
+    target: def-b
+`
+
+func registerDefinitionTestType(t *testing.T) {
+	if err := RegisterNodeType(NodeType{
+		Name:   builtinPrefix + "definition-test",
+		Params: []*workflow.TaskParam{{Key: "subject", Title: "Subject", Required: true}},
+	}, &funcExecutor{}); err != nil {
+		t.Fatalf("register node type failed: %v", err)
+	}
+	t.Cleanup(func() { nodeTypes.Delete(builtinPrefix + "definition-test") })
+}
+
+func TestParseWorkflowDefinition(t *testing.T) {
+	registerDefinitionTestType(t)
+
+	wfr, err := ParseWorkflowDefinition([]byte(testDefinition))
+	if err != nil {
+		t.Fatalf("parse definition failed: %v", err)
+	}
+	if wfr.Key != "deploy-test" || len(wfr.Nodes) != 2 || len(wfr.Edges) != 1 {
+		t.Fatalf("unexpected workflow %+v", wfr)
+	}
+	for _, p := range wfr.Nodes[1].Params {
+		if p.Key == "subject" && (p.Title != "Subject" || !p.Required) {
+			t.Errorf("expected param described by node type, got %+v", p)
+		}
+	}
+
+	data, err := MarshalWorkflowDefinition(wfr)
+	if err != nil {
+		t.Fatalf("marshal definition failed: %v", err)
+	}
+	again, err := ParseWorkflowDefinition(data)
+	if err != nil {
+		t.Fatalf("parse exported definition failed: %v, definition: %s", err, data)
+	}
+	if diff := diffDAG(snapshotDAG(wfr.WorkflowDAG), snapshotDAG(again.WorkflowDAG), 1, 2); len(diff.ChangedNodes)+len(diff.AddedNodes)+len(diff.RemovedNodes)+len(diff.AddedEdges)+len(diff.RemovedEdges) > 0 {
+		t.Errorf("expected same workflow after export and import, got %+v", diff)
+	}
+
+	for name, def := range map[string]string{
+		"missing key":   "title: test\nnodes: []\n",
+		"unknown field": "key: test\ntitle: test\nsteps: []\n",
+		"invalid yaml":  "key: [",
+	} {
+		if _, err := ParseWorkflowDefinition([]byte(def)); err == nil {
+			t.Errorf("expected error of %s", name)
+		}
+	}
+}
+
+func TestService_ImportWorkflow(t *testing.T) {
+	s := newTestService(t)
+	registerDefinitionTestType(t)
+
+	wfr, err := s.ImportWorkflow([]byte(testDefinition), "importer")
+	if err != nil {
+		t.Fatalf("import workflow failed: %v", err)
+	}
+	if wfr.Owner != "importer" || wfr.Version != 1 {
+		t.Errorf("expected version 1 owned by importer, got %d %s", wfr.Version, wfr.Owner)
+	}
+
+	again, err := s.ImportWorkflow([]byte(testDefinition), "another")
+	if err != nil {
+		t.Fatalf("import workflow again failed: %v", err)
+	}
+	if again.ID != wfr.ID || again.Version != 2 || again.Owner != "importer" {
+		t.Errorf("expected workflow %s updated to version 2 keeping owner, got %s %d %s", wfr.ID, again.ID, again.Version, again.Owner)
+	}
+
+	_, data, err := s.ExportWorkflow(wfr.ID)
+	if err != nil {
+		t.Fatalf("export workflow failed: %v", err)
+	}
+	if res, err := s.ImportWorkflow(data, "importer"); err != nil || res.ID != wfr.ID {
+		t.Errorf("expected exported workflow imported to itself, got %v, error: %v", res, err)
+	}
+
+	invalid := "key: invalid-test\ntitle: invalid\nnodes:\n  - id: def-c\n    uses: ok\nedges:\n  - source: def-c\n    target: def-x\n"
+	var verr *ValidationError
+	if _, err := s.ImportWorkflow([]byte(invalid), "importer"); !errors.As(err, &verr) {
+		t.Errorf("expected validation error, got %v", err)
+	}
+
+	shared := strings.Replace(testDefinition, "key: deploy-test", "key: deploy-shared-test", 1)
+	other, err := s.ImportWorkflow([]byte(shared), "importer")
+	if err != nil {
+		t.Fatalf("import workflow sharing node ids failed: %v", err)
+	}
+	for _, id := range []string{wfr.ID, other.ID} {
+		res, err := s.GetWorkflow(&Workflow{ID: id})
+		if err != nil {
+			t.Fatalf("get workflow %s failed: %v", id, err)
+		}
+		ids := make(map[string]bool)
+		for _, n := range res.Nodes {
+			ids[n.Id] = true
+		}
+		if len(ids) != 2 || !ids["def-a"] || !ids["def-b"] {
+			t.Errorf("expected nodes def-a and def-b of workflow %s, got %v", id, ids)
+		}
+	}
+
+	dup := newTestWorkflow(map[string]string{"def-d": "ok"}, nil)
+	dup.ID = ""
+	dup.Title = "duplicated"
+	dup.Owner = "test"
+	dup.Key = "deploy-test"
+	if err := s.AddWorkflow(dup); err == nil {
+		t.Errorf("expected error of duplicated key")
+	}
+}
@@ -31,8 +31,10 @@ func (e *Edges) BeforeCreate(tx *gorm.DB) error {
 }
 
 type Nodes struct {
-	ID         string `json:"id" gorm:"primary_key;" example:"00000000-0000-0000-0000-000000000000"`
-	WorkflowId string `json:"workflowId" example:"00000000-0000-0000-0000-000000000000"`
+	ID string `json:"id" gorm:"primary_key;" example:"00000000-0000-0000-0000-000000000000"`
+	// NodeId is the id of node in workflow, it is only unique in the workflow
+	NodeId     string `json:"nodeId" gorm:"index:idx_workflow_node" example:"build"`
+	WorkflowId string `json:"workflowId" gorm:"index:idx_workflow_node" example:"00000000-0000-0000-0000-000000000000"`
 	Uses       string `json:"uses" example:"test"`
 	Label      string `json:"label" example:"test"`
 	Params     Params `json:"params" example:"test"`
@@ -68,6 +70,10 @@ type Workflow struct {
 	ID    string `json:"id" gorm:"primary_key;" example:"00000000-0000-0000-0000-000000000000"`
 	Title string `json:"title" example:"test" validate:"required"`
 	Owner string `json:"owner" example:"test" validate:"required"`
+	// Key is a stable name of workflow, importing definition with same key updates the workflow
+	Key string `json:"key" gorm:"index" example:"deploy-api"`
+	// Version number of the latest revision
+	Version int `json:"version" example:"1"`
 
 	database.BaseModel
 }
@@ -87,3 +93,171 @@ type WorkflowRequest struct {
 	*Workflow
 	*workflow.WorkflowDAG
 }
+
+// Run is an execution of workflow
+type Run struct {
+	ID          string    `json:"id" gorm:"primary_key;" example:"00000000-0000-0000-0000-000000000000"`
+	WorkflowId  string    `json:"workflowId" gorm:"index" example:"00000000-0000-0000-0000-000000000000"`
+	Title       string    `json:"title" example:"test"`
+	Status      string    `json:"status" example:"running"`
+	Error       string    `json:"error" gorm:"type:text"`
+	Revision    int       `json:"revision" example:"1"` // version of workflow revision used by run
+	Trigger     string    `json:"trigger" example:"manual"`
+	TriggeredBy string    `json:"triggeredBy"`
+	Variables   Outputs   `json:"variables" gorm:"type:text"` // variables given by trigger, e.g. commit of git push
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+
+	database.BaseModel
+}
+
+func (r *Run) TableName() string {
+	return "workflow_run"
+}
+
+func (r *Run) BeforeCreate(tx *gorm.DB) error {
+	if len(r.ID) == 0 {
+		r.ID = uuid.NewString()
+	}
+	return nil
+}
+
+// NodeRun is the execution of a node in workflow run
+type NodeRun struct {
+	ID        string    `json:"id" gorm:"primary_key;" example:"00000000-0000-0000-0000-000000000000"`
+	RunId     string    `json:"runId" gorm:"index" example:"00000000-0000-0000-0000-000000000000"`
+	NodeId    string    `json:"nodeId" example:"00000000-0000-0000-0000-000000000000"`
+	Label     string    `json:"label" example:"test"`
+	Uses      string    `json:"uses" example:"test"`
+	Status    string    `json:"status" example:"pending"`
+	Logs      string    `json:"logs" gorm:"type:text"`
+	Outputs   Outputs   `json:"outputs" gorm:"type:text"`
+	Error     string    `json:"error" gorm:"type:text"`
+	StartTime time.Time `json:"startTime"`
+	EndTime   time.Time `json:"endTime"`
+
+	database.BaseModel
+}
+
+func (n *NodeRun) TableName() string {
+	return "workflow_node_run"
+}
+
+func (n *NodeRun) BeforeCreate(tx *gorm.DB) error {
+	if len(n.ID) == 0 {
+		n.ID = uuid.NewString()
+	}
+	return nil
+}
+
+// Outputs key/values output by node
+type Outputs map[string]string
+
+func (o *Outputs) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	case []byte:
+		return json.Unmarshal(v, o)
+	default:
+		return nil
+	}
+}
+
+func (o Outputs) Value() (driver.Value, error) {
+	s, err := json.Marshal(o)
+	return string(s), err
+}
+
+// Artifact is a file output by node, its content is kept in ArtifactStore
+type Artifact struct {
+	ID     string `json:"id" gorm:"primary_key;" example:"00000000-0000-0000-0000-000000000000"`
+	RunId  string `json:"runId" gorm:"index" example:"00000000-0000-0000-0000-000000000000"`
+	NodeId string `json:"nodeId" example:"00000000-0000-0000-0000-000000000000"`
+	Name   string `json:"name" example:"report.txt"`
+	Size   int64  `json:"size" example:"1024"`
+	Key    string `json:"-"`
+
+	database.BaseModel
+}
+
+func (a *Artifact) TableName() string {
+	return "workflow_artifact"
+}
+
+func (a *Artifact) BeforeCreate(tx *gorm.DB) error {
+	if len(a.ID) == 0 {
+		a.ID = uuid.NewString()
+	}
+	return nil
+}
+
+// RunDetail is a workflow run with its node runs, artifacts and edges of the revision it used
+type RunDetail struct {
+	*Run
+	Nodes     []*NodeRun       `json:"nodes"`
+	Edges     []*workflow.Edge `json:"edges"`
+	Artifacts []*Artifact      `json:"artifacts"`
+}
+
+// DAG nodes and edges of workflow revision stored as json
+type DAG struct {
+	Nodes []*workflow.Node `json:"nodes"`
+	Edges []*workflow.Edge `json:"edges"`
+}
+
+func (d *DAG) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	case []byte:
+		return json.Unmarshal(v, d)
+	default:
+		return nil
+	}
+}
+
+func (d DAG) Value() (driver.Value, error) {
+	s, err := json.Marshal(d)
+	return string(s), err
+}
+
+// Revision is an immutable numbered snapshot of workflow, a new revision is added on each save
+type Revision struct {
+	ID         string `json:"id" gorm:"primary_key;" example:"00000000-0000-0000-0000-000000000000"`
+	WorkflowId string `json:"workflowId" gorm:"uniqueIndex:idx_workflow_revision" example:"00000000-0000-0000-0000-000000000000"`
+	Version    int    `json:"version" gorm:"uniqueIndex:idx_workflow_revision" example:"1"`
+	Title      string `json:"title" example:"test"`
+	Owner      string `json:"owner" example:"test"`
+	DAG        DAG    `json:"dag" gorm:"type:text"`
+
+	database.BaseModel
+}
+
+func (r *Revision) TableName() string {
+	return "workflow_revision"
+}
+
+func (r *Revision) BeforeCreate(tx *gorm.DB) error {
+	if len(r.ID) == 0 {
+		r.ID = uuid.NewString()
+	}
+	return nil
+}
+
+// NodeChange is a node changed between two revisions
+type NodeChange struct {
+	From *workflow.Node `json:"from"`
+	To   *workflow.Node `json:"to"`
+}
+
+// RevisionDiff is the difference of nodes and edges between two revisions
+type RevisionDiff struct {
+	From         int              `json:"from" example:"1"`
+	To           int              `json:"to" example:"2"`
+	AddedNodes   []*workflow.Node `json:"addedNodes"`
+	RemovedNodes []*workflow.Node `json:"removedNodes"`
+	ChangedNodes []*NodeChange    `json:"changedNodes"`
+	AddedEdges   []*workflow.Edge `json:"addedEdges"`
+	RemovedEdges []*workflow.Edge `json:"removedEdges"`
+}
@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"github.com/MR5356/go-workflow"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ParamTypeString = "string"
+	ParamTypeText   = "text"
+	ParamTypeNumber = "number"
+	ParamTypeSelect = "select"
+)
+
+// nodeTypes name -> NodeType
+var nodeTypes = sync.Map{}
+
+// NodeType is a built-in node type, a node uses it by setting Uses to its name
+type NodeType struct {
+	Name        string                `json:"name" example:"builtin/http"`
+	DisplayName string                `json:"displayName" example:"http request"`
+	Description string                `json:"description"`
+	Params      []*workflow.TaskParam `json:"params"`
+
+	executor NodeExecutor
+}
+
+// RegisterNodeType register built-in node type with its executor
+func RegisterNodeType(nodeType NodeType, executor NodeExecutor) error {
+	if _, ok := nodeTypes.Load(nodeType.Name); ok {
+		return fmt.Errorf("node type %s already registered", nodeType.Name)
+	}
+	nodeType.executor = executor
+	nodeTypes.Store(nodeType.Name, nodeType)
+	logrus.Infof("node type %s registered", nodeType.Name)
+	return nil
+}
+
+// GetNodeTypes list registered node types ordered by name
+func GetNodeTypes() []NodeType {
+	res := make([]NodeType, 0)
+	nodeTypes.Range(func(key, value any) bool {
+		res = append(res, value.(NodeType))
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func getNodeType(name string) (NodeType, bool) {
+	if nodeType, ok := nodeTypes.Load(name); ok {
+		return nodeType.(NodeType), true
+	}
+	return NodeType{}, false
+}
+
+// builtinNodeExecutor returns executor of registered node type, other nodes run as go-workflow plugin
+func builtinNodeExecutor(node *workflow.Node) NodeExecutor {
+	if nodeType, ok := getNodeType(node.Uses); ok {
+		return nodeType.executor
+	}
+	return pluginExecutor{}
+}
+
+// nodeParams wraps params of node, values of missing params are empty
+type nodeParams struct {
+	*workflow.TaskParams
+}
+
+func newNodeParams(node *workflow.Node) *nodeParams {
+	return &nodeParams{&workflow.TaskParams{Params: node.Params}}
+}
+
+// Required returns value of param, error if it is empty
+func (p *nodeParams) Required(key string) (string, error) {
+	value := strings.TrimSpace(p.Get(key))
+	if value == "" {
+		return "", fmt.Errorf("param %s is required", key)
+	}
+	return value, nil
+}
+
+// List returns comma or newline separated values of param
+func (p *nodeParams) List(key string) []string {
+	res := make([]string, 0)
+	for _, v := range strings.FieldsFunc(p.Get(key), func(r rune) bool { return r == ',' || r == '\n' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+type runIDKey struct{}
+
+// withRunID returns ctx carrying id of workflow run, node executors use it to identify the run
+func withRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+func runIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,301 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/MR5356/aurora/internal/domain/host"
+	"github.com/MR5356/aurora/internal/domain/notify"
+	"github.com/MR5356/aurora/internal/domain/schedule"
+	"github.com/MR5356/aurora/internal/domain/script"
+	"github.com/MR5356/go-workflow"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	NodeTypeScript    = "builtin/script"
+	NodeTypeHTTP      = "builtin/http"
+	NodeTypeContainer = "builtin/container"
+	NodeTypeNotify    = "builtin/notify"
+	NodeTypeApproval  = "builtin/approval"
+)
+
+func registerBuiltinNodeTypes() error {
+	builtins := []struct {
+		nodeType NodeType
+		executor NodeExecutor
+	}{
+		{
+			nodeType: NodeType{
+				Name:        NodeTypeScript,
+				DisplayName: "run script",
+				Description: "run a stored script on selected hosts",
+				Params: []*workflow.TaskParam{
+					{Order: 1, Key: "scriptId", Title: "Script", Type: ParamTypeString, Required: true, Placeholder: "id of script"},
+					{Order: 2, Key: "hostIds", Title: "Hosts", Type: ParamTypeText, Required: true, Placeholder: "comma separated ids of hosts"},
+					{Order: 3, Key: "params", Title: "Params", Type: ParamTypeString, Placeholder: "params passed to script"},
+				},
+			},
+			executor: scriptExecutor{},
+		},
+		{
+			nodeType: NodeType{
+				Name:        NodeTypeHTTP,
+				DisplayName: "http request",
+				Description: "make an http request and check its status code, outputs: statusCode, body",
+				Params: []*workflow.TaskParam{
+					{Order: 1, Key: "method", Title: "Method", Type: ParamTypeSelect, Value: http.MethodGet, Candidate: candidates(http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete)},
+					{Order: 2, Key: "url", Title: "URL", Type: ParamTypeString, Required: true, Placeholder: "https://example.com"},
+					{Order: 3, Key: "headers", Title: "Headers", Type: ParamTypeText, Placeholder: "one header per line, e.g. Content-Type: application/json"},
+					{Order: 4, Key: "body", Title: "Body", Type: ParamTypeText},
+					{Order: 5, Key: "expectedStatus", Title: "Expected Status", Type: ParamTypeString, Placeholder: "comma separated status codes, empty means 2xx"},
+					{Order: 6, Key: "timeout", Title: "Timeout", Type: ParamTypeNumber, Placeholder: "timeout in seconds"},
+					{Order: 7, Key: "saveAs", Title: "Save As", Type: ParamTypeString, Placeholder: "save response body as artifact with this name"},
+				},
+			},
+			executor: httpExecutor{},
+		},
+		{
+			nodeType: NodeType{
+				Name:        NodeTypeContainer,
+				DisplayName: "container command",
+				Description: "run a command in a container of host, outputs: exitCode",
+				Params: []*workflow.TaskParam{
+					{Order: 1, Key: "hostId", Title: "Host", Type: ParamTypeString, Required: true, Placeholder: "id of host"},
+					{Order: 2, Key: "driver", Title: "Driver", Type: ParamTypeSelect, Value: "docker", Candidate: candidates("docker")},
+					{Order: 3, Key: "containerId", Title: "Container", Type: ParamTypeString, Required: true, Placeholder: "id or name of container"},
+					{Order: 4, Key: "user", Title: "User", Type: ParamTypeString},
+					{Order: 5, Key: "command", Title: "Command", Type: ParamTypeText, Required: true, Placeholder: "command run by sh -c"},
+				},
+			},
+			executor: containerExecutor{},
+		},
+		{
+			nodeType: NodeType{
+				Name:        NodeTypeNotify,
+				DisplayName: "notification",
+				Description: "send a notification",
+				Params: []*workflow.TaskParam{
+					{Order: 1, Key: "type", Title: "Type", Type: ParamTypeSelect, Value: notify.TypeEmail, Candidate: candidates(notify.TypeEmail)},
+					{Order: 2, Key: "receivers", Title: "Receivers", Type: ParamTypeText, Required: true, Placeholder: "comma separated receivers"},
+					{Order: 3, Key: "subject", Title: "Subject", Type: ParamTypeString, Required: true},
+					{Order: 4, Key: "body", Title: "Body", Type: ParamTypeText, Required: true},
+				},
+			},
+			executor: notifyExecutor{},
+		},
+		{
+			nodeType: NodeType{
+				Name:        NodeTypeApproval,
+				DisplayName: "manual approval",
+				Description: "wait until the node is approved or rejected, outputs: approvedBy, comment",
+				Params: []*workflow.TaskParam{
+					{Order: 1, Key: "approvers", Title: "Approvers", Type: ParamTypeText, Placeholder: "comma separated user ids, empty means anyone"},
+					{Order: 2, Key: "timeout", Title: "Timeout", Type: ParamTypeNumber, Placeholder: "timeout in seconds, empty means no timeout"},
+				},
+			},
+			executor: approvalExecutor{},
+		},
+	}
+
+	for _, b := range builtins {
+		if err := RegisterNodeType(b.nodeType, b.executor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func candidates(values ...string) []*workflow.Candidate {
+	res := make([]*workflow.Candidate, 0, len(values))
+	for _, v := range values {
+		res = append(res, &workflow.Candidate{DisplayName: v, Value: v})
+	}
+	return res
+}
+
+// scriptExecutor runs stored script on hosts by script task
+type scriptExecutor struct{}
+
+func (scriptExecutor) Execute(ctx context.Context, node *workflow.Node, log io.Writer) error {
+	params := newNodeParams(node)
+	scriptId, err := params.Required("scriptId")
+	if err != nil {
+		return err
+	}
+	rsp := &script.RunScriptParams{Params: params.Get("params")}
+	if rsp.ScriptId, err = uuid.Parse(scriptId); err != nil {
+		return fmt.Errorf("invalid script id %s: %v", scriptId, err)
+	}
+	for _, id := range params.List("hostIds") {
+		hostId, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid host id %s: %v", id, err)
+		}
+		rsp.HostIds = append(rsp.HostIds, hostId)
+	}
+	if len(rsp.HostIds) == 0 {
+		return fmt.Errorf("param hostIds is required")
+	}
+
+	ps, _ := json.Marshal(rsp)
+	task := script.NewTask()
+	task.SetParams(string(ps))
+	return writeResult(log, task.Run(ctx))
+}
+
+// httpExecutor makes http request by webhook task
+type httpExecutor struct{}
+
+func (httpExecutor) Execute(ctx context.Context, node *workflow.Node, log io.Writer) error {
+	params := newNodeParams(node)
+	wp := &schedule.WebhookParams{
+		Method:  params.Get("method"),
+		Body:    params.Get("body"),
+		Headers: make(map[string]string),
+	}
+
+	var err error
+	if wp.URL, err = params.Required("url"); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(params.Get("headers"), "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			wp.Headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	for _, s := range params.List("expectedStatus") {
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid expected status %s", s)
+		}
+		wp.ExpectedStatus = append(wp.ExpectedStatus, code)
+	}
+	if timeout := params.Get("timeout"); timeout != "" {
+		if wp.Timeout, err = strconv.ParseInt(timeout, 10, 64); err != nil {
+			return fmt.Errorf("invalid timeout %s", timeout)
+		}
+	}
+
+	fmt.Fprintf(log, "%s %s\n", wp.Method, wp.URL)
+	ps, _ := json.Marshal(wp)
+	task := &schedule.WebhookTask{}
+	task.SetParams(string(ps))
+	outputs := OutputsFromContext(ctx)
+
+	// response body is streamed to artifact store, the artifact is saved only if the request succeeds
+	saveAs := params.Get("saveAs")
+	var pw *io.PipeWriter
+	saved := make(chan error, 1)
+	if saveAs != "" {
+		var pr *io.PipeReader
+		pr, pw = io.Pipe()
+		task.BodyWriter = pw
+		go func() {
+			err := outputs.AddArtifact(saveAs, limitArtifact(pr, maxArtifactSize))
+			// unblock the request if the artifact failed before the whole body is read
+			pr.CloseWithError(err)
+			saved <- err
+		}()
+	}
+
+	result := task.Run(ctx)
+	response := new(schedule.WebhookOutput)
+	if err := json.Unmarshal([]byte(result.Output), response); err == nil {
+		outputs.Set("statusCode", strconv.Itoa(response.StatusCode))
+		outputs.Set("body", response.Body)
+	}
+	if pw != nil {
+		pw.CloseWithError(result.Error)
+		if err := <-saved; err != nil && result.Error == nil {
+			return err
+		}
+	}
+	return writeResult(log, result)
+}
+
+// containerExecutor runs command in container of host
+type containerExecutor struct{}
+
+func (containerExecutor) Execute(ctx context.Context, node *workflow.Node, log io.Writer) error {
+	params := newNodeParams(node)
+	hostId, err := params.Required("hostId")
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(hostId)
+	if err != nil {
+		return fmt.Errorf("invalid host id %s: %v", hostId, err)
+	}
+	containerId, err := params.Required("containerId")
+	if err != nil {
+		return err
+	}
+	command, err := params.Required("command")
+	if err != nil {
+		return err
+	}
+	// exec is only implemented by docker driver of host
+	driver := params.Get("driver")
+	if driver == "" {
+		driver = "docker"
+	}
+	if driver != "docker" {
+		return fmt.Errorf("driver %s is not supported by container node", driver)
+	}
+
+	fmt.Fprintf(log, "exec in container %s: %s\n", containerId, command)
+	code, err := host.GetService().ExecContainerCommand(ctx, id, containerId, driver, params.Get("user"), []string{"sh", "-c", command}, log)
+	if err != nil {
+		return err
+	}
+	OutputsFromContext(ctx).Set("exitCode", strconv.Itoa(code))
+	if code != 0 {
+		return fmt.Errorf("command exited with code %d", code)
+	}
+	return nil
+}
+
+// notifyExecutor sends notification by notifier
+type notifyExecutor struct{}
+
+func (notifyExecutor) Execute(ctx context.Context, node *workflow.Node, log io.Writer) error {
+	params := newNodeParams(node)
+	typ := params.Get("type")
+	if typ == "" {
+		typ = notify.TypeEmail
+	}
+	notifier := notify.GetNotifierManager().GetNotifier(typ)
+	if notifier == nil {
+		return fmt.Errorf("notifier %s not found", typ)
+	}
+
+	msg := &notify.MessageTemplate{
+		Receivers: notify.MessageReceiver{Receivers: params.List("receivers"), Type: typ},
+	}
+	if len(msg.Receivers.Receivers) == 0 {
+		return fmt.Errorf("param receivers is required")
+	}
+	var err error
+	if msg.Subject, err = params.Required("subject"); err != nil {
+		return err
+	}
+	if msg.Body, err = params.Required("body"); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(log, "send %s notification to %s\n", typ, strings.Join(msg.Receivers.Receivers, ","))
+	return notifier.Send(ctx, msg)
+}
+
+// writeResult writes output of schedule task result to log and returns its error
+func writeResult(log io.Writer, result *schedule.Result) error {
+	if result.Output != "" {
+		fmt.Fprintln(log, result.Output)
+	}
+	return result.Error
+}
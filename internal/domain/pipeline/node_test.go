@@ -0,0 +1,221 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/go-workflow"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestNode(uses string, params map[string]string) *workflow.Node {
+	node := &workflow.Node{Id: "node", Uses: uses}
+	for k, v := range params {
+		node.Params = append(node.Params, &workflow.TaskParam{Key: k, Value: v})
+	}
+	return node
+}
+
+func TestRegisterBuiltinNodeTypes(t *testing.T) {
+	if err := registerBuiltinNodeTypes(); err != nil {
+		t.Fatalf("register builtin node types failed: %v", err)
+	}
+	if err := RegisterNodeType(NodeType{Name: NodeTypeHTTP}, httpExecutor{}); err == nil {
+		t.Errorf("expected error of duplicate node type")
+	}
+
+	types := GetNodeTypes()
+	names := make([]string, 0)
+	for _, nt := range types {
+		names = append(names, nt.Name)
+		if len(nt.Params) == 0 {
+			t.Errorf("expected params of node type %s", nt.Name)
+		}
+	}
+	want := []string{NodeTypeApproval, NodeTypeContainer, NodeTypeHTTP, NodeTypeNotify, NodeTypeScript}
+	if len(names) != len(want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, names)
+		}
+	}
+
+	if _, ok := builtinNodeExecutor(newTestNode(NodeTypeHTTP, nil)).(httpExecutor); !ok {
+		t.Errorf("expected http executor")
+	}
+	if _, ok := builtinNodeExecutor(newTestNode("./plugin", nil)).(pluginExecutor); !ok {
+		t.Errorf("expected plugin executor")
+	}
+}
+
+func TestPluginExecutor(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	defer func(path string) { cfg.Server.PluginPath = path }(cfg.Server.PluginPath)
+	cfg.Server.PluginPath = dir
+	_ = os.WriteFile(filepath.Join(dir, "installed"), []byte("#!/bin/sh\n"), 0o755)
+	_ = os.WriteFile(filepath.Join(dir, "not-executable"), []byte("#!/bin/sh\n"), 0o644)
+
+	if path, err := resolvePlugin("installed"); err != nil || path != filepath.Join(dir, "installed") {
+		t.Errorf("expected installed plugin resolved, got %s, %v", path, err)
+	}
+
+	marker := filepath.Join(t.TempDir(), "marker")
+	for _, uses := range []string{"not-installed", "not-executable", "../installed", "touch " + marker, "installed; touch " + marker} {
+		if err := (pluginExecutor{}).Execute(context.Background(), newTestNode(uses, nil), io.Discard); err == nil {
+			t.Errorf("expected error of plugin %q", uses)
+		}
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("expected no command executed")
+	}
+}
+
+func TestNodeParams(t *testing.T) {
+	params := newNodeParams(newTestNode("", map[string]string{"list": "a, b\nc,,", "empty": " "}))
+	if got := params.List("list"); len(got) != 3 || got[0] != "a" || got[2] != "c" {
+		t.Errorf("expected [a b c], got %v", got)
+	}
+	if _, err := params.Required("empty"); err == nil {
+		t.Errorf("expected error of empty required param")
+	}
+	if v, err := params.Required("list"); err != nil || v == "" {
+		t.Errorf("expected value, got %s, error: %v", v, err)
+	}
+}
+
+func TestHttpExecutor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("X-Test") != "test" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		wantErr bool
+	}{
+		{name: "success", params: map[string]string{"method": "POST", "url": server.URL, "headers": "X-Test: test"}},
+		{name: "expected status", params: map[string]string{"method": "POST", "url": server.URL, "headers": "X-Test: test", "expectedStatus": "200"}, wantErr: true},
+		{name: "bad request", params: map[string]string{"method": "GET", "url": server.URL}, wantErr: true},
+		{name: "missing url", params: map[string]string{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := new(bytes.Buffer)
+			if err := (httpExecutor{}).Execute(context.Background(), newTestNode(NodeTypeHTTP, tt.params), log); (err != nil) != tt.wantErr {
+				t.Errorf("Execute() error = %v, wantErr %v, log: %s", err, tt.wantErr, log.String())
+			}
+		})
+	}
+}
+
+func TestHttpExecutorOutputs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	var saved string
+	outputs := newNodeOutputs(func(name string, r io.Reader) (*Artifact, error) {
+		content, err := io.ReadAll(r)
+		saved = string(content)
+		return &Artifact{Name: name, Size: int64(len(content))}, err
+	})
+	node := newTestNode(NodeTypeHTTP, map[string]string{"url": server.URL, "saveAs": "body.txt"})
+	if err := (httpExecutor{}).Execute(withNodeOutputs(context.Background(), outputs), node, new(bytes.Buffer)); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	values := outputs.Values()
+	if values["statusCode"] != "200" || values["body"] != "hello" {
+		t.Errorf("unexpected outputs %v", values)
+	}
+	if len(outputs.Artifacts()) != 1 || saved != "hello" {
+		t.Errorf("expected response body saved as artifact, got %q", saved)
+	}
+
+	saved = ""
+	outputs = newNodeOutputs(func(name string, r io.Reader) (*Artifact, error) {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		saved = string(content)
+		return &Artifact{Name: name, Size: int64(len(content))}, nil
+	})
+	node = newTestNode(NodeTypeHTTP, map[string]string{"url": server.URL, "saveAs": "body.txt", "expectedStatus": "404"})
+	if err := (httpExecutor{}).Execute(withNodeOutputs(context.Background(), outputs), node, new(bytes.Buffer)); err == nil {
+		t.Fatalf("expected error of unexpected status")
+	}
+	if len(outputs.Artifacts()) != 0 || saved != "" {
+		t.Errorf("expected response body of failed request not saved, got %q", saved)
+	}
+}
+
+func TestContainerExecutor(t *testing.T) {
+	node := newTestNode(NodeTypeContainer, map[string]string{"hostId": "00000000-0000-0000-0000-000000000001", "containerId": "test", "command": "id", "driver": "containerd"})
+	if err := (containerExecutor{}).Execute(context.Background(), node, io.Discard); err == nil {
+		t.Errorf("expected error of unsupported driver")
+	}
+}
+
+func TestApprovalExecutor(t *testing.T) {
+	s := &Service{}
+
+	execute := func(runId string, params map[string]string) chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- (approvalExecutor{}).Execute(withRunID(context.Background(), runId), newTestNode(NodeTypeApproval, params), new(syncBuffer))
+		}()
+		// wait until approval is pending
+		for i := 0; i < 100; i++ {
+			if _, ok := pendingApprovals.Load(approvalKey(runId, "node")); ok {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return done
+	}
+
+	done := execute("approve", map[string]string{"approvers": "alice,bob"})
+	if err := s.DecideApproval("approve", "node", &ApprovalDecision{Approved: true, By: "eve"}); err == nil {
+		t.Errorf("expected error of user not approver")
+	}
+	if err := s.DecideApproval("approve", "node", &ApprovalDecision{Approved: true, By: "bob"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected approved, got %v", err)
+	}
+	if err := s.DecideApproval("approve", "node", &ApprovalDecision{Approved: true, By: "bob"}); err == nil {
+		t.Errorf("expected error of node not waiting for approval")
+	}
+
+	done = execute("reject", nil)
+	if err := s.DecideApproval("reject", "node", &ApprovalDecision{Approved: false, By: "bob", Comment: "no"}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := <-done; err == nil {
+		t.Errorf("expected rejected error")
+	}
+
+	done = execute("timeout", map[string]string{"timeout": "1"})
+	if err := <-done; !errors.Is(err, ErrApprovalTimeout) {
+		t.Errorf("expected %v, got %v", ErrApprovalTimeout, err)
+	}
+}
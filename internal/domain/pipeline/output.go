@@ -0,0 +1,148 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/MR5356/go-workflow"
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// NodeOutputs collects key/values and artifacts output by a running node
+type NodeOutputs struct {
+	mu        sync.Mutex
+	values    Outputs
+	artifacts []*Artifact
+
+	// save stores content of artifact, it is nil if artifacts are not supported
+	save func(name string, r io.Reader) (*Artifact, error)
+}
+
+func newNodeOutputs(save func(name string, r io.Reader) (*Artifact, error)) *NodeOutputs {
+	return &NodeOutputs{values: make(Outputs), save: save}
+}
+
+// Set output value of key, later nodes reference it by {{ output "nodeId" "key" }}
+func (o *NodeOutputs) Set(key, value string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.values[key] = value
+}
+
+// AddArtifact store content of r as artifact, later nodes reference its id by {{ artifact "nodeId" "name" }}
+func (o *NodeOutputs) AddArtifact(name string, r io.Reader) error {
+	if name = strings.TrimSpace(name); name == "" || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid artifact name %q", name)
+	}
+	if o.save == nil {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+
+	artifact, err := o.save(name, r)
+	if err != nil {
+		return fmt.Errorf("save artifact %s failed: %v", name, err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.artifacts = append(o.artifacts, artifact)
+	return nil
+}
+
+// Values returns a copy of output values
+func (o *NodeOutputs) Values() Outputs {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	res := make(Outputs, len(o.values))
+	for k, v := range o.values {
+		res[k] = v
+	}
+	return res
+}
+
+// Artifacts returns artifacts added by node
+func (o *NodeOutputs) Artifacts() []*Artifact {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*Artifact{}, o.artifacts...)
+}
+
+type outputsKey struct{}
+
+func withNodeOutputs(ctx context.Context, outputs *NodeOutputs) context.Context {
+	return context.WithValue(ctx, outputsKey{}, outputs)
+}
+
+// OutputsFromContext returns outputs of the running node, outputs are dropped if ctx does not carry them
+func OutputsFromContext(ctx context.Context) *NodeOutputs {
+	if outputs, ok := ctx.Value(outputsKey{}).(*NodeOutputs); ok {
+		return outputs
+	}
+	return newNodeOutputs(nil)
+}
+
+// templateData is the data of param templates
+type templateData struct {
+	RunId     string
+	NodeId    string
+	Variables Outputs
+}
+
+// renderParams render templates in param values of node with outputs of its upstream nodes,
+// upstream maps node id to whether it is an upstream node
+func renderParams(node *workflow.Node, data *templateData, upstream map[string]bool, outputs map[string]Outputs, artifacts map[string][]*Artifact) (*workflow.Node, error) {
+	funcs := template.FuncMap{
+		"output": func(nodeId, key string) (string, error) {
+			if !upstream[nodeId] {
+				return "", fmt.Errorf("node %s is not upstream of node %s", nodeId, node.Id)
+			}
+			value, ok := outputs[nodeId][key]
+			if !ok {
+				return "", fmt.Errorf("output %s of node %s not found", key, nodeId)
+			}
+			return value, nil
+		},
+		"artifact": func(nodeId, name string) (string, error) {
+			if !upstream[nodeId] {
+				return "", fmt.Errorf("node %s is not upstream of node %s", nodeId, node.Id)
+			}
+			for _, a := range artifacts[nodeId] {
+				if a.Name == name {
+					return a.ID, nil
+				}
+			}
+			return "", fmt.Errorf("artifact %s of node %s not found", name, nodeId)
+		},
+	}
+
+	rendered := &workflow.Node{Id: node.Id, Label: node.Label, Uses: node.Uses, Status: node.Status}
+	for _, p := range node.Params {
+		value := p.Value
+		if strings.Contains(value, "{{") {
+			tpl, err := template.New(p.Key).Funcs(funcs).Option("missingkey=error").Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("parse template of param %s failed: %v", p.Key, err)
+			}
+			buf := new(bytes.Buffer)
+			if err := tpl.Execute(buf, data); err != nil {
+				return nil, fmt.Errorf("render template of param %s failed: %v", p.Key, err)
+			}
+			value = buf.String()
+		}
+		rendered.Params = append(rendered.Params, &workflow.TaskParam{
+			Title:       p.Title,
+			Placeholder: p.Placeholder,
+			Order:       p.Order,
+			Type:        p.Type,
+			Required:    p.Required,
+			Key:         p.Key,
+			Value:       value,
+			Candidate:   p.Candidate,
+		})
+	}
+	return rendered, nil
+}
@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	database2 "github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/go-workflow"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// addRevision add next revision of workflow in tx, version of wf is set to the new version
+func (s *Service) addRevision(tx *gorm.DB, wf *WorkflowRequest) error {
+	var latest int
+	if err := tx.Model(&Revision{}).Where("workflow_id = ?", wf.Workflow.ID).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	revision := &Revision{
+		WorkflowId: wf.Workflow.ID,
+		Version:    latest + 1,
+		Title:      wf.Title,
+		Owner:      wf.Owner,
+		DAG:        snapshotDAG(wf.WorkflowDAG),
+	}
+	if err := s.revisionDB.Insert(revision, tx); err != nil {
+		return err
+	}
+	wf.Workflow.Version = revision.Version
+	return nil
+}
+
+// backfillRevisions add the initial revision for workflows created before revisions are recorded,
+// runs of them without revision are bound to it, so that their edges can be shown
+func (s *Service) backfillRevisions() error {
+	var ids []string
+	if err := s.wfDB.DB.Model(&Workflow{}).
+		Where("id NOT IN (?)", s.revisionDB.DB.Model(&Revision{}).Select("workflow_id")).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		wf, err := s.wfDB.Detail(&Workflow{ID: id})
+		if err != nil {
+			return err
+		}
+		wfr, err := s.GetWorkflow(wf)
+		if err != nil {
+			return err
+		}
+
+		err = database2.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := s.addRevision(tx, wfr); err != nil {
+				return err
+			}
+			if err := s.wfDB.Update(&Workflow{ID: id}, map[string]any{"Version": wfr.Workflow.Version}, tx); err != nil {
+				return err
+			}
+			return tx.Model(&Run{}).Where("workflow_id = ? AND revision = 0", id).Update("revision", wfr.Workflow.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("backfill revision of workflow %s failed: %v", id, err)
+		}
+		logrus.Infof("backfill revision %d of workflow %s", wfr.Workflow.Version, id)
+	}
+	return nil
+}
+
+// snapshotDAG copy nodes and edges of dag without their run status
+func snapshotDAG(dag *workflow.WorkflowDAG) DAG {
+	res := DAG{Nodes: make([]*workflow.Node, 0), Edges: make([]*workflow.Edge, 0)}
+	if dag == nil {
+		return res
+	}
+	for _, n := range dag.Nodes {
+		res.Nodes = append(res.Nodes, &workflow.Node{Id: n.Id, Label: n.Label, Uses: n.Uses, Params: n.Params})
+	}
+	for _, e := range dag.Edges {
+		res.Edges = append(res.Edges, &workflow.Edge{Source: e.Source, Target: e.Target})
+	}
+	return res
+}
+
+// ListRevision list revisions of workflow, latest first
+func (s *Service) ListRevision(workflowId string) (res []*Revision, err error) {
+	err = s.revisionDB.DB.Order("version desc").Find(&res, &Revision{WorkflowId: workflowId}).Error
+	return
+}
+
+// GetRevision get revision of workflow by version
+func (s *Service) GetRevision(workflowId string, version int) (*Revision, error) {
+	// zero value fields are ignored by query conditions
+	if workflowId == "" || version <= 0 {
+		return nil, fmt.Errorf("revision %d of workflow %s not found", version, workflowId)
+	}
+	return s.revisionDB.Detail(&Revision{WorkflowId: workflowId, Version: version})
+}
+
+// DiffRevision compare nodes and edges of two revisions of workflow
+func (s *Service) DiffRevision(workflowId string, from, to int) (*RevisionDiff, error) {
+	fromRevision, err := s.GetRevision(workflowId, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := s.GetRevision(workflowId, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffDAG(fromRevision.DAG, toRevision.DAG, from, to), nil
+}
+
+// RollbackWorkflow restore workflow to a revision, it is saved as a new revision
+func (s *Service) RollbackWorkflow(workflowId string, version int) (*Workflow, error) {
+	revision, err := s.GetRevision(workflowId, version)
+	if err != nil {
+		return nil, err
+	}
+
+	wf := &WorkflowRequest{
+		Workflow:    &Workflow{ID: workflowId, Title: revision.Title, Owner: revision.Owner},
+		WorkflowDAG: &workflow.WorkflowDAG{Nodes: revision.DAG.Nodes, Edges: revision.DAG.Edges},
+	}
+	if err := s.UpdateWorkflow(wf); err != nil {
+		return nil, err
+	}
+	return wf.Workflow, nil
+}
+
+func diffDAG(from, to DAG, fromVersion, toVersion int) *RevisionDiff {
+	res := &RevisionDiff{
+		From:         fromVersion,
+		To:           toVersion,
+		AddedNodes:   make([]*workflow.Node, 0),
+		RemovedNodes: make([]*workflow.Node, 0),
+		ChangedNodes: make([]*NodeChange, 0),
+		AddedEdges:   make([]*workflow.Edge, 0),
+		RemovedEdges: make([]*workflow.Edge, 0),
+	}
+
+	fromNodes := make(map[string]*workflow.Node)
+	for _, n := range from.Nodes {
+		fromNodes[n.Id] = n
+	}
+	toNodes := make(map[string]*workflow.Node)
+	for _, n := range to.Nodes {
+		toNodes[n.Id] = n
+		if old, ok := fromNodes[n.Id]; !ok {
+			res.AddedNodes = append(res.AddedNodes, n)
+		} else if !nodeEqual(old, n) {
+			res.ChangedNodes = append(res.ChangedNodes, &NodeChange{From: old, To: n})
+		}
+	}
+	for _, n := range from.Nodes {
+		if _, ok := toNodes[n.Id]; !ok {
+			res.RemovedNodes = append(res.RemovedNodes, n)
+		}
+	}
+
+	fromEdges := make(map[string]bool)
+	for _, e := range from.Edges {
+		fromEdges[edgeId(e)] = true
+	}
+	toEdges := make(map[string]bool)
+	for _, e := range to.Edges {
+		toEdges[edgeId(e)] = true
+		if !fromEdges[edgeId(e)] {
+			res.AddedEdges = append(res.AddedEdges, e)
+		}
+	}
+	for _, e := range from.Edges {
+		if !toEdges[edgeId(e)] {
+			res.RemovedEdges = append(res.RemovedEdges, e)
+		}
+	}
+	return res
+}
+
+func nodeEqual(a, b *workflow.Node) bool {
+	if a.Label != b.Label || a.Uses != b.Uses {
+		return false
+	}
+	pa, _ := json.Marshal(a.Params)
+	pb, _ := json.Marshal(b.Params)
+	return string(pa) == string(pb)
+}
@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"github.com/MR5356/go-workflow"
+	"testing"
+)
+
+func TestDiffDAG(t *testing.T) {
+	from := DAG{
+		Nodes: []*workflow.Node{{Id: "a", Uses: "./a"}, {Id: "b", Uses: "./b"}, {Id: "c", Uses: "./c", Params: []*workflow.TaskParam{{Key: "k", Value: "1"}}}},
+		Edges: []*workflow.Edge{{Source: "a", Target: "b"}, {Source: "a", Target: "c"}},
+	}
+	to := DAG{
+		Nodes: []*workflow.Node{{Id: "a", Uses: "./a"}, {Id: "c", Uses: "./c", Params: []*workflow.TaskParam{{Key: "k", Value: "2"}}}, {Id: "d", Uses: "./d"}},
+		Edges: []*workflow.Edge{{Source: "a", Target: "c"}, {Source: "c", Target: "d"}},
+	}
+
+	diff := diffDAG(from, to, 1, 2)
+	if len(diff.AddedNodes) != 1 || diff.AddedNodes[0].Id != "d" {
+		t.Errorf("expected added node d, got %v", diff.AddedNodes)
+	}
+	if len(diff.RemovedNodes) != 1 || diff.RemovedNodes[0].Id != "b" {
+		t.Errorf("expected removed node b, got %v", diff.RemovedNodes)
+	}
+	if len(diff.ChangedNodes) != 1 || diff.ChangedNodes[0].To.Id != "c" {
+		t.Errorf("expected changed node c, got %v", diff.ChangedNodes)
+	}
+	if len(diff.AddedEdges) != 1 || edgeId(diff.AddedEdges[0]) != "c->d" {
+		t.Errorf("expected added edge c->d, got %v", diff.AddedEdges)
+	}
+	if len(diff.RemovedEdges) != 1 || edgeId(diff.RemovedEdges[0]) != "a->b" {
+		t.Errorf("expected removed edge a->b, got %v", diff.RemovedEdges)
+	}
+}
+
+func TestService_Revision(t *testing.T) {
+	s := newTestService(t)
+
+	wfr := newTestWorkflow(map[string]string{"rev-a": "ok"}, nil)
+	wfr.ID = ""
+	wfr.Title = "v1"
+	wfr.Owner = "test"
+	if err := s.AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+	if wfr.Version != 1 {
+		t.Errorf("expected version 1, got %d", wfr.Version)
+	}
+
+	id := wfr.ID
+	wfr = newTestWorkflow(map[string]string{"rev-a": "ok", "rev-b": "ok"}, [][2]string{{"rev-a", "rev-b"}})
+	wfr.ID = id
+	wfr.Title = "v2"
+	wfr.Owner = "test"
+	if err := s.UpdateWorkflow(wfr); err != nil {
+		t.Fatalf("update workflow failed: %v", err)
+	}
+	if wfr.Version != 2 {
+		t.Errorf("expected version 2, got %d", wfr.Version)
+	}
+
+	unknown := newTestWorkflow(map[string]string{"rev-a": "ok"}, nil)
+	unknown.ID = "unknown"
+	unknown.Title = "unknown"
+	unknown.Owner = "test"
+	if err := s.UpdateWorkflow(unknown); err == nil || err.Error() != "workflow unknown not found" {
+		t.Errorf("expected workflow not found, got %v", err)
+	}
+	if revisions, _ := s.ListRevision("unknown"); len(revisions) != 0 {
+		t.Errorf("expected no revision of unknown workflow, got %d", len(revisions))
+	}
+
+	revisions, err := s.ListRevision(wfr.ID)
+	if err != nil || len(revisions) != 2 || revisions[0].Version != 2 {
+		t.Fatalf("expected 2 revisions latest first, got %v, error: %v", revisions, err)
+	}
+
+	diff, err := s.DiffRevision(wfr.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("diff revision failed: %v", err)
+	}
+	if len(diff.AddedNodes) != 1 || len(diff.AddedEdges) != 1 {
+		t.Errorf("expected 1 added node and edge, got %+v", diff)
+	}
+
+	wf, err := s.RollbackWorkflow(wfr.ID, 1)
+	if err != nil {
+		t.Fatalf("rollback workflow failed: %v", err)
+	}
+	if wf.Version != 3 || wf.Title != "v1" {
+		t.Errorf("expected version 3 with title v1, got %d %s", wf.Version, wf.Title)
+	}
+	current, err := s.GetWorkflow(&Workflow{ID: wfr.ID})
+	if err != nil {
+		t.Fatalf("get workflow failed: %v", err)
+	}
+	if len(current.Nodes) != 1 || len(current.Edges) != 0 || current.Version != 3 {
+		t.Errorf("expected workflow rolled back to revision 1, got %d nodes %d edges version %d", len(current.Nodes), len(current.Edges), current.Version)
+	}
+
+	run, err := s.StartRun(wfr.ID, TriggerManual, "tester")
+	if err != nil {
+		t.Fatalf("start run failed: %v", err)
+	}
+	if res := waitRunFinished(t, s, run.ID); res.Revision != 3 {
+		t.Errorf("expected run of revision 3, got %d", res.Revision)
+	}
+
+	if _, err := s.GetRevision(wfr.ID, 0); err == nil {
+		t.Errorf("expected error of revision 0")
+	}
+}
+
+func TestService_BackfillRevisions(t *testing.T) {
+	s := newTestService(t)
+
+	// workflow saved before revisions are recorded
+	wf := &Workflow{Title: "legacy", Owner: "test"}
+	if err := s.wfDB.Insert(wf); err != nil {
+		t.Fatalf("insert workflow failed: %v", err)
+	}
+	for _, id := range []string{"backfill-a", "backfill-b"} {
+		if err := s.nDB.Insert(&Nodes{NodeId: id, WorkflowId: wf.ID, Uses: "ok", Label: id}); err != nil {
+			t.Fatalf("insert node failed: %v", err)
+		}
+	}
+	if err := s.eDB.Insert(&Edges{WorkflowId: wf.ID, Source: "backfill-a", Target: "backfill-b"}); err != nil {
+		t.Fatalf("insert edge failed: %v", err)
+	}
+	run := &Run{WorkflowId: wf.ID, Status: RunStatusSuccess}
+	if err := s.runDB.Insert(run); err != nil {
+		t.Fatalf("insert run failed: %v", err)
+	}
+
+	if err := s.backfillRevisions(); err != nil {
+		t.Fatalf("backfill revisions failed: %v", err)
+	}
+	if err := s.backfillRevisions(); err != nil {
+		t.Fatalf("backfill revisions again failed: %v", err)
+	}
+
+	revisions, err := s.ListRevision(wf.ID)
+	if err != nil || len(revisions) != 1 || revisions[0].Version != 1 {
+		t.Fatalf("expected revision 1, got %v, error: %v", revisions, err)
+	}
+	if res, _ := s.wfDB.Detail(&Workflow{ID: wf.ID}); res.Version != 1 {
+		t.Errorf("expected workflow version 1, got %d", res.Version)
+	}
+	res, err := s.DetailRun(run.ID)
+	if err != nil {
+		t.Fatalf("detail run failed: %v", err)
+	}
+	if res.Revision != 1 || len(res.Edges) != 1 {
+		t.Errorf("expected run bound to revision 1 with 1 edge, got revision %d with %d edges", res.Revision, len(res.Edges))
+	}
+}
@@ -0,0 +1,269 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	database2 "github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
+	"github.com/MR5356/aurora/pkg/util/structutil"
+	"github.com/MR5356/go-workflow"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io"
+	"time"
+)
+
+// prepareRun build DAG of workflow and persist run with its pending node runs
+func (s *Service) prepareRun(ctx context.Context, workflowId, trigger, triggeredBy string, variables Outputs) (*Run, *workflow.Workflow, map[string]*NodeRun, error) {
+	wfr, err := s.GetWorkflow(&Workflow{ID: workflowId})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dag, err := buildDAG(ctx, wfr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	run := &Run{
+		WorkflowId:  wfr.ID,
+		Title:       wfr.Title,
+		Revision:    wfr.Version,
+		Status:      RunStatusRunning,
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Variables:   variables,
+		StartTime:   time.Now(),
+	}
+
+	tx := database2.GetDB().Begin()
+	defer tx.Rollback()
+
+	if err := s.runDB.Insert(run, tx); err != nil {
+		return nil, nil, nil, err
+	}
+
+	nodeRuns := make(map[string]*NodeRun)
+	for _, n := range dag.Nodes {
+		nodeRun := &NodeRun{
+			RunId:  run.ID,
+			NodeId: n.Id,
+			Label:  n.Label,
+			Uses:   n.Uses,
+			Status: workflow.NodeStatusPending,
+		}
+		if err := s.nodeRunDB.Insert(nodeRun, tx); err != nil {
+			return nil, nil, nil, err
+		}
+		nodeRuns[n.Id] = nodeRun
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, nil, nil, err
+	}
+	return run, dag, nodeRuns, nil
+}
+
+// StartRun start a run of workflow in background
+func (s *Service) StartRun(workflowId, trigger, triggeredBy string) (*Run, error) {
+	return s.StartRunWithVariables(workflowId, trigger, triggeredBy, nil)
+}
+
+// StartRunWithVariables start a run of workflow in background, params of nodes reference variables by {{ .Variables.key }}
+func (s *Service) StartRunWithVariables(workflowId, trigger, triggeredBy string, variables map[string]string) (*Run, error) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	run, dag, nodeRuns, err := s.prepareRun(ctx, workflowId, trigger, triggeredBy, variables)
+	if err != nil {
+		cancel(err)
+		return nil, err
+	}
+
+	// run is updated by executeRun, return a copy of it
+	res := *run
+	runningRuns.Store(run.ID, cancel)
+	go s.executeRun(ctx, cancel, run, dag, nodeRuns)
+	return &res, nil
+}
+
+// RunWorkflow run workflow and wait for it to finish, the run is canceled when ctx is done
+func (s *Service) RunWorkflow(ctx context.Context, workflowId, trigger, triggeredBy string) (*Run, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	run, dag, nodeRuns, err := s.prepareRun(ctx, workflowId, trigger, triggeredBy, nil)
+	if err != nil {
+		cancel(err)
+		return nil, err
+	}
+
+	runningRuns.Store(run.ID, cancel)
+	s.executeRun(ctx, cancel, run, dag, nodeRuns)
+	return run, nil
+}
+
+func (s *Service) executeRun(ctx context.Context, cancel context.CancelCauseFunc, run *Run, dag *workflow.Workflow, nodeRuns map[string]*NodeRun) {
+	defer func() {
+		runningRuns.Delete(run.ID)
+		cancel(nil)
+	}()
+
+	r := &runner{
+		dag:      dag,
+		executor: s.nodeExecutor,
+		onChange: func(node *workflow.Node, res *nodeResult) {
+			nodeRun := nodeRuns[node.Id]
+			nodeRun.Status = node.Status
+			if node.Status == workflow.NodeStatusRunning {
+				nodeRun.StartTime = time.Now()
+			} else {
+				nodeRun.EndTime = time.Now()
+			}
+			if res != nil {
+				nodeRun.Logs = res.log
+				nodeRun.Outputs = res.outputs
+				if res.err != nil {
+					nodeRun.Error = res.err.Error()
+				}
+			}
+			if err := s.nodeRunDB.Update(&NodeRun{ID: nodeRun.ID}, structutil.Struct2Map(nodeRun)); err != nil {
+				logrus.Errorf("update node run %s failed, error: %v", nodeRun.ID, err)
+			}
+		},
+		saveArtifact: func(node *workflow.Node, name string, r io.Reader) (*Artifact, error) {
+			return s.saveArtifact(ctx, run.ID, node.Id, name, r)
+		},
+		variables: run.Variables,
+	}
+
+	err := r.run(withRunID(ctx, run.ID))
+	run.EndTime = time.Now()
+	switch {
+	case err == nil:
+		run.Status = RunStatusSuccess
+	case ctx.Err() != nil:
+		run.Status = RunStatusCanceled
+		run.Error = err.Error()
+	default:
+		run.Status = RunStatusFailure
+		run.Error = err.Error()
+	}
+	logrus.Infof("workflow %s run %s finished with %s", run.WorkflowId, run.ID, run.Status)
+
+	if err := s.runDB.Update(&Run{ID: run.ID}, structutil.Struct2Map(run)); err != nil {
+		logrus.Errorf("update workflow run %s failed, error: %v", run.ID, err)
+	}
+
+	finished := *run
+	if err := eventbus.GetEventBus().Publish(TopicRunFinished, &finished); err != nil {
+		logrus.Errorf("publish finished workflow run %s failed, error: %v", run.ID, err)
+	}
+}
+
+// reconcileRuns fail runs left running by the previous server process, runs and approval waits live in memory
+// and never resume, so their running nodes fail and pending nodes are aborted
+func (s *Service) reconcileRuns() error {
+	var ids []string
+	if err := s.runDB.DB.Model(&Run{}).Where(&Run{Status: RunStatusRunning}).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	logrus.Warnf("%d workflow runs are interrupted by server restart", len(ids))
+
+	now := time.Now()
+	tx := database2.GetDB().Begin()
+	defer tx.Rollback()
+	if err := tx.Model(&NodeRun{}).Where("run_id IN ? AND status = ?", ids, workflow.NodeStatusRunning).
+		Updates(map[string]any{"status": workflow.NodeStatusFailure, "error": ErrRunInterrupted.Error(), "end_time": now}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&NodeRun{}).Where("run_id IN ? AND status = ?", ids, workflow.NodeStatusPending).
+		Updates(map[string]any{"status": workflow.NodeStatusAborted, "error": ErrRunInterrupted.Error(), "end_time": now}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&Run{}).Where("id IN ?", ids).
+		Updates(map[string]any{"status": RunStatusFailure, "error": ErrRunInterrupted.Error(), "end_time": now}).Error; err != nil {
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// CancelRun cancel a running workflow run, running nodes are aborted and pending nodes are skipped
+func (s *Service) CancelRun(id string) error {
+	cancel, ok := runningRuns.Load(id)
+	if !ok {
+		return fmt.Errorf("workflow run %s is not running", id)
+	}
+	cancel.(context.CancelCauseFunc)(ErrRunCanceled)
+	return nil
+}
+
+// DeleteRun delete a finished workflow run with its node runs and artifacts
+func (s *Service) DeleteRun(id string) error {
+	if _, ok := runningRuns.Load(id); ok {
+		return fmt.Errorf("workflow run %s is running", id)
+	}
+	// zero value fields are ignored by query conditions
+	if id == "" {
+		return fmt.Errorf("workflow run %s not found", id)
+	}
+	if _, err := s.runDB.Detail(&Run{ID: id}); err != nil {
+		return fmt.Errorf("workflow run %s not found", id)
+	}
+	return s.deleteRuns(context.Background(), []string{id})
+}
+
+// deleteRuns delete runs with their node runs and artifacts, content of artifacts is removed from store
+func (s *Service) deleteRuns(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var artifacts []*Artifact
+	if err := s.artifactDB.DB.Where("run_id IN ?", ids).Find(&artifacts).Error; err != nil {
+		return err
+	}
+
+	err := database2.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("run_id IN ?", ids).Delete(&Artifact{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("run_id IN ?", ids).Delete(&NodeRun{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&Run{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.deleteArtifactContent(ctx, artifacts)
+	return nil
+}
+
+// ListNodeTypes list built-in node types with their params
+func (s *Service) ListNodeTypes() []NodeType {
+	return GetNodeTypes()
+}
+
+// DetailRun detail workflow run with its node runs
+func (s *Service) DetailRun(id string) (*RunDetail, error) {
+	run, err := s.runDB.Detail(&Run{ID: id})
+	if err != nil {
+		return nil, err
+	}
+	var nodeRuns []*NodeRun
+	if err := s.nodeRunDB.DB.Order("start_time").Find(&nodeRuns, &NodeRun{RunId: id}).Error; err != nil {
+		return nil, err
+	}
+	res := &RunDetail{Run: run, Nodes: nodeRuns, Edges: make([]*workflow.Edge, 0)}
+	if revision, err := s.GetRevision(run.WorkflowId, run.Revision); err == nil {
+		res.Edges = revision.DAG.Edges
+	}
+	if res.Artifacts, err = s.artifactDB.List(&Artifact{RunId: id}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PageRun page workflow runs
+func (s *Service) PageRun(num, size int, run *Run) (*database2.Pager[*Run], error) {
+	return s.runDB.Page(run, int64(num), int64(size))
+}
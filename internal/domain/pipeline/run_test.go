@@ -0,0 +1,234 @@
+package pipeline
+
+import (
+	"context"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
+	"github.com/MR5356/go-workflow"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var setupOnce sync.Once
+
+// TestMain installs plugins named by behaviours of funcExecutor, so that test workflows using them are valid
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "aurora-plugins")
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range []string{"ok", "fail", "block", "panic", "echo"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			panic(err)
+		}
+	}
+	config.Current(config.WithDatabase("sqlite", ":memory:")).Server.PluginPath = dir
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestService returns service with in-memory database shared by tests
+func newTestService(t *testing.T) *Service {
+	setupOnce.Do(func() {
+		cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+		database.NewDatabase(cfg)
+		eventbus.NewEventBus(cfg)
+		if err := database.GetDB().AutoMigrate(&Workflow{}, &Nodes{}, &Edges{}, &Run{}, &NodeRun{}, &Revision{}, &Artifact{}); err != nil {
+			t.Fatalf("auto migrate failed: %v", err)
+		}
+	})
+
+	s := GetService()
+	executor := &funcExecutor{}
+	s.nodeExecutor = func(node *workflow.Node) NodeExecutor { return executor }
+	s.SetArtifactStore(NewLocalArtifactStore(t.TempDir()))
+	return s
+}
+
+func waitRunFinished(t *testing.T, s *Service, id string) *RunDetail {
+	for i := 0; i < 100; i++ {
+		res, err := s.DetailRun(id)
+		if err != nil {
+			t.Fatalf("detail run failed: %v", err)
+		}
+		if res.Status != RunStatusRunning {
+			return res
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("run %s not finished", id)
+	return nil
+}
+
+func TestService_RunWorkflow(t *testing.T) {
+	s := newTestService(t)
+
+	wfr := newTestWorkflow(map[string]string{"run-a": "ok", "run-b": "fail", "run-c": "ok"}, [][2]string{{"run-a", "run-b"}, {"run-b", "run-c"}})
+	wfr.ID = ""
+	wfr.Title = "test"
+	wfr.Owner = "test"
+	if err := s.AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+
+	run, err := s.StartRun(wfr.ID, TriggerManual, "tester")
+	if err != nil {
+		t.Fatalf("start run failed: %v", err)
+	}
+
+	res := waitRunFinished(t, s, run.ID)
+	if res.Status != RunStatusFailure || res.TriggeredBy != "tester" {
+		t.Errorf("expected failure run triggered by tester, got %s %s", res.Status, res.TriggeredBy)
+	}
+	want := map[string]string{"run-a": workflow.NodeStatusSuccess, "run-b": workflow.NodeStatusFailure, "run-c": workflow.NodeStatusAborted}
+	if len(res.Nodes) != len(want) {
+		t.Fatalf("expected %d node runs, got %d", len(want), len(res.Nodes))
+	}
+	for _, n := range res.Nodes {
+		if n.Status != want[n.NodeId] {
+			t.Errorf("expected node %s %s, got %s", n.NodeId, want[n.NodeId], n.Status)
+		}
+	}
+
+	page, err := s.PageRun(1, 10, &Run{WorkflowId: wfr.ID})
+	if err != nil || page.Total != 1 {
+		t.Errorf("expected 1 run, got %v, error: %v", page, err)
+	}
+}
+
+func TestService_CancelRun(t *testing.T) {
+	s := newTestService(t)
+
+	wfr := newTestWorkflow(map[string]string{"cancel-a": "block"}, nil)
+	wfr.ID = ""
+	wfr.Title = "test"
+	wfr.Owner = "test"
+	if err := s.AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+
+	run, err := s.StartRun(wfr.ID, TriggerManual, "tester")
+	if err != nil {
+		t.Fatalf("start run failed: %v", err)
+	}
+	if err := s.CancelRun(run.ID); err != nil {
+		t.Fatalf("cancel run failed: %v", err)
+	}
+
+	res := waitRunFinished(t, s, run.ID)
+	if res.Status != RunStatusCanceled {
+		t.Errorf("expected canceled, got %s", res.Status)
+	}
+	if err := s.CancelRun(run.ID); err == nil {
+		t.Errorf("expected error when canceling finished run")
+	}
+}
+
+func TestWorkflowTask(t *testing.T) {
+	s := newTestService(t)
+
+	wfr := newTestWorkflow(map[string]string{"task-a": "ok"}, nil)
+	wfr.ID = ""
+	wfr.Title = "test"
+	wfr.Owner = "test"
+	if err := s.AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+
+	task := &WorkflowTask{}
+	task.SetParams(`{"workflowId":"` + wfr.ID + `"}`)
+	if res := task.Run(context.Background()); res.Error != nil {
+		t.Errorf("expected no error, got %v", res.Error)
+	}
+
+	task = &WorkflowTask{}
+	task.SetParams(`{"workflowId":"not-exist"}`)
+	if res := task.Run(context.Background()); res.Error == nil {
+		t.Errorf("expected error of not exist workflow")
+	}
+}
+
+func TestService_RunVariables(t *testing.T) {
+	s := newTestService(t)
+
+	finished := make(chan *Run, 1)
+	handler := func(run *Run) { finished <- run }
+	if err := eventbus.GetEventBus().Subscribe(TopicRunFinished, handler); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	defer eventbus.GetEventBus().UnSubscribe(TopicRunFinished, handler)
+
+	wfr := newTestWorkflow(map[string]string{"var-a": "echo"}, nil)
+	wfr.Nodes[0].Params = []*workflow.TaskParam{{Key: "message", Value: "{{ .Variables.branch }}"}}
+	wfr.ID = ""
+	wfr.Title = "variables"
+	wfr.Owner = "test"
+	if err := s.AddWorkflow(wfr); err != nil {
+		t.Fatalf("add workflow failed: %v", err)
+	}
+
+	run, err := s.StartRunWithVariables(wfr.ID, TriggerGit, "", map[string]string{"branch": "main"})
+	if err != nil {
+		t.Fatalf("start run failed: %v", err)
+	}
+	select {
+	case res := <-finished:
+		if res.ID != run.ID || res.Status != RunStatusSuccess || res.Variables["branch"] != "main" {
+			t.Errorf("unexpected finished run %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("run not finished")
+	}
+
+	detail := waitRunFinished(t, s, run.ID)
+	if len(detail.Nodes) != 1 || detail.Nodes[0].Outputs["message"] != "main" {
+		t.Errorf("expected variable rendered in params, got %+v", detail.Nodes)
+	}
+}
+
+func TestService_ReconcileRuns(t *testing.T) {
+	s := newTestService(t)
+
+	interrupted := &Run{WorkflowId: "reconcile", Status: RunStatusRunning, StartTime: time.Now()}
+	finished := &Run{WorkflowId: "reconcile", Status: RunStatusSuccess, StartTime: time.Now()}
+	for _, run := range []*Run{interrupted, finished} {
+		if err := s.runDB.Insert(run); err != nil {
+			t.Fatalf("insert run failed: %v", err)
+		}
+	}
+	nodeRuns := []*NodeRun{
+		{RunId: interrupted.ID, NodeId: "reconcile-a", Status: workflow.NodeStatusSuccess},
+		{RunId: interrupted.ID, NodeId: "reconcile-b", Status: workflow.NodeStatusRunning},
+		{RunId: interrupted.ID, NodeId: "reconcile-c", Status: workflow.NodeStatusPending},
+		{RunId: finished.ID, NodeId: "reconcile-d", Status: workflow.NodeStatusSuccess},
+	}
+	for _, n := range nodeRuns {
+		if err := s.nodeRunDB.Insert(n); err != nil {
+			t.Fatalf("insert node run failed: %v", err)
+		}
+	}
+
+	if err := s.reconcileRuns(); err != nil {
+		t.Fatalf("reconcile runs failed: %v", err)
+	}
+
+	res, _ := s.DetailRun(interrupted.ID)
+	if res.Status != RunStatusFailure || res.Error != ErrRunInterrupted.Error() || res.EndTime.IsZero() {
+		t.Errorf("expected interrupted run failed, got %s %q", res.Status, res.Error)
+	}
+	want := map[string]string{"reconcile-a": workflow.NodeStatusSuccess, "reconcile-b": workflow.NodeStatusFailure, "reconcile-c": workflow.NodeStatusAborted}
+	for _, n := range res.Nodes {
+		if n.Status != want[n.NodeId] {
+			t.Errorf("expected node %s %s, got %s", n.NodeId, want[n.NodeId], n.Status)
+		}
+	}
+	if res, _ := s.DetailRun(finished.ID); res.Status != RunStatusSuccess || res.Nodes[0].Status != workflow.NodeStatusSuccess {
+		t.Errorf("expected finished run unchanged, got %s", res.Status)
+	}
+}
@@ -0,0 +1,313 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/go-workflow"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	RunStatusRunning  = "running"
+	RunStatusSuccess  = "success"
+	RunStatusFailure  = "failure"
+	RunStatusCanceled = "canceled"
+
+	TriggerManual   = "manual"
+	TriggerSchedule = "schedule"
+	TriggerGit      = "git"
+
+	// TopicRunFinished is published with *Run when a workflow run finished
+	TopicRunFinished = "topic.pipeline.run_finished"
+
+	// maxNodeLogLength max length of node logs stored in node run
+	maxNodeLogLength = 64 * 1024
+)
+
+var (
+	ErrRunCanceled    = errors.New("workflow run canceled")
+	ErrRunInterrupted = errors.New("workflow run interrupted by server restart")
+
+	// runningRuns run id -> context.CancelCauseFunc
+	runningRuns = sync.Map{}
+)
+
+// NodeExecutor executes a single node of workflow, logs of the node are written to log
+type NodeExecutor interface {
+	Execute(ctx context.Context, node *workflow.Node, log io.Writer) error
+}
+
+// pluginNamePattern names of plugins, a plugin is an executable file of the name in plugin path
+var pluginNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// resolvePlugin returns path of the installed plugin named uses, error if it is not an executable in plugin path
+func resolvePlugin(uses string) (string, error) {
+	if !pluginNamePattern.MatchString(uses) {
+		return "", fmt.Errorf("invalid plugin name %q", uses)
+	}
+	dir, err := filepath.Abs(config.Current().Server.PluginPath)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, uses)
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+		return "", fmt.Errorf("plugin %s is not installed", uses)
+	}
+	return path, nil
+}
+
+// pluginExecutor runs node as go-workflow plugin, Uses of node is the name of an installed plugin
+type pluginExecutor struct{}
+
+func (pluginExecutor) Execute(ctx context.Context, node *workflow.Node, log io.Writer) error {
+	path, err := resolvePlugin(node.Uses)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(log, "start plugin: %s\n", node.Uses)
+
+	// go-workflow starts plugin with sh -c, so the resolved path is quoted
+	plugin := &workflow.Node{Id: node.Id, Label: node.Label, Uses: "'" + strings.ReplaceAll(path, "'", `'\''`) + "'", Params: node.Params}
+	task, err := plugin.Dispense()
+	if err != nil {
+		return fmt.Errorf("dispense plugin failed: %v", err)
+	}
+	defer task.Close()
+
+	if err := task.SetParams(&workflow.TaskParams{Params: node.Params}); err != nil {
+		return fmt.Errorf("set params failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- task.Run()
+	}()
+
+	select {
+	case <-ctx.Done():
+		// plugin process is killed by task.Close
+		return context.Cause(ctx)
+	case err := <-done:
+		return err
+	}
+}
+
+// buildDAG turns stored workflow into go-workflow DAG, nodes of DAG are copies of stored nodes
+func buildDAG(ctx context.Context, wfr *WorkflowRequest) (*workflow.Workflow, error) {
+	if wfr.WorkflowDAG == nil {
+		return nil, fmt.Errorf("workflow %s has no nodes", wfr.ID)
+	}
+	if err := ValidateWorkflow(wfr.WorkflowDAG); err != nil {
+		return nil, err
+	}
+
+	dag := workflow.NewWorkflow(ctx, nil)
+	nodes := make(map[string]*workflow.Node)
+	for _, n := range wfr.Nodes {
+		node := &workflow.Node{Id: n.Id, Label: n.Label, Uses: n.Uses, Params: n.Params}
+		nodes[n.Id] = node
+		dag.AddNode(node)
+	}
+	for _, e := range wfr.Edges {
+		dag.AddEdge(nodes[e.Source], nodes[e.Target])
+	}
+	return dag, nil
+}
+
+type nodeResult struct {
+	node      *workflow.Node
+	err       error
+	log       string
+	outputs   Outputs
+	artifacts []*Artifact
+}
+
+// runner executes nodes of a DAG in dependency order, independent nodes run concurrently.
+// If a node fails, its downstream nodes are aborted, other branches keep running
+type runner struct {
+	dag      *workflow.Workflow
+	executor func(node *workflow.Node) NodeExecutor
+	// onChange is called in the goroutine of run when status of a node changes
+	onChange func(node *workflow.Node, res *nodeResult)
+	// saveArtifact stores artifact output by node, artifacts are dropped if it is nil
+	saveArtifact func(node *workflow.Node, name string, r io.Reader) (*Artifact, error)
+	// variables of run, referenced by {{ .Variables.key }} in params
+	variables Outputs
+}
+
+func (r *runner) run(ctx context.Context) error {
+	upstreams := make(map[string]int)
+	downstreams := make(map[string][]*workflow.Node)
+	nodes := make(map[string]*workflow.Node)
+	for _, n := range r.dag.Nodes {
+		nodes[n.Id] = n
+	}
+	for _, e := range r.dag.Edges {
+		upstreams[e.Target]++
+		downstreams[e.Source] = append(downstreams[e.Source], nodes[e.Target])
+	}
+
+	// outputs and artifacts of succeeded nodes, they are referenced by params of downstream nodes
+	outputs := make(map[string]Outputs)
+	artifacts := make(map[string][]*Artifact)
+	data := &templateData{RunId: runIDFromContext(ctx), Variables: r.variables}
+
+	results := make(chan *nodeResult)
+	running := 0
+	start := func(node *workflow.Node) {
+		node.Status = workflow.NodeStatusRunning
+		r.onChange(node, nil)
+		running++
+
+		rendered, err := renderParams(node, data, ancestors(r.dag.Edges, node.Id), outputs, artifacts)
+		go func() {
+			if err != nil {
+				results <- &nodeResult{node: node, err: err}
+				return
+			}
+			results <- r.execute(ctx, node, rendered)
+		}()
+	}
+
+	// abort marks pending node and its pending downstream nodes as aborted
+	var abort func(node *workflow.Node, reason error)
+	abort = func(node *workflow.Node, reason error) {
+		if node.Status != workflow.NodeStatusPending {
+			return
+		}
+		node.Status = workflow.NodeStatusAborted
+		r.onChange(node, &nodeResult{node: node, err: reason})
+		for _, d := range downstreams[node.Id] {
+			abort(d, fmt.Errorf("upstream node %s is not successful", node.Id))
+		}
+	}
+
+	for _, n := range r.dag.Nodes {
+		if upstreams[n.Id] == 0 && ctx.Err() == nil {
+			start(n)
+		}
+	}
+
+	failed := false
+	for running > 0 {
+		res := <-results
+		running--
+
+		switch {
+		case res.err == nil:
+			res.node.Status = workflow.NodeStatusSuccess
+			outputs[res.node.Id] = res.outputs
+			artifacts[res.node.Id] = res.artifacts
+		case ctx.Err() != nil:
+			res.node.Status = workflow.NodeStatusAborted
+		default:
+			res.node.Status = workflow.NodeStatusFailure
+			failed = true
+		}
+		r.onChange(res.node, res)
+
+		for _, d := range downstreams[res.node.Id] {
+			if res.node.Status != workflow.NodeStatusSuccess {
+				abort(d, fmt.Errorf("upstream node %s is not successful", res.node.Id))
+				continue
+			}
+			upstreams[d.Id]--
+			if upstreams[d.Id] == 0 && d.Status == workflow.NodeStatusPending && ctx.Err() == nil {
+				start(d)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		cause := context.Cause(ctx)
+		for _, n := range r.dag.Nodes {
+			abort(n, cause)
+		}
+		return cause
+	}
+	if failed {
+		return errors.New("some nodes of workflow failed")
+	}
+	return nil
+}
+
+// execute runs node with rendered params, it recovers panic and collects logs and outputs of node
+func (r *runner) execute(ctx context.Context, node, rendered *workflow.Node) (res *nodeResult) {
+	log := new(syncBuffer)
+	var save func(name string, r io.Reader) (*Artifact, error)
+	if r.saveArtifact != nil {
+		save = func(name string, reader io.Reader) (*Artifact, error) {
+			return r.saveArtifact(node, name, reader)
+		}
+	}
+	outputs := newNodeOutputs(save)
+
+	res = &nodeResult{node: node}
+	defer func() {
+		if e := recover(); e != nil {
+			logrus.Errorf("workflow node %s panic: %v", node.Id, e)
+			res.err = fmt.Errorf("node panic: %v", e)
+		}
+		res.log = truncateLog(log.String())
+		res.outputs = outputs.Values()
+		res.artifacts = outputs.Artifacts()
+	}()
+
+	start := time.Now()
+	res.err = r.executor(rendered).Execute(withNodeOutputs(ctx, outputs), rendered, log)
+	fmt.Fprintf(log, "finished in %s\n", time.Since(start).Round(time.Millisecond))
+	return res
+}
+
+// ancestors returns ids of all upstream nodes of node
+func ancestors(edges []*workflow.Edge, id string) map[string]bool {
+	res := make(map[string]bool)
+	var walk func(id string)
+	walk = func(id string) {
+		for _, e := range edges {
+			if e.Target == id && !res[e.Source] {
+				res[e.Source] = true
+				walk(e.Source)
+			}
+		}
+	}
+	walk(id)
+	return res
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes of node executor
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// truncateLog keeps the tail of log, which usually contains the error
+func truncateLog(log string) string {
+	if len(log) <= maxNodeLogLength {
+		return log
+	}
+	return "...(truncated)\n" + log[len(log)-maxNodeLogLength:]
+}
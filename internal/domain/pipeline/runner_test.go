@@ -0,0 +1,209 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MR5356/go-workflow"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// funcExecutor executes node by Uses of node: "ok", "fail", "block", "panic" or "echo",
+// "echo" writes param message to log and outputs it as value and artifact
+type funcExecutor struct {
+	mu       sync.Mutex
+	executed []string
+}
+
+func (e *funcExecutor) Execute(ctx context.Context, node *workflow.Node, log io.Writer) error {
+	e.mu.Lock()
+	e.executed = append(e.executed, node.Id)
+	e.mu.Unlock()
+
+	fmt.Fprintf(log, "run %s\n", node.Id)
+	switch node.Uses {
+	case "fail":
+		return errors.New("node failed")
+	case "block":
+		<-ctx.Done()
+		return context.Cause(ctx)
+	case "panic":
+		panic("node panic")
+	case "echo":
+		message := newNodeParams(node).Get("message")
+		fmt.Fprintln(log, message)
+		outputs := OutputsFromContext(ctx)
+		outputs.Set("message", message)
+		return outputs.AddArtifact("message.txt", strings.NewReader(message))
+	default:
+		return nil
+	}
+}
+
+func newTestWorkflow(nodes map[string]string, edges [][2]string) *WorkflowRequest {
+	wfr := &WorkflowRequest{Workflow: &Workflow{ID: "test"}, WorkflowDAG: &workflow.WorkflowDAG{}}
+	for id, uses := range nodes {
+		wfr.Nodes = append(wfr.Nodes, &workflow.Node{Id: id, Label: id, Uses: uses})
+	}
+	for _, e := range edges {
+		wfr.Edges = append(wfr.Edges, &workflow.Edge{Source: e[0], Target: e[1]})
+	}
+	return wfr
+}
+
+func TestBuildDAG(t *testing.T) {
+	tests := []struct {
+		name    string
+		wfr     *WorkflowRequest
+		wantErr bool
+	}{
+		{name: "valid", wfr: newTestWorkflow(map[string]string{"a": "ok", "b": "ok"}, [][2]string{{"a", "b"}})},
+		{name: "empty", wfr: &WorkflowRequest{Workflow: &Workflow{ID: "test"}}, wantErr: true},
+		{name: "dangling source", wfr: newTestWorkflow(map[string]string{"a": "ok"}, [][2]string{{"x", "a"}}), wantErr: true},
+		{name: "dangling target", wfr: newTestWorkflow(map[string]string{"a": "ok"}, [][2]string{{"a", "x"}}), wantErr: true},
+		{name: "cycle", wfr: newTestWorkflow(map[string]string{"a": "ok", "b": "ok", "c": "ok"}, [][2]string{{"a", "b"}, {"b", "c"}, {"c", "a"}}), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := buildDAG(context.Background(), tt.wfr); (err != nil) != tt.wantErr {
+				t.Errorf("buildDAG() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func runTestWorkflow(t *testing.T, ctx context.Context, wfr *WorkflowRequest) (map[string]string, error) {
+	dag, err := buildDAG(ctx, wfr)
+	if err != nil {
+		t.Fatalf("build dag failed: %v", err)
+	}
+
+	executor := &funcExecutor{}
+	status := make(map[string]string)
+	r := &runner{
+		dag:      dag,
+		executor: func(node *workflow.Node) NodeExecutor { return executor },
+		onChange: func(node *workflow.Node, res *nodeResult) { status[node.Id] = node.Status },
+	}
+	err = r.run(ctx)
+	return status, err
+}
+
+func TestRunner(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   map[string]string
+		edges   [][2]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "success",
+			nodes: map[string]string{"a": "ok", "b": "ok", "c": "ok", "d": "ok"},
+			edges: [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}},
+			want:  map[string]string{"a": "success", "b": "success", "c": "success", "d": "success"},
+		},
+		{
+			name:    "failure aborts downstream only",
+			nodes:   map[string]string{"a": "ok", "b": "fail", "c": "ok", "d": "ok", "e": "ok"},
+			edges:   [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}, {"c", "d"}, {"c", "e"}},
+			want:    map[string]string{"a": "success", "b": "failure", "c": "success", "d": "aborted", "e": "success"},
+			wantErr: true,
+		},
+		{
+			name:    "panic",
+			nodes:   map[string]string{"a": "panic", "b": "ok"},
+			edges:   [][2]string{{"a", "b"}},
+			want:    map[string]string{"a": "failure", "b": "aborted"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := runTestWorkflow(t, context.Background(), newTestWorkflow(tt.nodes, tt.edges))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for id, want := range tt.want {
+				if status[id] != want {
+					t.Errorf("expected node %s %s, got %s", id, want, status[id])
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerOutputs(t *testing.T) {
+	wfr := newTestWorkflow(map[string]string{"a": "echo", "b": "echo", "c": "echo"}, [][2]string{{"a", "b"}})
+	for _, n := range wfr.Nodes {
+		n.Params = []*workflow.TaskParam{{Key: "message", Value: "from " + n.Id}}
+	}
+	for _, n := range wfr.Nodes {
+		switch n.Id {
+		case "b":
+			n.Params[0].Value = `{{ output "a" "message" }} to b, artifact {{ artifact "a" "message.txt" }}`
+		case "c":
+			n.Params[0].Value = `{{ output "a" "message" }}`
+		}
+	}
+
+	dag, err := buildDAG(context.Background(), wfr)
+	if err != nil {
+		t.Fatalf("build dag failed: %v", err)
+	}
+	results := make(map[string]*nodeResult)
+	r := &runner{
+		dag:      dag,
+		executor: func(node *workflow.Node) NodeExecutor { return &funcExecutor{} },
+		onChange: func(node *workflow.Node, res *nodeResult) {
+			if res != nil {
+				results[node.Id] = res
+			}
+		},
+		saveArtifact: func(node *workflow.Node, name string, r io.Reader) (*Artifact, error) {
+			return &Artifact{ID: node.Id + "-" + name, NodeId: node.Id, Name: name}, nil
+		},
+	}
+	if err := r.run(context.Background()); err == nil {
+		t.Fatalf("expected error of node c which references node a not upstream")
+	}
+
+	if got := results["b"].outputs["message"]; got != "from a to b, artifact a-message.txt" {
+		t.Errorf("unexpected output of node b: %s", got)
+	}
+	if len(results["a"].artifacts) != 1 {
+		t.Errorf("expected 1 artifact of node a, got %d", len(results["a"].artifacts))
+	}
+	if results["c"].err == nil || !strings.Contains(results["c"].err.Error(), "not upstream") {
+		t.Errorf("expected node c failed as node a is not upstream, got %v", results["c"].err)
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	time.AfterFunc(50*time.Millisecond, func() { cancel(ErrRunCanceled) })
+
+	status, err := runTestWorkflow(t, ctx, newTestWorkflow(map[string]string{"a": "block", "b": "ok"}, [][2]string{{"a", "b"}}))
+	if !errors.Is(err, ErrRunCanceled) {
+		t.Errorf("expected %v, got %v", ErrRunCanceled, err)
+	}
+	if status["a"] != workflow.NodeStatusAborted || status["b"] != workflow.NodeStatusAborted {
+		t.Errorf("expected all nodes aborted, got %v", status)
+	}
+}
+
+func TestTruncateLog(t *testing.T) {
+	log := make([]byte, maxNodeLogLength+10)
+	if got := truncateLog(string(log)); len(got) > maxNodeLogLength+len("...(truncated)\n") {
+		t.Errorf("expected log truncated, got length %d", len(got))
+	}
+	if got := truncateLog("test"); got != "test" {
+		t.Errorf("expected test, got %s", got)
+	}
+}
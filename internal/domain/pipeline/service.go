@@ -5,11 +5,16 @@ TODO 需要改造为分布式，不应该将整个workflow跑在单副本
 package pipeline
 
 import (
+	"context"
+	"fmt"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/schedule"
 	database2 "github.com/MR5356/aurora/internal/infrastructure/database"
 	"github.com/MR5356/aurora/pkg/util/structutil"
 	"github.com/MR5356/aurora/pkg/util/validate"
 	"github.com/MR5356/go-workflow"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	"sync"
 )
 
@@ -22,6 +27,17 @@ type Service struct {
 	wfDB *database2.BaseMapper[*Workflow]
 	nDB  *database2.BaseMapper[*Nodes]
 	eDB  *database2.BaseMapper[*Edges]
+
+	runDB     *database2.BaseMapper[*Run]
+	nodeRunDB *database2.BaseMapper[*NodeRun]
+
+	revisionDB *database2.BaseMapper[*Revision]
+
+	artifactDB    *database2.BaseMapper[*Artifact]
+	artifactStore ArtifactStore
+
+	// nodeExecutor returns the executor of workflow node
+	nodeExecutor func(node *workflow.Node) NodeExecutor
 }
 
 func GetService() *Service {
@@ -30,6 +46,16 @@ func GetService() *Service {
 			wfDB: database2.NewMapper(database2.GetDB(), &Workflow{}),
 			nDB:  database2.NewMapper(database2.GetDB(), &Nodes{}),
 			eDB:  database2.NewMapper(database2.GetDB(), &Edges{}),
+
+			runDB:     database2.NewMapper(database2.GetDB(), &Run{}),
+			nodeRunDB: database2.NewMapper(database2.GetDB(), &NodeRun{}),
+
+			revisionDB: database2.NewMapper(database2.GetDB(), &Revision{}),
+
+			artifactDB:    database2.NewMapper(database2.GetDB(), &Artifact{}),
+			artifactStore: NewLocalArtifactStore(config.Current().Server.ArtifactPath),
+
+			nodeExecutor: builtinNodeExecutor,
 		}
 	})
 	return service
@@ -40,6 +66,12 @@ func (s *Service) AddWorkflow(wf *WorkflowRequest) error {
 		logrus.Errorf("validate workflow failed, error: %v", err)
 		return err
 	}
+	if err := ValidateWorkflow(wf.WorkflowDAG); err != nil {
+		return err
+	}
+	if err := s.checkWorkflowKey(wf.Workflow); err != nil {
+		return err
+	}
 
 	tx := database2.GetDB().Begin()
 	defer tx.Rollback()
@@ -49,9 +81,18 @@ func (s *Service) AddWorkflow(wf *WorkflowRequest) error {
 		return err
 	}
 
+	if err := s.addRevision(tx, wf); err != nil {
+		logrus.Errorf("add workflow revision failed, error: %v", err)
+		return err
+	}
+	if err := s.wfDB.Update(&Workflow{ID: wf.Workflow.ID}, map[string]any{"Version": wf.Workflow.Version}, tx); err != nil {
+		logrus.Errorf("update workflow version failed, error: %v", err)
+		return err
+	}
+
 	for _, n := range wf.Nodes {
 		node := &Nodes{
-			ID:         n.Id,
+			NodeId:     n.Id,
 			WorkflowId: wf.Workflow.ID,
 			Uses:       n.Uses,
 			Label:      n.Label,
@@ -85,11 +126,30 @@ func (s *Service) UpdateWorkflow(wf *WorkflowRequest) error {
 		logrus.Errorf("validate workflow failed, error: %v", err)
 		return err
 	}
+	if err := ValidateWorkflow(wf.WorkflowDAG); err != nil {
+		return err
+	}
+	if err := s.checkWorkflowKey(wf.Workflow); err != nil {
+		return err
+	}
+	if _, err := s.wfDB.Detail(&Workflow{ID: wf.Workflow.ID}); wf.Workflow.ID == "" || err != nil {
+		return fmt.Errorf("workflow %s not found", wf.Workflow.ID)
+	}
 
 	tx := database2.GetDB().Begin()
 	defer tx.Rollback()
 
-	if err := s.wfDB.Update(&Workflow{ID: wf.Workflow.ID}, structutil.Struct2Map(wf.Workflow), tx); err != nil {
+	if err := s.addRevision(tx, wf); err != nil {
+		logrus.Errorf("add workflow revision failed, error: %v", err)
+		return err
+	}
+
+	fields := structutil.Struct2Map(wf.Workflow)
+	// key is stable once set, request without key keeps it
+	if wf.Key == "" {
+		delete(fields, "Key")
+	}
+	if err := s.wfDB.Update(&Workflow{ID: wf.Workflow.ID}, fields, tx); err != nil {
 		logrus.Errorf("update workflow failed, error: %v", err)
 		return err
 	}
@@ -100,7 +160,7 @@ func (s *Service) UpdateWorkflow(wf *WorkflowRequest) error {
 	}
 	for _, n := range wf.Nodes {
 		node := &Nodes{
-			ID:         n.Id,
+			NodeId:     n.Id,
 			WorkflowId: wf.Workflow.ID,
 			Uses:       n.Uses,
 			Label:      n.Label,
@@ -131,6 +191,25 @@ func (s *Service) UpdateWorkflow(wf *WorkflowRequest) error {
 	return nil
 }
 
+// checkWorkflowKey returns error if key of wf is used by another workflow
+func (s *Service) checkWorkflowKey(wf *Workflow) error {
+	if wf.Key == "" {
+		return nil
+	}
+	query := s.wfDB.DB.Model(&Workflow{}).Where(&Workflow{Key: wf.Key})
+	if wf.ID != "" {
+		query = query.Not(&Workflow{ID: wf.ID})
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("workflow key %s is already used", wf.Key)
+	}
+	return nil
+}
+
 func (s *Service) DeleteWorkflow(wf *Workflow) error {
 	tx := database2.GetDB().Begin()
 	defer tx.Rollback()
@@ -145,11 +224,26 @@ func (s *Service) DeleteWorkflow(wf *Workflow) error {
 		return err
 	}
 
+	if err := tx.Where("workflow_id = ?", wf.ID).Delete(&Revision{}).Error; err != nil {
+		logrus.Errorf("delete workflow revision failed, error: %v", err)
+		return err
+	}
+
 	if err := s.wfDB.Delete(&Workflow{ID: wf.ID}, tx); err != nil {
 		logrus.Errorf("delete workflow failed, error: %v", err)
 		return err
 	}
 	tx.Commit()
+
+	// runs still running are kept, they are finished by their own
+	var runIds []string
+	if err := s.runDB.DB.Model(&Run{}).Where("workflow_id = ? AND status <> ?", wf.ID, RunStatusRunning).Pluck("id", &runIds).Error; err != nil {
+		return err
+	}
+	if err := s.deleteRuns(context.Background(), runIds); err != nil {
+		logrus.Errorf("delete runs of workflow %s failed, error: %v", wf.ID, err)
+		return err
+	}
 	return nil
 }
 
@@ -165,7 +259,7 @@ func (s *Service) GetWorkflow(wf *Workflow) (*WorkflowRequest, error) {
 	node := make([]*workflow.Node, 0)
 	for _, n := range nodes {
 		node = append(node, &workflow.Node{
-			Id:     n.ID,
+			Id:     n.NodeId,
 			Label:  n.Label,
 			Uses:   n.Uses,
 			Params: n.Params,
@@ -191,6 +285,8 @@ func (s *Service) GetWorkflow(wf *Workflow) (*WorkflowRequest, error) {
 	wfr.ID = wf.ID
 	wfr.Title = wf.Title
 	wfr.Owner = wf.Owner
+	wfr.Key = wf.Key
+	wfr.Version = wf.Version
 	wfr.CreatedAt = wf.CreatedAt
 	wfr.UpdatedAt = wf.UpdatedAt
 	wfr.Edges = edge
@@ -215,7 +311,34 @@ func (s *Service) ListWorkflow(wf *Workflow) ([]*WorkflowRequest, error) {
 }
 
 func (s *Service) Initialize() error {
-	if err := database2.GetDB().AutoMigrate(&Workflow{}, &Nodes{}, &Edges{}); err != nil {
+	if err := database2.GetDB().AutoMigrate(&Workflow{}, &Nodes{}, &Edges{}, &Run{}, &NodeRun{}, &Revision{}, &Artifact{}); err != nil {
+		return err
+	}
+
+	// node id was the primary key of node rows before rows are scoped by workflow
+	if err := database2.GetDB().Model(&Nodes{}).Where("node_id = ? OR node_id IS NULL", "").Update("node_id", gorm.Expr("id")).Error; err != nil {
+		return err
+	}
+
+	if err := s.reconcileRuns(); err != nil {
+		return err
+	}
+
+	if err := s.backfillRevisions(); err != nil {
+		return err
+	}
+
+	if err := registerBuiltinNodeTypes(); err != nil {
+		return err
+	}
+
+	if err := schedule.GetExecutorManager().Register(schedule.Executor{
+		Name:         "pipeline",
+		DisplayName:  "pipeline executor",
+		ParamsSchema: runWorkflowParamsSchema,
+	}, func() schedule.Task {
+		return &WorkflowTask{}
+	}); err != nil {
 		return err
 	}
 	return nil
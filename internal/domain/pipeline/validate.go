@@ -0,0 +1,182 @@
+package pipeline
+
+import (
+	"fmt"
+	"github.com/MR5356/go-workflow"
+	"strings"
+)
+
+const (
+	ProblemDuplicateNode = "duplicate_node"
+	ProblemDanglingEdge  = "dangling_edge"
+	ProblemCycle         = "cycle"
+	ProblemUnknownUses   = "unknown_uses"
+	ProblemMissingParam  = "missing_param"
+
+	// builtinPrefix prefix of built-in node types, Uses without it is the name of an installed plugin
+	builtinPrefix = "builtin/"
+)
+
+// Problem is a problem of workflow tied to a node or an edge
+type Problem struct {
+	Code    string `json:"code" example:"dangling_edge"`
+	NodeId  string `json:"nodeId,omitempty"`
+	EdgeId  string `json:"edgeId,omitempty" example:"source->target"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when workflow has problems
+type ValidationError struct {
+	Problems []*Problem `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		msgs = append(msgs, p.Message)
+	}
+	return "invalid workflow: " + strings.Join(msgs, "; ")
+}
+
+func edgeId(e *workflow.Edge) string {
+	return e.Source + "->" + e.Target
+}
+
+// ValidateWorkflow check cycles, dangling edges, unknown uses and missing required params of workflow
+func ValidateWorkflow(dag *workflow.WorkflowDAG) error {
+	if dag == nil {
+		return nil
+	}
+
+	problems := make([]*Problem, 0)
+	nodes := make(map[string]*workflow.Node)
+	for _, n := range dag.Nodes {
+		if _, ok := nodes[n.Id]; ok {
+			problems = append(problems, &Problem{Code: ProblemDuplicateNode, NodeId: n.Id, Message: fmt.Sprintf("node %s is duplicated", n.Id)})
+			continue
+		}
+		nodes[n.Id] = n
+		problems = append(problems, validateNode(n)...)
+	}
+
+	graph := make(map[string][]string)
+	for _, e := range dag.Edges {
+		dangling := false
+		if _, ok := nodes[e.Source]; !ok {
+			problems = append(problems, &Problem{Code: ProblemDanglingEdge, EdgeId: edgeId(e), Message: fmt.Sprintf("source node %s of edge not found", e.Source)})
+			dangling = true
+		}
+		if _, ok := nodes[e.Target]; !ok {
+			problems = append(problems, &Problem{Code: ProblemDanglingEdge, EdgeId: edgeId(e), Message: fmt.Sprintf("target node %s of edge not found", e.Target)})
+			dangling = true
+		}
+		if !dangling {
+			graph[e.Source] = append(graph[e.Source], e.Target)
+		}
+	}
+
+	for _, cycle := range findCycles(dag.Nodes, graph) {
+		chain := strings.Join(append(cycle, cycle[0]), " -> ")
+		for _, id := range cycle {
+			problems = append(problems, &Problem{Code: ProblemCycle, NodeId: id, Message: fmt.Sprintf("node %s is in cycle %s", id, chain)})
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateNode check uses and required params of node
+func validateNode(n *workflow.Node) []*Problem {
+	uses := strings.TrimSpace(n.Uses)
+	if uses == "" {
+		return []*Problem{{Code: ProblemUnknownUses, NodeId: n.Id, Message: fmt.Sprintf("uses of node %s is empty", n.Id)}}
+	}
+
+	nodeType, ok := getNodeType(uses)
+	if !ok {
+		if strings.HasPrefix(uses, builtinPrefix) {
+			return []*Problem{{Code: ProblemUnknownUses, NodeId: n.Id, Message: fmt.Sprintf("node type %s of node %s not found", uses, n.Id)}}
+		}
+		if _, err := resolvePlugin(uses); err != nil {
+			return []*Problem{{Code: ProblemUnknownUses, NodeId: n.Id, Message: fmt.Sprintf("uses of node %s: %v", n.Id, err)}}
+		}
+		// installed plugin, its params are unknown until it runs
+		return nil
+	}
+
+	problems := make([]*Problem, 0)
+	params := newNodeParams(n)
+	for _, p := range nodeType.Params {
+		if !p.Required {
+			continue
+		}
+		if _, err := params.Required(p.Key); err != nil {
+			problems = append(problems, &Problem{Code: ProblemMissingParam, NodeId: n.Id, Param: p.Key, Message: fmt.Sprintf("param %s of node %s is required", p.Key, n.Id)})
+		}
+	}
+	return problems
+}
+
+// findCycles returns strongly connected components which contain a cycle, ordered by appearance of nodes
+func findCycles(nodes []*workflow.Node, graph map[string][]string) [][]string {
+	index := 0
+	indexes := make(map[string]int)
+	lowLinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	stack := make([]string, 0)
+	res := make([][]string, 0)
+
+	var connect func(id string)
+	connect = func(id string) {
+		indexes[id] = index
+		lowLinks[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		selfLoop := false
+		for _, next := range graph[id] {
+			if next == id {
+				selfLoop = true
+			}
+			if _, ok := indexes[next]; !ok {
+				connect(next)
+				lowLinks[id] = min(lowLinks[id], lowLinks[next])
+			} else if onStack[next] {
+				lowLinks[id] = min(lowLinks[id], indexes[next])
+			}
+		}
+
+		if lowLinks[id] != indexes[id] {
+			return
+		}
+		component := make([]string, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			// reverse to the order of traversal
+			for i, j := 0, len(component)-1; i < j; i, j = i+1, j-1 {
+				component[i], component[j] = component[j], component[i]
+			}
+			res = append(res, component)
+		}
+	}
+
+	for _, n := range nodes {
+		if _, ok := indexes[n.Id]; !ok {
+			connect(n.Id)
+		}
+	}
+	return res
+}
@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"errors"
+	"github.com/MR5356/go-workflow"
+	"testing"
+)
+
+func TestValidateWorkflow(t *testing.T) {
+	if err := RegisterNodeType(NodeType{
+		Name:   builtinPrefix + "validate-test",
+		Params: []*workflow.TaskParam{{Key: "url", Required: true}, {Key: "body"}},
+	}, httpExecutor{}); err != nil {
+		t.Fatalf("register node type failed: %v", err)
+	}
+	defer nodeTypes.Delete(builtinPrefix + "validate-test")
+
+	node := func(id, uses string, params ...string) *workflow.Node {
+		n := &workflow.Node{Id: id, Uses: uses}
+		for i := 0; i+1 < len(params); i += 2 {
+			n.Params = append(n.Params, &workflow.TaskParam{Key: params[i], Value: params[i+1]})
+		}
+		return n
+	}
+	edge := func(source, target string) *workflow.Edge {
+		return &workflow.Edge{Source: source, Target: target}
+	}
+
+	tests := []struct {
+		name  string
+		dag   *workflow.WorkflowDAG
+		want  []Problem
+		valid bool
+	}{
+		{
+			name:  "valid",
+			dag:   &workflow.WorkflowDAG{Nodes: []*workflow.Node{node("a", "ok"), node("b", builtinPrefix+"validate-test", "url", "http://localhost")}, Edges: []*workflow.Edge{edge("a", "b")}},
+			valid: true,
+		},
+		{
+			name:  "nil",
+			valid: true,
+		},
+		{
+			name: "dangling edge",
+			dag:  &workflow.WorkflowDAG{Nodes: []*workflow.Node{node("a", "ok")}, Edges: []*workflow.Edge{edge("a", "x"), edge("y", "a")}},
+			want: []Problem{{Code: ProblemDanglingEdge, EdgeId: "a->x"}, {Code: ProblemDanglingEdge, EdgeId: "y->a"}},
+		},
+		{
+			name: "cycle",
+			dag:  &workflow.WorkflowDAG{Nodes: []*workflow.Node{node("a", "ok"), node("b", "ok"), node("c", "ok"), node("d", "ok")}, Edges: []*workflow.Edge{edge("a", "b"), edge("b", "c"), edge("c", "b"), edge("d", "d")}},
+			want: []Problem{{Code: ProblemCycle, NodeId: "b"}, {Code: ProblemCycle, NodeId: "c"}, {Code: ProblemCycle, NodeId: "d"}},
+		},
+		{
+			name: "unknown uses",
+			dag:  &workflow.WorkflowDAG{Nodes: []*workflow.Node{node("a", ""), node("b", builtinPrefix+"not-exist"), node("c", "not-installed"), node("d", "sh -c 'id'")}},
+			want: []Problem{{Code: ProblemUnknownUses, NodeId: "a"}, {Code: ProblemUnknownUses, NodeId: "b"}, {Code: ProblemUnknownUses, NodeId: "c"}, {Code: ProblemUnknownUses, NodeId: "d"}},
+		},
+		{
+			name: "missing param",
+			dag:  &workflow.WorkflowDAG{Nodes: []*workflow.Node{node("a", builtinPrefix+"validate-test", "body", "test")}},
+			want: []Problem{{Code: ProblemMissingParam, NodeId: "a", Param: "url"}},
+		},
+		{
+			name: "duplicate node",
+			dag:  &workflow.WorkflowDAG{Nodes: []*workflow.Node{node("a", "ok"), node("a", "ok")}},
+			want: []Problem{{Code: ProblemDuplicateNode, NodeId: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkflow(tt.dag)
+			if tt.valid {
+				if err != nil {
+					t.Errorf("expected valid, got %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Fatalf("expected %d problems, got %d: %v", len(tt.want), len(verr.Problems), err)
+			}
+			for i, want := range tt.want {
+				got := verr.Problems[i]
+				if got.Code != want.Code || got.NodeId != want.NodeId || got.EdgeId != want.EdgeId || got.Param != want.Param {
+					t.Errorf("expected problem %+v, got %+v", want, *got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/MR5356/aurora/internal/domain/schedule"
+	"github.com/MR5356/aurora/pkg/util/jsonschema"
+)
+
+// runWorkflowParamsSchema json schema of RunWorkflowParams
+var runWorkflowParamsSchema = &jsonschema.Schema{
+	Type:     jsonschema.TypeObject,
+	Required: []string{"workflowId"},
+	Properties: map[string]*jsonschema.Schema{
+		"workflowId": {Type: jsonschema.TypeString, Format: jsonschema.FormatUUID, Title: "Workflow", Description: "id of workflow to run"},
+	},
+}
+
+type RunWorkflowParams struct {
+	WorkflowId string `json:"workflowId"`
+}
+
+// WorkflowTask runs a workflow as schedule task and waits for it to finish
+type WorkflowTask struct {
+	params *RunWorkflowParams
+	err    error
+}
+
+func (t *WorkflowTask) SetParams(params string) {
+	ps := new(RunWorkflowParams)
+	if err := json.Unmarshal([]byte(params), ps); err != nil {
+		t.err = fmt.Errorf("unmarshal workflow params failed: %v", err)
+		return
+	}
+	t.params = ps
+}
+
+func (t *WorkflowTask) Run(ctx context.Context) *schedule.Result {
+	if t.err != nil {
+		return &schedule.Result{Error: t.err}
+	}
+	if t.params == nil {
+		return &schedule.Result{Error: fmt.Errorf("workflow params is empty")}
+	}
+
+	run, err := GetService().RunWorkflow(ctx, t.params.WorkflowId, TriggerSchedule, "")
+	if err != nil {
+		return &schedule.Result{Error: err}
+	}
+
+	result := &schedule.Result{
+		Output:    fmt.Sprintf("workflow run %s finished with %s", run.ID, run.Status),
+		StartTime: run.StartTime,
+		EndTime:   run.EndTime,
+	}
+	if run.Status != RunStatusSuccess {
+		result.Error = fmt.Errorf("workflow run %s %s: %s", run.ID, run.Status, run.Error)
+	}
+	return result
+}
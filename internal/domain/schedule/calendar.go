@@ -0,0 +1,136 @@
+package schedule
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"time"
+	_ "time/tzdata"
+)
+
+const (
+	dateLayout = "2006-01-02"
+
+	// maxBlackoutSkips max blackout ranges skipped when computing next time
+	maxBlackoutSkips = 100
+)
+
+// validate check date format and order of blackout ranges
+func (c *Calendar) validate() error {
+	for _, r := range c.Blackout {
+		start, err := time.Parse(dateLayout, r.Start)
+		if err != nil {
+			return err
+		}
+		end, err := time.Parse(dateLayout, r.End)
+		if err != nil {
+			return err
+		}
+		if end.Before(start) {
+			return fmt.Errorf("blackout range %s ~ %s: end is before start", r.Start, r.End)
+		}
+	}
+	return nil
+}
+
+// excludes reports whether the date of t is in a blackout range,
+// if so, until is the start of the day after the range in the location of t
+func (c *Calendar) excludes(t time.Time) (excluded bool, until time.Time) {
+	date := t.Format(dateLayout)
+	for _, r := range c.Blackout {
+		if date < r.Start || date > r.End {
+			continue
+		}
+		end, err := time.ParseInLocation(dateLayout, r.End, t.Location())
+		if err != nil {
+			continue
+		}
+		return true, end.AddDate(0, 0, 1)
+	}
+	return false, time.Time{}
+}
+
+// calendarSchedule skips the ticks of cron schedule in blackout dates of calendar
+type calendarSchedule struct {
+	cron.Schedule
+
+	location   *time.Location
+	calendarID uuid.UUID
+	loader     func(id uuid.UUID) (*Calendar, error)
+}
+
+func (s *calendarSchedule) Next(t time.Time) time.Time {
+	next := s.Schedule.Next(t)
+
+	// load calendar every time, so that changes of calendar take effect without re-registering cron job
+	calendar, err := s.loader(s.calendarID)
+	if err != nil {
+		logrus.Errorf("load calendar %s failed, ignore blackout dates, error: %v", s.calendarID, err)
+		return next
+	}
+
+	for i := 0; i < maxBlackoutSkips && !next.IsZero(); i++ {
+		excluded, until := calendar.excludes(next.In(s.location))
+		if !excluded {
+			return next
+		}
+		next = s.Schedule.Next(until.Add(-time.Second))
+	}
+
+	// no run in the foreseeable future
+	return time.Time{}
+}
+
+func loadLocation(timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(timeZone)
+}
+
+// parseSchedule parse cron string of schedule with its time zone and calendar
+func (s *Service) parseSchedule(schedule *Schedule) (cron.Schedule, error) {
+	sched, err := cronParser.Parse(schedule.CronString)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := loadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	if spec, ok := sched.(*cron.SpecSchedule); ok {
+		if schedule.TimeZone != "" {
+			spec.Location = location
+		} else {
+			location = spec.Location
+		}
+	}
+
+	if schedule.CalendarID == uuid.Nil {
+		return sched, nil
+	}
+
+	return &calendarSchedule{
+		Schedule:   sched,
+		location:   location,
+		calendarID: schedule.CalendarID,
+		loader: func(id uuid.UUID) (*Calendar, error) {
+			return s.calendarDB.Detail(&Calendar{ID: id})
+		},
+	}, nil
+}
+
+// fillNextTime compute next time of enabled schedules
+func (s *Service) fillNextTime(schedules ...*Schedule) {
+	now := time.Now()
+	for _, schedule := range schedules {
+		if !schedule.Enabled || schedule.isDependencyTriggered() {
+			continue
+		}
+		if sched, err := s.parseSchedule(schedule); err == nil {
+			schedule.NextTime = sched.Next(now)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestCalendarValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		blackout DateRanges
+		wantErr  bool
+	}{
+		{name: "single date", blackout: DateRanges{{Start: "2024-10-01", End: "2024-10-01"}}},
+		{name: "range", blackout: DateRanges{{Start: "2024-10-01", End: "2024-10-07"}}},
+		{name: "end before start", blackout: DateRanges{{Start: "2024-10-07", End: "2024-10-01"}}, wantErr: true},
+		{name: "invalid date", blackout: DateRanges{{Start: "2024-13-01", End: "2024-13-01"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Calendar{Blackout: tt.blackout}
+			if err := c.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCalendarSchedule(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("load location failed: %v", err)
+	}
+
+	calendar := &Calendar{Blackout: DateRanges{
+		{Start: "2024-10-01", End: "2024-10-03"},
+		{Start: "2024-10-05", End: "2024-10-05"},
+	}}
+	s := &Service{}
+
+	tests := []struct {
+		name     string
+		schedule *Schedule
+		from     time.Time
+		want     time.Time
+	}{
+		{
+			name:     "time zone",
+			schedule: &Schedule{CronString: "0 0 9 * * *", TimeZone: "Asia/Shanghai"},
+			from:     time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC),
+			want:     time.Date(2024, 9, 1, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "skip blackout range",
+			schedule: &Schedule{CronString: "0 0 9 * * *", TimeZone: "Asia/Shanghai", CalendarID: uuid.New()},
+			from:     time.Date(2024, 9, 30, 12, 0, 0, 0, shanghai),
+			want:     time.Date(2024, 10, 4, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "skip continuous blackout dates",
+			schedule: &Schedule{CronString: "0 0 9 * * *", TimeZone: "Asia/Shanghai", CalendarID: uuid.New()},
+			from:     time.Date(2024, 10, 4, 12, 0, 0, 0, shanghai),
+			want:     time.Date(2024, 10, 6, 9, 0, 0, 0, shanghai),
+		},
+		{
+			name:     "blackout date in schedule time zone",
+			schedule: &Schedule{CronString: "0 0 1 * * *", TimeZone: "Asia/Shanghai", CalendarID: uuid.New()},
+			// 2024-09-30 17:00 UTC is 2024-10-01 01:00 in Shanghai, which is in blackout
+			from: time.Date(2024, 9, 30, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 10, 4, 1, 0, 0, 0, shanghai),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := s.parseSchedule(tt.schedule)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if cs, ok := sched.(*calendarSchedule); ok {
+				cs.loader = func(id uuid.UUID) (*Calendar, error) {
+					return calendar, nil
+				}
+			}
+
+			if next := sched.Next(tt.from); !next.Equal(tt.want) {
+				t.Errorf("expected %s, got %s", tt.want, next)
+			}
+		})
+	}
+}
+
+func TestParseScheduleInvalidTimeZone(t *testing.T) {
+	if _, err := (&Service{}).parseSchedule(&Schedule{CronString: "0 0 9 * * *", TimeZone: "Mars/Olympus"}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
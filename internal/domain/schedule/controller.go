@@ -90,21 +90,50 @@ func (c *Controller) handlePageSchedule(ctx *gin.Context) {
 
 // @Summary	page schedule record
 // @Tags		schedule
-// @Success	200		{object}	response.Response
-// @Param		page	query		int	false	"page number"
-// @Param		size	query		int	false	"size number"
+// @Success	200			{object}	response.Response
+// @Param		page		query		int		false	"page number"
+// @Param		size		query		int		false	"size number"
+// @Param		scheduleID	query		string	false	"schedule id"
 // @Router		/schedule/record/page [get]
 // @Produce	json
 func (c *Controller) handlePageScheduleRecord(ctx *gin.Context) {
 	page, size := ginutil.GetPageParams(ctx)
 
-	if res, err := c.service.PageScheduleRecord(page, size, &Record{}); err != nil {
+	record := &Record{}
+	if scheduleID := ctx.Query("scheduleID"); scheduleID != "" {
+		id, err := uuid.Parse(scheduleID)
+		if err != nil {
+			response.Error(ctx, response.CodeParamsError)
+			return
+		}
+		record.ScheduleID = id
+	}
+
+	if res, err := c.service.PageScheduleRecord(page, size, record); err != nil {
 		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
 	} else {
 		response.Success(ctx, res)
 	}
 }
 
+// @Summary	detail schedule record
+// @Tags		schedule
+// @Param		id	path		string	true	"record id"
+// @Success	200	{object}	response.Response{data=Record}
+// @Router		/schedule/record/{id}/detail [get]
+// @Produce	json
+func (c *Controller) handleDetailScheduleRecord(ctx *gin.Context) {
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+	} else {
+		if res, err := c.service.DetailScheduleRecord(id); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		} else {
+			response.Success(ctx, res)
+		}
+	}
+}
+
 // @Summary	detail schedule
 // @Tags		schedule
 // @Param		id	path		string	true	"schedule id"
@@ -141,6 +170,94 @@ func (c *Controller) handleDeleteSchedule(ctx *gin.Context) {
 	}
 }
 
+// @Summary	run schedule now
+// @Tags		schedule
+// @Param		id	path		string	true	"schedule id"
+// @Success	200	{object}	response.Response{data=Record}
+// @Router		/schedule/{id}/run [post]
+// @Produce	json
+func (c *Controller) handleRunSchedule(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	u, err := user.GetJWTService().ParseToken(ginutil.GetToken(ctx))
+	if err != nil {
+		response.Error(ctx, response.CodeNotLogin)
+		return
+	}
+
+	if res, err := c.service.RunSchedule(id, u.ID); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	rerun schedule record
+// @Tags		schedule
+// @Param		id	path		string	true	"record id"
+// @Success	200	{object}	response.Response{data=Record}
+// @Router		/schedule/record/{id}/rerun [post]
+// @Produce	json
+func (c *Controller) handleRerunScheduleRecord(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+
+	u, err := user.GetJWTService().ParseToken(ginutil.GetToken(ctx))
+	if err != nil {
+		response.Error(ctx, response.CodeNotLogin)
+		return
+	}
+
+	if res, err := c.service.RerunScheduleRecord(id, u.ID); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	list retry attempts of schedule record
+// @Tags		schedule
+// @Param		id	path		string	true	"record id"
+// @Success	200	{object}	response.Response{data=[]Record}
+// @Router		/schedule/record/{id}/attempts [get]
+// @Produce	json
+func (c *Controller) handleListScheduleRecordAttempts(ctx *gin.Context) {
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+	} else {
+		if res, err := c.service.ListScheduleRecordAttempts(id); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+		} else {
+			response.Success(ctx, res)
+		}
+	}
+}
+
+// @Summary	cancel schedule record
+// @Tags		schedule
+// @Param		id	path		string	true	"record id"
+// @Success	200	{object}	response.Response
+// @Router		/schedule/record/{id}/cancel [post]
+// @Produce	json
+func (c *Controller) handleCancelScheduleRecord(ctx *gin.Context) {
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+	} else {
+		if err := c.service.CancelScheduleRecord(id); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		} else {
+			response.Success(ctx, nil)
+		}
+	}
+}
+
 // @Summary	get task executors
 // @Tags		schedule
 // @Success	200	{object}	response.Response
@@ -156,7 +273,7 @@ func (c *Controller) handleGetTaskExecutors(ctx *gin.Context) {
 // @Router		/schedule/list [get]
 // @Produce	json
 func (c *Controller) handleListSchedule(ctx *gin.Context) {
-	res, err := c.service.scheduleDB.List(&Schedule{})
+	res, err := c.service.ListSchedule(&Schedule{})
 	if err != nil {
 		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
 	} else {
@@ -224,6 +341,100 @@ func (c *Controller) handleBatchDisableSchedule(ctx *gin.Context) {
 	}
 }
 
+// @Summary	add calendar
+// @Tags		schedule
+// @Param		calendar	body		Calendar	true	"calendar info"
+// @Success	200			{object}	response.Response
+// @Router		/schedule/calendar [post]
+// @Produce	json
+func (c *Controller) handleAddCalendar(ctx *gin.Context) {
+	calendar := new(Calendar)
+	if err := ctx.ShouldBindJSON(calendar); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	if err := c.service.AddCalendar(calendar); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, nil)
+	}
+}
+
+// @Summary	update calendar
+// @Tags		schedule
+// @Param		id			path		string		true	"calendar id"
+// @Param		calendar	body		Calendar	true	"calendar info"
+// @Success	200			{object}	response.Response
+// @Router		/schedule/calendar/{id} [put]
+// @Produce	json
+func (c *Controller) handleUpdateCalendar(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	calendar := new(Calendar)
+	if err := ctx.ShouldBindJSON(calendar); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+		return
+	}
+	calendar.ID = id
+	if err := c.service.UpdateCalendar(calendar); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+	} else {
+		response.Success(ctx, nil)
+	}
+}
+
+// @Summary	delete calendar
+// @Tags		schedule
+// @Param		id	path		string	true	"calendar id"
+// @Success	200	{object}	response.Response
+// @Router		/schedule/calendar/{id} [delete]
+// @Produce	json
+func (c *Controller) handleDeleteCalendar(ctx *gin.Context) {
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+	} else {
+		if err := c.service.DeleteCalendar(id); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		} else {
+			response.Success(ctx, nil)
+		}
+	}
+}
+
+// @Summary	list calendar
+// @Tags		schedule
+// @Success	200	{object}	response.Response{data=[]Calendar}
+// @Router		/schedule/calendar/list [get]
+// @Produce	json
+func (c *Controller) handleListCalendar(ctx *gin.Context) {
+	if res, err := c.service.ListCalendar(); err != nil {
+		response.ErrorWithMsg(ctx, response.CodeServerError, err.Error())
+	} else {
+		response.Success(ctx, res)
+	}
+}
+
+// @Summary	detail calendar
+// @Tags		schedule
+// @Param		id	path		string	true	"calendar id"
+// @Success	200	{object}	response.Response{data=Calendar}
+// @Router		/schedule/calendar/{id}/detail [get]
+// @Produce	json
+func (c *Controller) handleDetailCalendar(ctx *gin.Context) {
+	if id, err := uuid.Parse(ctx.Param("id")); err != nil {
+		response.Error(ctx, response.CodeParamsError)
+	} else {
+		if res, err := c.service.DetailCalendar(id); err != nil {
+			response.ErrorWithMsg(ctx, response.CodeParamsError, err.Error())
+		} else {
+			response.Success(ctx, res)
+		}
+	}
+}
+
 func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
 	api := group.Group("/schedule")
 
@@ -260,6 +471,12 @@ func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
 			Action:   []string{ActionAdmin, ActionOwner},
 			Domain:   AuthDomain,
 		},
+		{
+			Function: c.handleRunSchedule,
+			IsBefore: true,
+			Action:   []string{ActionAdmin, ActionOwner},
+			Domain:   AuthDomain,
+		},
 		{
 			Function: c.handleDetailSchedule,
 			IsBefore: true,
@@ -304,6 +521,9 @@ func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
 	// delete schedule
 	api.DELETE("/:id", c.handleDeleteSchedule)
 
+	// run schedule now
+	api.POST("/:id/run", c.handleRunSchedule)
+
 	// batch delete schedule
 	api.PUT("/batch/delete", c.handleBatchDeleteSchedule)
 
@@ -316,6 +536,25 @@ func (c *Controller) RegisterRoute(group *gin.RouterGroup) {
 	// page schedule record
 	api.GET("/record/page", c.handlePageScheduleRecord)
 
+	// detail schedule record
+	api.GET("/record/:id/detail", c.handleDetailScheduleRecord)
+
+	// list retry attempts of schedule record
+	api.GET("/record/:id/attempts", c.handleListScheduleRecordAttempts)
+
+	// rerun schedule record
+	api.POST("/record/:id/rerun", c.handleRerunScheduleRecord)
+
+	// cancel schedule record
+	api.POST("/record/:id/cancel", c.handleCancelScheduleRecord)
+
 	// get task executors
 	api.GET("/executors", c.handleGetTaskExecutors)
+
+	// calendar
+	api.GET("/calendar/list", c.handleListCalendar)
+	api.GET("/calendar/:id/detail", c.handleDetailCalendar)
+	api.POST("/calendar", c.handleAddCalendar)
+	api.PUT("/calendar/:id", c.handleUpdateCalendar)
+	api.DELETE("/calendar/:id", c.handleDeleteCalendar)
 }
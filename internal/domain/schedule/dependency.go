@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DependencyStatusAny matches any finish status of upstream
+	DependencyStatusAny = "any"
+
+	topicTaskFinished = "topic.schedule.task_finished"
+)
+
+// isDependencyTriggered reports whether schedule is triggered by upstream schedules instead of cron
+func (s *Schedule) isDependencyTriggered() bool {
+	return s.TriggerType == TriggerDependency
+}
+
+// matches reports whether the finished record of upstream triggers the dependency
+func (d Dependency) matches(record *Record) bool {
+	return d.ScheduleID == record.ScheduleID && (d.Status == DependencyStatusAny || d.Status == record.Status)
+}
+
+// verifyDependencies check upstream schedules exist and they do not depend on schedule itself
+func (s *Service) verifyDependencies(schedule *Schedule) error {
+	schedules, err := s.scheduleDB.List(&Schedule{TriggerType: TriggerDependency})
+	if err != nil {
+		return err
+	}
+
+	// upstream graph: schedule id -> upstream schedule ids
+	graph := make(map[uuid.UUID][]uuid.UUID)
+	for _, sc := range schedules {
+		for _, dep := range sc.DependsOn {
+			graph[sc.ID] = append(graph[sc.ID], dep.ScheduleID)
+		}
+	}
+	graph[schedule.ID] = nil
+
+	for _, dep := range schedule.DependsOn {
+		if dep.ScheduleID == schedule.ID {
+			return fmt.Errorf("schedule can not depend on itself")
+		}
+		if _, err := s.scheduleDB.Detail(&Schedule{ID: dep.ScheduleID}); err != nil {
+			return fmt.Errorf("upstream schedule %s not found", dep.ScheduleID)
+		}
+		graph[schedule.ID] = append(graph[schedule.ID], dep.ScheduleID)
+	}
+
+	// walk upstream, a cycle exists if schedule itself is reached
+	visited := make(map[uuid.UUID]bool)
+	var walk func(id uuid.UUID) bool
+	walk = func(id uuid.UUID) bool {
+		for _, up := range graph[id] {
+			if up == schedule.ID {
+				return true
+			}
+			if visited[up] {
+				continue
+			}
+			visited[up] = true
+			if walk(up) {
+				return true
+			}
+		}
+		return false
+	}
+	if walk(schedule.ID) {
+		return fmt.Errorf("schedule dependencies contain a cycle")
+	}
+	return nil
+}
+
+// triggerDependents run the enabled downstream schedules of a finished record
+func (s *Service) triggerDependents(params string) {
+	record := new(Record)
+	if err := json.Unmarshal([]byte(params), record); err != nil {
+		logrus.Errorf("unmarshal record failed, error: %v", err)
+		return
+	}
+
+	schedules, err := s.scheduleDB.List(&Schedule{TriggerType: TriggerDependency, Enabled: true})
+	if err != nil {
+		logrus.Errorf("list dependency schedules failed, error: %v", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		for _, dep := range schedule.DependsOn {
+			if !dep.matches(record) {
+				continue
+			}
+
+			logrus.Infof("schedule %s finished with %s, trigger schedule %s", record.ScheduleID, record.Status, schedule.ID)
+			if _, err := s.startTask(schedule, WithTrigger(TriggerDependency, record.ID.String())); err != nil {
+				logrus.Errorf("trigger schedule %s failed, error: %v", schedule.ID, err)
+			}
+			break
+		}
+	}
+}
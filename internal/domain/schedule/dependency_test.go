@@ -0,0 +1,98 @@
+package schedule
+
+import (
+	"encoding/json"
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestDependencyMatches(t *testing.T) {
+	upstream := uuid.New()
+
+	tests := []struct {
+		name   string
+		dep    Dependency
+		record *Record
+		want   bool
+	}{
+		{name: "success", dep: Dependency{ScheduleID: upstream, Status: TaskStatusSuccess}, record: &Record{ScheduleID: upstream, Status: TaskStatusSuccess}, want: true},
+		{name: "status mismatch", dep: Dependency{ScheduleID: upstream, Status: TaskStatusSuccess}, record: &Record{ScheduleID: upstream, Status: TaskStatusError}, want: false},
+		{name: "any", dep: Dependency{ScheduleID: upstream, Status: DependencyStatusAny}, record: &Record{ScheduleID: upstream, Status: TaskStatusError}, want: true},
+		{name: "other schedule", dep: Dependency{ScheduleID: upstream, Status: DependencyStatusAny}, record: &Record{ScheduleID: uuid.New(), Status: TaskStatusSuccess}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dep.matches(tt.record); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}, &Schedule{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	// register executor temporarily, so that the executor list in other tests is not affected
+	executor := "dependency-test"
+	task := &blockingTask{done: make(chan struct{})}
+	GetExecutorManager().tasks.Store(executor, Executor{Name: executor, task: func() Task { return task }})
+	defer GetExecutorManager().tasks.Delete(executor)
+	close(task.done)
+
+	s := GetService()
+	a := &Schedule{Title: "a", CronString: "0 0 0 * * *", Executor: executor}
+	if err := s.scheduleDB.Insert(a); err != nil {
+		t.Fatalf("insert schedule failed: %v", err)
+	}
+	b := &Schedule{Title: "b", Executor: executor, Enabled: true, TriggerType: TriggerDependency, DependsOn: Dependencies{{ScheduleID: a.ID, Status: TaskStatusSuccess}}}
+	if err := s.verifyTaskParams(b); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := s.scheduleDB.Insert(b); err != nil {
+		t.Fatalf("insert schedule failed: %v", err)
+	}
+
+	// a depends on b makes a cycle
+	a.TriggerType = TriggerDependency
+	a.DependsOn = Dependencies{{ScheduleID: b.ID, Status: DependencyStatusAny}}
+	if err := s.verifyTaskParams(a); err == nil {
+		t.Errorf("expected cycle error, got nil")
+	}
+
+	// depends on itself
+	b.DependsOn = Dependencies{{ScheduleID: b.ID, Status: DependencyStatusAny}}
+	if err := s.verifyTaskParams(b); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	// unknown upstream
+	b.DependsOn = Dependencies{{ScheduleID: uuid.New(), Status: DependencyStatusAny}}
+	if err := s.verifyTaskParams(b); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	// finished a triggers b
+	upstream, _ := json.Marshal(&Record{ID: uuid.New(), ScheduleID: a.ID, Status: TaskStatusSuccess})
+	s.triggerDependents(string(upstream))
+
+	for i := 0; i < 50; i++ {
+		if record, err := s.recordDB.Detail(&Record{ScheduleID: b.ID}); err == nil && record.Status != TaskStatusRunning {
+			if record.Trigger != TriggerDependency {
+				t.Errorf("expected trigger %s, got %s", TriggerDependency, record.Trigger)
+			}
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Errorf("expected schedule b triggered")
+}
@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"errors"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	MisfirePolicySkip = "skip"
+	MisfirePolicyOnce = "once"
+	MisfirePolicyAll  = "all"
+
+	// maxCatchUpRuns max runs replayed for a schedule with MisfirePolicyAll
+	maxCatchUpRuns = 100
+)
+
+// cronParser is the same parser used by cron.WithSeconds
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// missedTicks returns the cron ticks in (from, to)
+func missedTicks(sched cron.Schedule, from, to time.Time) []time.Time {
+	res := make([]time.Time, 0)
+	for t := sched.Next(from); !t.IsZero() && t.Before(to); t = sched.Next(t) {
+		res = append(res, t)
+		if len(res) >= maxCatchUpRuns {
+			break
+		}
+	}
+	return res
+}
+
+// lastTickTime returns the time of the last cron triggered run of schedule,
+// or the last update time of schedule if it has never run since then
+func (s *Service) lastTickTime(schedule *Schedule) (time.Time, error) {
+	last := schedule.UpdatedAt
+
+	record := new(Record)
+	err := s.recordDB.DB.
+		Where(&Record{ScheduleID: schedule.ID, Attempt: 1}).
+		Where(map[string]any{"trigger": []string{TriggerCron, TriggerCatchUp}}).
+		Order("start_time desc").
+		First(record).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return last, err
+	}
+
+	if err == nil && record.StartTime.After(last) {
+		last = record.StartTime
+	}
+	return last, nil
+}
+
+// catchesUp reports whether the cron ticks of schedule missed while server is down are replayed
+func (s *Schedule) catchesUp() bool {
+	return !s.isDependencyTriggered() && s.MisfirePolicy != "" && s.MisfirePolicy != MisfirePolicySkip
+}
+
+// catchUp replay the cron ticks missed while server is down according to the misfire policy of schedule,
+// it should finish before the cron job of schedule is added, otherwise the first tick may move the last tick time
+func (s *Service) catchUp(schedule *Schedule) {
+	if !schedule.catchesUp() {
+		return
+	}
+
+	last, err := s.lastTickTime(schedule)
+	if err != nil {
+		logrus.Errorf("get last tick time of schedule %s failed, error: %v", schedule.ID, err)
+		return
+	}
+
+	sched, err := s.parseSchedule(schedule)
+	if err != nil {
+		logrus.Errorf("parse cron of schedule %s failed, error: %v", schedule.ID, err)
+		return
+	}
+
+	missed := missedTicks(sched, last, time.Now())
+	if len(missed) == 0 {
+		return
+	}
+
+	runs := len(missed)
+	if schedule.MisfirePolicy == MisfirePolicyOnce {
+		runs = 1
+	}
+	logrus.Infof("schedule %s missed %d ticks since %s, catch up %d runs", schedule.ID, len(missed), last, runs)
+
+	taskFunc, err := GetExecutorManager().GetExecutor(schedule.Executor)
+	if err != nil {
+		logrus.Errorf("get task executor failed, error: %v", err)
+		return
+	}
+
+	for i := 0; i < runs; i++ {
+		task := taskFunc()
+		task.SetParams(schedule.Params)
+		if !NewWrapper(task, schedule, WithTrigger(TriggerCatchUp, "")).run() {
+			logrus.Warnf("schedule %s is running, skip catch up run %d/%d", schedule.ID, i+1, runs)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package schedule
+
+import (
+	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func TestMissedTicks(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		cronString string
+		to         time.Time
+		count      int
+	}{
+		{name: "no missed", cronString: "0 0 * * * *", to: from.Add(time.Minute * 30), count: 0},
+		{name: "hourly", cronString: "0 0 * * * *", to: from.Add(time.Hour*3 + time.Minute), count: 3},
+		{name: "capped", cronString: "* * * * * *", to: from.Add(time.Hour), count: maxCatchUpRuns},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := cronParser.Parse(tt.cronString)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			ticks := missedTicks(sched, from, tt.to)
+			if len(ticks) != tt.count {
+				t.Errorf("expected %d ticks, got %d", tt.count, len(ticks))
+			}
+		})
+	}
+}
+
+func TestLastTickTime(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	updated := time.Now().Add(-time.Hour * 24)
+	schedule := &Schedule{ID: uuid.New(), BaseModel: database.BaseModel{UpdatedAt: updated}}
+
+	last, err := GetService().lastTickTime(schedule)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !last.Equal(updated) {
+		t.Errorf("expected %s, got %s", updated, last)
+	}
+
+	cronRun := updated.Add(time.Hour)
+	manualRun := updated.Add(time.Hour * 2)
+	for _, record := range []*Record{
+		{ScheduleID: schedule.ID, Attempt: 1, Trigger: TriggerCron, StartTime: cronRun},
+		{ScheduleID: schedule.ID, Attempt: 1, Trigger: TriggerManual, StartTime: manualRun},
+	} {
+		if err := GetService().recordDB.Insert(record); err != nil {
+			t.Fatalf("insert record failed: %v", err)
+		}
+	}
+
+	last, err = GetService().lastTickTime(schedule)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !last.Equal(cronRun) {
+		t.Errorf("expected %s, got %s", cronRun, last)
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+	_ = GetExecutorManager().Register(Executor{Name: "catchup"}, func() Task { return &TestTask{} })
+	t.Cleanup(func() { GetExecutorManager().tasks.Delete("catchup") })
+
+	newSchedule := func() *Schedule {
+		return &Schedule{ID: uuid.New(), Title: "catch up", Executor: "catchup", CronString: "0 0 * * * *",
+			MisfirePolicy: MisfirePolicyOnce, BaseModel: database.BaseModel{UpdatedAt: time.Now().Add(-3 * time.Hour)}}
+	}
+	count := func(schedule *Schedule) int64 {
+		n, _ := GetService().recordDB.Count(&Record{ScheduleID: schedule.ID, Trigger: TriggerCatchUp})
+		return n
+	}
+
+	schedule := newSchedule()
+	GetService().catchUp(schedule)
+	if n := count(schedule); n != 1 {
+		t.Errorf("expected 1 catch up run, got %d", n)
+	}
+
+	// catch up run is skipped if the schedule is running
+	schedule = newSchedule()
+	if err := eventbus.GetEventBus().TryLock(schedule.ID.String()); err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+	GetService().catchUp(schedule)
+	_ = eventbus.GetEventBus().UnLock(schedule.ID.String())
+	if n := count(schedule); n != 0 {
+		t.Errorf("expected no catch up run while running, got %d", n)
+	}
+}
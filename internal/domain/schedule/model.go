@@ -1,7 +1,10 @@
 package schedule
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/pkg/util/jsonschema"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"time"
@@ -11,12 +14,33 @@ type Schedule struct {
 	ID         uuid.UUID `json:"id" gorm:"primary_key;type:uuid;" example:"00000000-0000-0000-0000-000000000000"`
 	Title      string    `json:"title" validate:"required"`
 	Desc       string    `json:"desc"`
-	CronString string    `json:"cronString" validate:"required" example:"*/5 * * * * *"`
+	CronString string    `json:"cronString" validate:"required_unless=TriggerType dependency" example:"*/5 * * * * *"`
 	Executor   string    `json:"executor" validate:"required" example:"test"`
 	NextTime   time.Time `json:"nextTime" gorm:"-" swaggerignore:"true"`
 	Params     string    `json:"params"`
-	Enabled    bool      `json:"enabled" example:"true"`
-	Status     string    `json:"status"`
+	Timeout    int64     `json:"timeout" validate:"gte=0" example:"0"` // seconds, 0 means no timeout
+
+	// retry policy of failed runs
+	RetryMaxAttempts int     `json:"retryMaxAttempts" validate:"gte=0" example:"3"` // total attempts, 0 or 1 means no retry
+	RetryDelay       int64   `json:"retryDelay" validate:"gte=0" example:"10"`      // seconds before the first retry
+	RetryBackoff     float64 `json:"retryBackoff" validate:"gte=0" example:"2"`     // delay multiplier of each retry, less than 1 means 1
+	RetryOn          string  `json:"retryOn" validate:"omitempty,oneof=all error timeout" example:"all"`
+
+	// TriggerType run schedule by cron string or after upstream schedules finish
+	TriggerType string `json:"triggerType" validate:"omitempty,oneof=cron dependency" example:"cron"`
+	// DependsOn upstream schedules of dependency trigger, schedule runs when any of them finishes with the expected status
+	DependsOn Dependencies `json:"dependsOn" gorm:"type:text" validate:"required_if=TriggerType dependency,dive"`
+
+	// TimeZone IANA time zone of CronString, empty means server local time zone
+	TimeZone string `json:"timeZone" example:"Asia/Shanghai"`
+	// CalendarID blackout calendar of schedule, ticks in blackout dates are skipped
+	CalendarID uuid.UUID `json:"calendarID" gorm:"type:uuid;"`
+
+	// MisfirePolicy how to handle cron ticks missed while server is down
+	MisfirePolicy string `json:"misfirePolicy" validate:"omitempty,oneof=skip once all" example:"skip"`
+
+	Enabled bool   `json:"enabled" example:"true"`
+	Status  string `json:"status"`
 
 	database.BaseModel
 }
@@ -31,12 +55,22 @@ func (s *Schedule) BeforeCreate(tx *gorm.DB) error {
 }
 
 type Record struct {
-	ID         uuid.UUID `json:"id" gorm:"primary_key;type:uuid;" swaggerignore:"true"`
-	ScheduleID uuid.UUID `json:"scheduleID" gorm:"type:uuid;"`
-	Title      string    `json:"title" validate:"required"`
-	Executor   string    `json:"executor" validate:"required"`
-	Params     string    `json:"params"`
-	Status     string    `json:"status"`
+	ID          uuid.UUID `json:"id" gorm:"primary_key;type:uuid;" swaggerignore:"true"`
+	ScheduleID  uuid.UUID `json:"scheduleID" gorm:"type:uuid;"`
+	Title       string    `json:"title" validate:"required"`
+	Executor    string    `json:"executor" validate:"required"`
+	Params      string    `json:"params"`
+	Status      string    `json:"status"`
+	Output      string    `json:"output" gorm:"type:text"`
+	Error       string    `json:"error" gorm:"type:text"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+	Duration    int64     `json:"duration" example:"1000"` // milliseconds
+	ParentID    uuid.UUID `json:"parentID" gorm:"type:uuid;index"`
+	Attempt     int       `json:"attempt" example:"1"`
+	Trigger     string    `json:"trigger" example:"cron"`
+	TriggeredBy string    `json:"triggeredBy"`
+	RerunOf     uuid.UUID `json:"rerunOf" gorm:"type:uuid;"`
 
 	database.BaseModel
 }
@@ -53,5 +87,74 @@ func (r *Record) BeforeCreate(tx *gorm.DB) error {
 type Executor struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"displayName"`
-	task        func() Task
+	// ParamsSchema json schema of Schedule.Params, nil means params are not checked before running
+	ParamsSchema *jsonschema.Schema `json:"paramsSchema,omitempty"`
+	task         func() Task
+}
+
+// Dependency is an upstream schedule and the finish status which triggers the downstream schedule
+type Dependency struct {
+	ScheduleID uuid.UUID `json:"scheduleID" validate:"required"`
+	Status     string    `json:"status" validate:"required,oneof=success error canceled any" example:"success"`
+}
+
+type Dependencies []Dependency
+
+func (d *Dependencies) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	case []byte:
+		return json.Unmarshal(v, d)
+	default:
+		return nil
+	}
+}
+
+func (d Dependencies) Value() (driver.Value, error) {
+	s, err := json.Marshal(d)
+	return string(s), err
+}
+
+// DateRange is a blackout range of dates in format 2006-01-02, End is inclusive and equals Start for a single date
+type DateRange struct {
+	Start string `json:"start" validate:"required,datetime=2006-01-02" example:"2024-10-01"`
+	End   string `json:"end" validate:"required,datetime=2006-01-02" example:"2024-10-07"`
+}
+
+type DateRanges []DateRange
+
+func (d *DateRanges) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case string:
+		return json.Unmarshal([]byte(v), d)
+	case []byte:
+		return json.Unmarshal(v, d)
+	default:
+		return nil
+	}
+}
+
+func (d DateRanges) Value() (driver.Value, error) {
+	s, err := json.Marshal(d)
+	return string(s), err
+}
+
+// Calendar is a reusable set of blackout dates that schedules can reference
+type Calendar struct {
+	ID       uuid.UUID  `json:"id" gorm:"primary_key;type:uuid;" swaggerignore:"true"`
+	Title    string     `json:"title" validate:"required"`
+	Desc     string     `json:"desc"`
+	Blackout DateRanges `json:"blackout" gorm:"type:text" validate:"dive"`
+
+	database.BaseModel
+}
+
+func (c *Calendar) TableName() string {
+	return "schedule_calendar"
+}
+
+func (c *Calendar) BeforeCreate(tx *gorm.DB) error {
+	c.ID = uuid.New()
+	return nil
 }
@@ -53,3 +53,20 @@ func (m *Manager) GetExecutors() []Executor {
 
 	return res
 }
+
+// ValidateParams validate params against the params schema of executor
+func (m *Manager) ValidateParams(name, params string) error {
+	task, ok := m.tasks.Load(name)
+	if !ok {
+		return fmt.Errorf("task executor %s not found", name)
+	}
+
+	schema := task.(Executor).ParamsSchema
+	if schema == nil {
+		return nil
+	}
+	if err := schema.ValidateJSON(params); err != nil {
+		return fmt.Errorf("invalid params of task executor %s: %v", name, err)
+	}
+	return nil
+}
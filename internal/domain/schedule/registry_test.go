@@ -2,6 +2,7 @@ package schedule
 
 import (
 	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/pkg/util/jsonschema"
 	"testing"
 )
 
@@ -52,3 +53,34 @@ func TestGetExecutorManager(t *testing.T) {
 		t.Errorf("expected nil task")
 	}
 }
+
+func TestManager_ValidateParams(t *testing.T) {
+	m := &Manager{}
+
+	_ = m.Register(Executor{Name: "plain"}, func() Task { return &TestTask{} })
+	_ = m.Register(Executor{Name: "typed", ParamsSchema: &jsonschema.Schema{
+		Type:       jsonschema.TypeObject,
+		Required:   []string{"url"},
+		Properties: map[string]*jsonschema.Schema{"url": {Type: jsonschema.TypeString}},
+	}}, func() Task { return &TestTask{} })
+
+	tests := []struct {
+		name     string
+		executor string
+		params   string
+		wantErr  bool
+	}{
+		{name: "without schema", executor: "plain", params: "anything"},
+		{name: "valid params", executor: "typed", params: `{"url":"http://localhost"}`},
+		{name: "invalid params", executor: "typed", params: `{"url":1}`, wantErr: true},
+		{name: "unknown executor", executor: "unknown", params: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.ValidateParams(tt.executor, tt.params); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
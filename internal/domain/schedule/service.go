@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 	"sync"
 )
 
@@ -32,6 +33,7 @@ var (
 type Service struct {
 	scheduleDB *database2.BaseMapper[*Schedule]
 	recordDB   *database2.BaseMapper[*Record]
+	calendarDB *database2.BaseMapper[*Calendar]
 	cron       *cron.Cron
 	cronJobMap sync.Map
 }
@@ -44,6 +46,7 @@ func GetService() *Service {
 		service = &Service{
 			scheduleDB: database2.NewMapper(database2.GetDB(), &Schedule{}),
 			recordDB:   database2.NewMapper(database2.GetDB(), &Record{}),
+			calendarDB: database2.NewMapper(database2.GetDB(), &Calendar{}),
 			cron:       c,
 			cronJobMap: sync.Map{},
 		}
@@ -92,6 +95,11 @@ func (s *Service) addCronTask(params string) {
 		return
 	}
 
+	if schedule.isDependencyTriggered() {
+		logrus.Debugf("schedule %s is triggered by dependencies, skip cron", schedule.ID)
+		return
+	}
+
 	if _, ok := s.cronJobMap.Load(schedule.ID); ok {
 		logrus.Errorf("task %s already exists", schedule.ID)
 		return
@@ -103,14 +111,16 @@ func (s *Service) addCronTask(params string) {
 		return
 	}
 
-	f := taskFunc()
-	f.SetParams(schedule.Params)
-
-	jobId, err := s.cron.AddJob(schedule.CronString, NewWrapper(f, schedule))
+	sched, err := s.parseSchedule(schedule)
 	if err != nil {
 		logrus.Errorf("add cron job failed, error: %v", err)
 		return
 	}
+
+	f := taskFunc()
+	f.SetParams(schedule.Params)
+
+	jobId := s.cron.Schedule(sched, NewWrapper(f, schedule))
 	s.cronJobMap.Store(schedule.ID, jobId)
 }
 
@@ -184,7 +194,22 @@ func (s *Service) UpdateSchedule(schedule *Schedule) error {
 
 // DetailSchedule detail schedule
 func (s *Service) DetailSchedule(id uuid.UUID) (*Schedule, error) {
-	return s.scheduleDB.Detail(&Schedule{ID: id})
+	schedule, err := s.scheduleDB.Detail(&Schedule{ID: id})
+	if err != nil {
+		return nil, err
+	}
+	s.fillNextTime(schedule)
+	return schedule, nil
+}
+
+// ListSchedule list schedules
+func (s *Service) ListSchedule(schedule *Schedule) ([]*Schedule, error) {
+	schedules, err := s.scheduleDB.List(schedule)
+	if err != nil {
+		return nil, err
+	}
+	s.fillNextTime(schedules...)
+	return schedules, nil
 }
 
 func (s *Service) verifyTaskParams(schedule *Schedule) error {
@@ -192,14 +217,24 @@ func (s *Service) verifyTaskParams(schedule *Schedule) error {
 		return err
 	}
 
-	// verify task executor
-	if _, err := GetExecutorManager().GetExecutor(schedule.Executor); err != nil {
+	// verify task executor and its params
+	if err := GetExecutorManager().ValidateParams(schedule.Executor, schedule.Params); err != nil {
 		return err
 	}
 
-	// verify cron string
-	parser := cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	if _, err := parser.Parse(schedule.CronString); err != nil {
+	// verify calendar
+	if schedule.CalendarID != uuid.Nil {
+		if _, err := s.calendarDB.Detail(&Calendar{ID: schedule.CalendarID}); err != nil {
+			return fmt.Errorf("calendar %s not found", schedule.CalendarID)
+		}
+	}
+
+	if schedule.isDependencyTriggered() {
+		return s.verifyDependencies(schedule)
+	}
+
+	// verify cron string and time zone
+	if _, err := s.parseSchedule(schedule); err != nil {
 		return err
 	}
 	return nil
@@ -284,18 +319,139 @@ func (s *Service) GetTaskExecutors() []Executor {
 	return GetExecutorManager().GetExecutors()
 }
 
-// PageScheduleRecord page task records
+// PageScheduleRecord page task records, attempts of retried tasks are left out
 func (s *Service) PageScheduleRecord(num, size int, record *Record) (*database2.Pager[*Record], error) {
-	return s.recordDB.Page(record, int64(num), int64(size))
+	res := new(database2.Pager[*Record])
+	res.CurrentPage = int64(num)
+	res.PageSize = int64(size)
+
+	// retry attempts are listed with their first record
+	query := func() *gorm.DB {
+		return s.recordDB.DB.Model(&Record{}).Where(record).Where("parent_id IS NULL OR parent_id = ?", uuid.Nil)
+	}
+	if err := query().Count(&res.Total).Error; err != nil {
+		return nil, err
+	}
+	res.Data = make([]*Record, 0)
+	if res.Total == 0 {
+		return res, nil
+	}
+	if err := query().Order("updated_at desc").Scopes(database2.Pagination(res)).Find(&res.Data).Error; err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RunSchedule run schedule immediately, even if it is disabled
+func (s *Service) RunSchedule(id uuid.UUID, triggeredBy string) (*Record, error) {
+	schedule, err := s.scheduleDB.Detail(&Schedule{ID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startTask(schedule, WithTrigger(TriggerManual, triggeredBy))
+}
+
+// RerunScheduleRecord run a past record again with its saved params, timeout and retry policy of schedule are kept
+func (s *Service) RerunScheduleRecord(id uuid.UUID, triggeredBy string) (*Record, error) {
+	record, err := s.recordDB.Detail(&Record{ID: id})
+	if err != nil {
+		return nil, err
+	}
+	if record.ScheduleID == uuid.Nil {
+		return nil, fmt.Errorf("schedule of record %s not found", id)
+	}
+	schedule, err := s.scheduleDB.Detail(&Schedule{ID: record.ScheduleID})
+	if err != nil {
+		return nil, fmt.Errorf("schedule of record %s not found", id)
+	}
+
+	schedule.Params = record.Params
+	return s.startTask(schedule, WithTrigger(TriggerRerun, triggeredBy), WithRerunOf(record.ID))
+}
+
+func (s *Service) startTask(schedule *Schedule, opts ...WrapperOption) (*Record, error) {
+	taskFunc, err := GetExecutorManager().GetExecutor(schedule.Executor)
+	if err != nil {
+		return nil, err
+	}
+
+	task := taskFunc()
+	task.SetParams(schedule.Params)
+
+	return NewWrapper(task, schedule, opts...).Start()
+}
+
+// ListScheduleRecordAttempts list retry attempts of a task record
+func (s *Service) ListScheduleRecordAttempts(id uuid.UUID) (res []*Record, err error) {
+	err = s.recordDB.DB.Order("attempt").Find(&res, &Record{ParentID: id}).Error
+	return
+}
+
+// CancelScheduleRecord cancel a running task record
+func (s *Service) CancelScheduleRecord(id uuid.UUID) error {
+	return CancelTask(id)
+}
+
+// DetailScheduleRecord detail task record
+func (s *Service) DetailScheduleRecord(id uuid.UUID) (*Record, error) {
+	return s.recordDB.Detail(&Record{ID: id})
 }
 
 // PageSchedule page schedules
 func (s *Service) PageSchedule(num, size int, schedule *Schedule) (*database2.Pager[*Schedule], error) {
-	return s.scheduleDB.Page(schedule, int64(num), int64(size))
+	res, err := s.scheduleDB.Page(schedule, int64(num), int64(size))
+	if err != nil {
+		return nil, err
+	}
+	s.fillNextTime(res.Data...)
+	return res, nil
+}
+
+// AddCalendar add blackout calendar
+func (s *Service) AddCalendar(calendar *Calendar) error {
+	if err := validate.Validate(calendar); err != nil {
+		return err
+	}
+	if err := calendar.validate(); err != nil {
+		return err
+	}
+	return s.calendarDB.Insert(calendar)
+}
+
+// UpdateCalendar update blackout calendar, schedules referencing it take effect from their next tick
+func (s *Service) UpdateCalendar(calendar *Calendar) error {
+	if err := validate.Validate(calendar); err != nil {
+		return err
+	}
+	if err := calendar.validate(); err != nil {
+		return err
+	}
+	return s.calendarDB.Update(&Calendar{ID: calendar.ID}, structutil.Struct2Map(calendar))
+}
+
+// DeleteCalendar delete blackout calendar which is not referenced by any schedule
+func (s *Service) DeleteCalendar(id uuid.UUID) error {
+	if count, err := s.scheduleDB.Count(&Schedule{CalendarID: id}); err != nil {
+		return err
+	} else if count > 0 {
+		return fmt.Errorf("calendar %s is used by %d schedules", id, count)
+	}
+	return s.calendarDB.Delete(&Calendar{ID: id})
+}
+
+// ListCalendar list blackout calendars
+func (s *Service) ListCalendar() ([]*Calendar, error) {
+	return s.calendarDB.List(&Calendar{})
+}
+
+// DetailCalendar detail blackout calendar
+func (s *Service) DetailCalendar(id uuid.UUID) (*Calendar, error) {
+	return s.calendarDB.Detail(&Calendar{ID: id})
 }
 
 func (s *Service) Initialize() (err error) {
-	if err = database2.GetDB().AutoMigrate(&Record{}, &Schedule{}); err != nil {
+	if err = database2.GetDB().AutoMigrate(&Record{}, &Schedule{}, &Calendar{}); err != nil {
 		return err
 	}
 
@@ -313,6 +469,13 @@ func (s *Service) Initialize() (err error) {
 		return err
 	}
 
+	if err := GetExecutorManager().Register(Executor{Name: "webhook", DisplayName: "http webhook executor", ParamsSchema: webhookParamsSchema}, func() Task {
+		return &WebhookTask{}
+	}); err != nil {
+		logrus.Errorf("register webhook task failed, error: %v", err)
+		return err
+	}
+
 	if err = eventbus.GetEventBus().Subscribe(topicAddCronTask, s.addCronTask); err != nil {
 		return err
 	}
@@ -321,17 +484,32 @@ func (s *Service) Initialize() (err error) {
 		return err
 	}
 
+	if err = eventbus.GetEventBus().Subscribe(topicTaskFinished, s.triggerDependents); err != nil {
+		return err
+	}
+
 	if jobs, err := s.scheduleDB.List(&Schedule{Enabled: true}); err != nil {
 		return err
 	} else {
 		for _, job := range jobs {
-			if js, err := json.Marshal(job); err != nil {
+			js, err := json.Marshal(job)
+			if err != nil {
 				return err
-			} else {
+			}
+			if !job.catchesUp() {
 				if err = eventbus.GetEventBus().Publish(topicAddCronTask, string(js)); err != nil {
 					return err
 				}
+				continue
 			}
+
+			// replay the ticks missed while server is down before cron takes over
+			go func(job *Schedule, js string) {
+				s.catchUp(job)
+				if err := eventbus.GetEventBus().Publish(topicAddCronTask, js); err != nil {
+					logrus.Errorf("publish add cron task failed, error: %v", err)
+				}
+			}(job, string(js))
 		}
 	}
 
@@ -1,81 +1,335 @@
 package schedule
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	database2 "github.com/MR5356/aurora/internal/infrastructure/database"
 	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
 	"github.com/MR5356/aurora/pkg/util/structutil"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
 )
 
 const (
-	TaskStatusRunning = "running"
-	TaskStatusError   = "error"
-	TaskStatusSuccess = "success"
+	TaskStatusRunning  = "running"
+	TaskStatusError    = "error"
+	TaskStatusSuccess  = "success"
+	TaskStatusCanceled = "canceled"
+
+	// TriggerCron and TriggerDependency are also trigger types of schedule
+	TriggerCron       = "cron"
+	TriggerManual     = "manual"
+	TriggerRerun      = "rerun"
+	TriggerCatchUp    = "catchup"
+	TriggerDependency = "dependency"
+
+	RetryOnAll     = "all"
+	RetryOnError   = "error"
+	RetryOnTimeout = "timeout"
+
+	// maxOutputLength max length of task output stored in record
+	maxOutputLength = 64 * 1024
+)
+
+var (
+	ErrTaskCanceled = errors.New("task canceled")
+	ErrTaskTimeout  = errors.New("task timeout")
+
+	// runningTasks record id -> context.CancelCauseFunc
+	runningTasks = sync.Map{}
 )
 
 type Task interface {
-	// Run task entry
-	Run()
+	// Run task entry, returns the result of this run.
+	// ctx is done when the task is canceled or timeout, task should return as soon as possible
+	Run(ctx context.Context) *Result
 	// SetParams set task params, you can use it to set task configuration
 	SetParams(params string)
 }
 
+// Result is the structured result reported by a task run
+type Result struct {
+	// Error is not nil if the run failed
+	Error error
+	// Output captured logs of the run
+	Output string
+	// StartTime and EndTime are filled by WrappedTask if the task leaves them empty
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// Duration of the run
+func (r *Result) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
 type WrappedTask struct {
 	Task
 
-	schedule *Schedule
-	db       *database2.BaseMapper[*Record]
+	schedule    *Schedule
+	db          *database2.BaseMapper[*Record]
+	trigger     string
+	triggeredBy string
+	rerunOf     uuid.UUID
 }
 
-func NewWrapper(task Task, schedule *Schedule) *WrappedTask {
-	return &WrappedTask{
+type WrapperOption func(w *WrappedTask)
+
+// WithTrigger set how the task is triggered and who triggered it
+func WithTrigger(trigger, triggeredBy string) WrapperOption {
+	return func(w *WrappedTask) {
+		w.trigger = trigger
+		w.triggeredBy = triggeredBy
+	}
+}
+
+// WithRerunOf mark the task as a re-run of the given record
+func WithRerunOf(recordID uuid.UUID) WrapperOption {
+	return func(w *WrappedTask) {
+		w.rerunOf = recordID
+	}
+}
+
+func NewWrapper(task Task, schedule *Schedule, opts ...WrapperOption) *WrappedTask {
+	w := &WrappedTask{
 		Task:     task,
 		schedule: schedule,
 		db:       database2.NewMapper(database2.GetDB(), &Record{}),
+		trigger:  TriggerCron,
 	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
 }
 
+// Run is called by cron, it will skip if the task is already running
 func (w *WrappedTask) Run() {
+	w.run()
+}
+
+// run task and wait until it finishes, returns false if it is skipped because the task is already running
+func (w *WrappedTask) run() bool {
 	// attempt to lock
-	if err := eventbus.GetEventBus().TryLock(w.schedule.ID.String()); err != nil {
+	if err := w.lock(); err != nil {
 		logrus.Debugf("task %s try lock failed, skip, error: %v", w.schedule.ID, err)
-		return
+		return false
 	}
+	defer w.unlock()
 
-	defer func(bus eventbus.EventBus, key string) {
-		err := bus.UnLock(key)
-		if err != nil {
-			logrus.Errorf("task %s unlock failed, error: %v", w.schedule.ID, err)
-		}
-	}(eventbus.GetEventBus(), w.schedule.ID.String())
+	record := w.newRecord(uuid.Nil, 1)
+	ctx, release := w.newContext(context.Background(), record)
+	w.runWithRetry(ctx, release, record)
+	return true
+}
 
-	// insert record
-	record := &Record{
-		ScheduleID: w.schedule.ID,
-		Title:      w.schedule.Title,
-		Executor:   w.schedule.Executor,
-		Params:     w.schedule.Params,
-		Status:     TaskStatusRunning,
+// Start run task in background, returns error if the task is already running
+func (w *WrappedTask) Start() (*Record, error) {
+	if err := w.lock(); err != nil {
+		return nil, fmt.Errorf("task %s is running", w.schedule.ID)
 	}
 
-	err := w.db.Insert(record)
-	if err != nil {
-		logrus.Errorf("task %s record insert record failed, error: %v", w.schedule.ID, err)
+	record := w.newRecord(uuid.Nil, 1)
+	ctx, release := w.newContext(context.Background(), record)
+	go func() {
+		defer w.unlock()
+		w.runWithRetry(ctx, release, record)
+	}()
+	return record, nil
+}
+
+func (w *WrappedTask) lock() error {
+	return eventbus.GetEventBus().TryLock(w.schedule.ID.String())
+}
+
+func (w *WrappedTask) unlock() {
+	if err := eventbus.GetEventBus().UnLock(w.schedule.ID.String()); err != nil {
+		logrus.Errorf("task %s unlock failed, error: %v", w.schedule.ID, err)
 	}
+}
+
+// runWithRetry execute the first attempt, then retry it according to the retry policy of schedule.
+// retries are recorded as children of the first record, which can be canceled until the last attempt
+// finishes and ends with the status of the last attempt
+func (w *WrappedTask) runWithRetry(ctx context.Context, release func(), record *Record) {
+	defer release()
+	err := w.execute(ctx, record)
 
-	defer func() {
-		if finalErr := recover(); finalErr != nil {
-			logrus.Errorf("task %s recover failed, error: %v", w.schedule.ID, finalErr)
-			record.Status = TaskStatusError
-		} else {
-			record.Status = TaskStatusSuccess
+	last := record
+	delay := time.Duration(w.schedule.RetryDelay) * time.Second
+retry:
+	for attempt := 2; w.shouldRetry(err, attempt); attempt++ {
+		logrus.Infof("task %s failed, retry %d/%d after %s, error: %v", w.schedule.ID, attempt, w.schedule.RetryMaxAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			logrus.Infof("task %s canceled while waiting for retry %d", w.schedule.ID, attempt)
+			last = &Record{Status: TaskStatusCanceled, Error: context.Cause(ctx).Error()}
+			break retry
+		case <-time.After(delay):
 		}
+		delay = time.Duration(float64(delay) * max(w.schedule.RetryBackoff, 1))
 
-		if updateErr := w.db.Update(&Record{ID: record.ID}, structutil.Struct2Map(record)); updateErr != nil {
+		last = w.newRecord(record.ID, attempt)
+		attemptCtx, attemptRelease := w.newContext(ctx, last)
+		err = w.execute(attemptCtx, last)
+		attemptRelease()
+	}
+
+	if last != record {
+		record.Status, record.Error, record.EndTime = last.Status, last.Error, last.EndTime
+		if record.EndTime.IsZero() {
+			record.EndTime = time.Now()
+		}
+		record.Duration = record.EndTime.Sub(record.StartTime).Milliseconds()
+		if updateErr := w.db.Update(&Record{ID: record.ID}, map[string]any{"status": record.Status, "error": record.Error,
+			"end_time": record.EndTime, "duration": record.Duration}); updateErr != nil {
 			logrus.Errorf("task %s record update failed, error: %v", w.schedule.ID, updateErr)
 		}
+	}
+
+	// notify downstream schedules with the final record
+	if rs, err := json.Marshal(record); err != nil {
+		logrus.Errorf("marshal record failed, error: %v", err)
+	} else if err := eventbus.GetEventBus().Publish(topicTaskFinished, string(rs)); err != nil {
+		logrus.Errorf("publish task finished failed, error: %v", err)
+	}
+}
+
+func (w *WrappedTask) shouldRetry(err error, attempt int) bool {
+	if err == nil || attempt > w.schedule.RetryMaxAttempts || errors.Is(err, ErrTaskCanceled) {
+		return false
+	}
+
+	timeout := errors.Is(err, ErrTaskTimeout)
+	switch w.schedule.RetryOn {
+	case RetryOnError:
+		return !timeout
+	case RetryOnTimeout:
+		return timeout
+	default:
+		return true
+	}
+}
+
+// newRecord insert a running record, parentID is the first attempt of retries
+func (w *WrappedTask) newRecord(parentID uuid.UUID, attempt int) *Record {
+	record := &Record{
+		ScheduleID:  w.schedule.ID,
+		Title:       w.schedule.Title,
+		Executor:    w.schedule.Executor,
+		Params:      w.schedule.Params,
+		Status:      TaskStatusRunning,
+		Trigger:     w.trigger,
+		TriggeredBy: w.triggeredBy,
+		RerunOf:     w.rerunOf,
+		ParentID:    parentID,
+		Attempt:     attempt,
+		StartTime:   time.Now(),
+	}
+
+	if err := w.db.Insert(record); err != nil {
+		logrus.Errorf("task %s record insert record failed, error: %v", w.schedule.ID, err)
+	}
+	return record
+}
+
+// newContext create the context of a run derived from parent, it can be canceled by CancelTask until release is called
+func (w *WrappedTask) newContext(parent context.Context, record *Record) (ctx context.Context, release func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	runningTasks.Store(record.ID, cancel)
+	return ctx, func() {
+		runningTasks.Delete(record.ID)
+		cancel(nil)
+	}
+}
+
+// execute run task once and update record, returns the error of this run
+func (w *WrappedTask) execute(ctx context.Context, record *Record) error {
+	if w.schedule.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, time.Duration(w.schedule.Timeout)*time.Second, ErrTaskTimeout)
+		defer cancel()
+	}
+
+	// run task in background, so that a task ignoring ctx still releases the lock when canceled
+	done := make(chan *Result, 1)
+	go func() {
+		defer func() {
+			if finalErr := recover(); finalErr != nil {
+				logrus.Errorf("task %s recover failed, error: %v", w.schedule.ID, finalErr)
+				done <- &Result{Error: fmt.Errorf("panic: %v", finalErr)}
+			}
+		}()
+		done <- w.Task.Run(ctx)
 	}()
 
-	// run task
-	w.Task.Run()
+	var result *Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = &Result{Error: context.Cause(ctx)}
+	}
+	if result == nil {
+		result = new(Result)
+	}
+	if result.Error != nil && ctx.Err() != nil {
+		result.Error = context.Cause(ctx)
+	}
+
+	w.finishRecord(record, result)
+	// the first record keeps running until the last attempt finishes
+	if record.Attempt == 1 && w.shouldRetry(result.Error, 2) {
+		record.Status = TaskStatusRunning
+	}
+	if updateErr := w.db.Update(&Record{ID: record.ID}, structutil.Struct2Map(record)); updateErr != nil {
+		logrus.Errorf("task %s record update failed, error: %v", w.schedule.ID, updateErr)
+	}
+	return result.Error
+}
+
+// finishRecord fill record with the result of task
+func (w *WrappedTask) finishRecord(record *Record, result *Result) {
+	if result.StartTime.IsZero() {
+		result.StartTime = record.StartTime
+	}
+	if result.EndTime.IsZero() {
+		result.EndTime = time.Now()
+	}
+
+	record.StartTime = result.StartTime
+	record.EndTime = result.EndTime
+	record.Duration = result.Duration().Milliseconds()
+	record.Output = truncateOutput(result.Output)
+
+	switch {
+	case result.Error == nil:
+		record.Status = TaskStatusSuccess
+	case errors.Is(result.Error, ErrTaskCanceled):
+		record.Status = TaskStatusCanceled
+		record.Error = result.Error.Error()
+	default:
+		record.Status = TaskStatusError
+		record.Error = result.Error.Error()
+	}
+}
+
+// CancelTask cancel a running task by record id
+func CancelTask(recordID uuid.UUID) error {
+	cancel, ok := runningTasks.Load(recordID)
+	if !ok {
+		return fmt.Errorf("task record %s is not running", recordID)
+	}
+	cancel.(context.CancelCauseFunc)(ErrTaskCanceled)
+	return nil
+}
+
+func truncateOutput(output string) string {
+	if len(output) <= maxOutputLength {
+		return output
+	}
+	return output[len(output)-maxOutputLength:]
 }
@@ -1,11 +1,15 @@
 package schedule
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/MR5356/aurora/internal/config"
 	"github.com/MR5356/aurora/internal/infrastructure/database"
 	"github.com/MR5356/aurora/internal/infrastructure/eventbus"
 	"github.com/google/uuid"
 	"testing"
+	"time"
 )
 
 var _ = config.New(config.WithDatabase("sqlite", ":memory:"))
@@ -46,3 +50,321 @@ func TestNewWrapperWithError(t *testing.T) {
 
 	wrappedTask.Run()
 }
+
+type resultTask struct {
+	result *Result
+}
+
+func (t *resultTask) Run(ctx context.Context) *Result {
+	return t.result
+}
+
+func (t *resultTask) SetParams(params string) {}
+
+func TestWrappedTaskRecordResult(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		result *Result
+		status string
+		errMsg string
+	}{
+		{name: "success", result: &Result{Output: "hello"}, status: TaskStatusSuccess},
+		{name: "error", result: &Result{Output: "hello", Error: errors.New("boom")}, status: TaskStatusError, errMsg: "boom"},
+		{name: "nil result", result: nil, status: TaskStatusSuccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduleID := uuid.New()
+			wrappedTask := NewWrapper(&resultTask{result: tt.result}, &Schedule{
+				ID:       scheduleID,
+				Title:    tt.name,
+				Executor: "test",
+			})
+			wrappedTask.Run()
+
+			record, err := wrappedTask.db.Detail(&Record{ScheduleID: scheduleID})
+			if err != nil {
+				t.Fatalf("expected record, got error %v", err)
+			}
+			if record.Status != tt.status {
+				t.Errorf("expected status %s, got %s", tt.status, record.Status)
+			}
+			if record.Error != tt.errMsg {
+				t.Errorf("expected error %q, got %q", tt.errMsg, record.Error)
+			}
+			if tt.result != nil && record.Output != tt.result.Output {
+				t.Errorf("expected output %q, got %q", tt.result.Output, record.Output)
+			}
+			if record.EndTime.Before(record.StartTime) {
+				t.Errorf("expected end time after start time")
+			}
+		})
+	}
+}
+
+type blockingTask struct {
+	done chan struct{}
+}
+
+func (t *blockingTask) Run(ctx context.Context) *Result {
+	<-t.done
+	return &Result{Output: "done"}
+}
+
+func (t *blockingTask) SetParams(params string) {}
+
+func TestWrappedTaskStart(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	schedule := &Schedule{ID: uuid.New(), Title: "manual", Executor: "test"}
+	task := &blockingTask{done: make(chan struct{})}
+
+	record, err := NewWrapper(task, schedule, WithTrigger(TriggerManual, "user")).Start()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if record.Trigger != TriggerManual || record.TriggeredBy != "user" {
+		t.Errorf("expected manual trigger by user, got %s by %s", record.Trigger, record.TriggeredBy)
+	}
+
+	if _, err := NewWrapper(task, schedule).Start(); err == nil {
+		t.Errorf("expected error when task is running, got nil")
+	}
+
+	close(task.done)
+	for i := 0; i < 50; i++ {
+		if err := eventbus.GetEventBus().TryLock(schedule.ID.String()); err == nil {
+			_ = eventbus.GetEventBus().UnLock(schedule.ID.String())
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	res, err := database.NewMapper(database.GetDB(), &Record{}).Detail(&Record{ID: record.ID})
+	if err != nil {
+		t.Fatalf("expected record, got error %v", err)
+	}
+	if res.Status != TaskStatusSuccess {
+		t.Errorf("expected status %s, got %s", TaskStatusSuccess, res.Status)
+	}
+}
+
+func waitRecordFinished(t *testing.T, id uuid.UUID) *Record {
+	mapper := database.NewMapper(database.GetDB(), &Record{})
+	for i := 0; i < 50; i++ {
+		record, err := mapper.Detail(&Record{ID: id})
+		if err == nil && record.Status != TaskStatusRunning {
+			return record
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("record %s not finished", id)
+	return nil
+}
+
+func TestWrappedTaskCancelAndTimeout(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	// blocking task ignores ctx, wrapper should still finish the record
+	task := &blockingTask{done: make(chan struct{})}
+	defer close(task.done)
+
+	record, err := NewWrapper(task, &Schedule{ID: uuid.New(), Title: "cancel", Executor: "test"}).Start()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := CancelTask(record.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if res := waitRecordFinished(t, record.ID); res.Status != TaskStatusCanceled {
+		t.Errorf("expected status %s, got %s", TaskStatusCanceled, res.Status)
+	}
+	if err := CancelTask(record.ID); err == nil {
+		t.Errorf("expected error when task is not running, got nil")
+	}
+
+	record, err = NewWrapper(task, &Schedule{ID: uuid.New(), Title: "timeout", Executor: "test", Timeout: 1}).Start()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	res := waitRecordFinished(t, record.ID)
+	if res.Status != TaskStatusError || res.Error != ErrTaskTimeout.Error() {
+		t.Errorf("expected status %s with timeout error, got %s: %s", TaskStatusError, res.Status, res.Error)
+	}
+}
+
+type flakyTask struct {
+	failures int
+	runs     int
+}
+
+func (t *flakyTask) Run(ctx context.Context) *Result {
+	t.runs++
+	if t.runs <= t.failures {
+		return &Result{Error: fmt.Errorf("failure %d", t.runs)}
+	}
+	return &Result{Output: "ok"}
+}
+
+func (t *flakyTask) SetParams(params string) {}
+
+func TestWrappedTaskRetry(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		failures int
+		attempts int
+		retryOn  string
+		runs     int
+		status   string
+	}{
+		{name: "no retry", failures: 1, attempts: 0, runs: 1, status: TaskStatusError},
+		{name: "retry until success", failures: 2, attempts: 5, runs: 3, status: TaskStatusSuccess},
+		{name: "retry exhausted", failures: 5, attempts: 3, runs: 3, status: TaskStatusError},
+		{name: "retry on timeout only", failures: 5, attempts: 3, retryOn: RetryOnTimeout, runs: 1, status: TaskStatusError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &flakyTask{failures: tt.failures}
+			schedule := &Schedule{ID: uuid.New(), Title: tt.name, Executor: "test", RetryMaxAttempts: tt.attempts, RetryOn: tt.retryOn}
+			NewWrapper(task, schedule).Run()
+
+			if task.runs != tt.runs {
+				t.Errorf("expected %d runs, got %d", tt.runs, task.runs)
+			}
+
+			mapper := database.NewMapper(database.GetDB(), &Record{})
+			first, err := mapper.Detail(&Record{ScheduleID: schedule.ID, Attempt: 1})
+			if err != nil {
+				t.Fatalf("expected first record, got error %v", err)
+			}
+			children, err := GetService().ListScheduleRecordAttempts(first.ID)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(children) != tt.runs-1 {
+				t.Errorf("expected %d child records, got %d", tt.runs-1, len(children))
+			}
+			if first.Status != tt.status {
+				t.Errorf("expected first record with final status %s, got %s", tt.status, first.Status)
+			}
+			if page, err := GetService().PageScheduleRecord(1, 10, &Record{ScheduleID: schedule.ID}); err != nil || page.Total != 1 {
+				t.Errorf("expected only first record paged, got %+v, error: %v", page, err)
+			}
+		})
+	}
+}
+
+func TestWrappedTaskCancelRetry(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	task := &flakyTask{failures: 5}
+	schedule := &Schedule{ID: uuid.New(), Title: "cancel retry", Executor: "test", RetryMaxAttempts: 3, RetryDelay: 3600}
+	record, err := NewWrapper(task, schedule).Start()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// first attempt fails right away, the record keeps running and is cancelable while waiting for retry
+	for i := 0; i < 50; i++ {
+		if res, _ := GetService().DetailScheduleRecord(record.ID); res.Error != "" {
+			if res.Status != TaskStatusRunning {
+				t.Errorf("expected first record running while waiting for retry, got %s", res.Status)
+			}
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := CancelTask(record.ID); err != nil {
+		t.Fatalf("expected cancel while waiting for retry, got %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if res, _ := GetService().DetailScheduleRecord(record.ID); res.Status == TaskStatusCanceled {
+			if children, _ := GetService().ListScheduleRecordAttempts(record.ID); len(children) != 0 {
+				t.Errorf("expected no retry after cancel, got %d", len(children))
+			}
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Errorf("expected first record canceled")
+}
+
+func TestRerunScheduleRecord(t *testing.T) {
+	cfg := config.Current(config.WithDatabase("sqlite", ":memory:"))
+	eventbus.NewEventBus(cfg)
+	database.NewDatabase(cfg)
+	if err := database.GetDB().AutoMigrate(&Record{}, &Schedule{}); err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	executor := "rerun-test"
+	task := &flakyTask{failures: 1}
+	GetExecutorManager().tasks.Store(executor, Executor{Name: executor, task: func() Task { return task }})
+	t.Cleanup(func() { GetExecutorManager().tasks.Delete(executor) })
+
+	s := GetService()
+	schedule := &Schedule{Title: "rerun", CronString: "0 0 0 * * *", Executor: executor, Params: `{"v":2}`, RetryMaxAttempts: 2}
+	if err := s.scheduleDB.Insert(schedule); err != nil {
+		t.Fatalf("insert schedule failed: %v", err)
+	}
+	past := &Record{ScheduleID: schedule.ID, Title: "rerun", Executor: executor, Params: `{"v":1}`, Status: TaskStatusError, Attempt: 1}
+	if err := s.recordDB.Insert(past); err != nil {
+		t.Fatalf("insert record failed: %v", err)
+	}
+
+	record, err := s.RerunScheduleRecord(past.ID, "tester")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if res, _ := s.DetailScheduleRecord(record.ID); res.Status != TaskStatusRunning {
+			// retry policy of schedule is kept, so the failing first attempt is retried
+			if res.Status != TaskStatusSuccess || res.Params != past.Params || res.RerunOf != past.ID {
+				t.Errorf("unexpected rerun record: %+v", res)
+			}
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if task.runs != 2 {
+		t.Errorf("expected 2 runs, got %d", task.runs)
+	}
+
+	if err := s.scheduleDB.Delete(&Schedule{ID: schedule.ID}); err != nil {
+		t.Fatalf("delete schedule failed: %v", err)
+	}
+	if _, err := s.RerunScheduleRecord(past.ID, "tester"); err == nil {
+		t.Errorf("expected error of rerunning record of deleted schedule")
+	}
+}
@@ -1,6 +1,9 @@
 package schedule
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"time"
 )
@@ -9,12 +12,17 @@ type TestTask struct {
 	params string
 }
 
-func (t *TestTask) Run() {
+func (t *TestTask) Run(ctx context.Context) *Result {
 	if t.params == "" {
-		panic("test task params is empty")
+		return &Result{Error: errors.New("test task params is empty")}
+	}
+	select {
+	case <-ctx.Done():
+		return &Result{Error: ctx.Err()}
+	case <-time.After(time.Second * 20):
 	}
-	time.Sleep(time.Second * 20)
 	logrus.Infof("test task params: %s", t.params)
+	return &Result{Output: fmt.Sprintf("test task params: %s", t.params)}
 }
 
 func (t *TestTask) SetParams(params string) {
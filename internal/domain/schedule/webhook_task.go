@@ -0,0 +1,175 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/MR5356/aurora/pkg/util/jsonschema"
+	"io"
+	"net/http"
+	"slices"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultWebhookTimeout = 30 * time.Second
+
+	// maxWebhookBodyLength max length of response body stored in record
+	maxWebhookBodyLength = 4 * 1024
+)
+
+// webhookParamsSchema json schema of WebhookParams
+var webhookParamsSchema = &jsonschema.Schema{
+	Type:     jsonschema.TypeObject,
+	Required: []string{"url"},
+	Properties: map[string]*jsonschema.Schema{
+		"method": {
+			Type:    jsonschema.TypeString,
+			Title:   "Method",
+			Default: http.MethodGet,
+			Enum:    []any{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		},
+		"url": {Type: jsonschema.TypeString, Title: "URL", Format: jsonschema.FormatURI},
+		"headers": {
+			Type:              jsonschema.TypeObject,
+			Title:             "Headers",
+			Description:       "request headers",
+			PatternProperties: map[string]*jsonschema.Schema{"^.*$": {Type: jsonschema.TypeString}},
+		},
+		"body": {
+			Type:        jsonschema.TypeString,
+			Title:       "Body",
+			Description: "request body, go template with {{.Now}} and {{.Unix}}",
+		},
+		"expectedStatus": {
+			Type:        jsonschema.TypeArray,
+			Title:       "Expected Status",
+			Description: "expected status codes, empty means 2xx",
+			Items:       &jsonschema.Schema{Type: jsonschema.TypeInteger, Minimum: jsonschema.Ptr(100.0), Maximum: jsonschema.Ptr(599.0)},
+		},
+		"timeout": {
+			Type:        jsonschema.TypeInteger,
+			Title:       "Timeout",
+			Description: "request timeout in seconds",
+			Default:     int(defaultWebhookTimeout.Seconds()),
+			Minimum:     jsonschema.Ptr(1.0),
+		},
+	},
+}
+
+type WebhookParams struct {
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers"`
+	Body           string            `json:"body"`
+	ExpectedStatus []int             `json:"expectedStatus"`
+	Timeout        int64             `json:"timeout"` // seconds
+}
+
+// WebhookOutput is the output of webhook task stored in record
+type WebhookOutput struct {
+	StatusCode int    `json:"statusCode"`
+	Body       string `json:"body"`
+}
+
+// WebhookTask makes an http request on each run
+type WebhookTask struct {
+	params *WebhookParams
+	err    error
+
+	// BodyWriter receives the whole response body if it is set, body stored in output is truncated
+	BodyWriter io.Writer
+}
+
+func (t *WebhookTask) SetParams(params string) {
+	ps := new(WebhookParams)
+	if err := json.Unmarshal([]byte(params), ps); err != nil {
+		t.err = fmt.Errorf("unmarshal webhook params failed: %v", err)
+		return
+	}
+	t.params = ps
+}
+
+func (t *WebhookTask) Run(ctx context.Context) *Result {
+	if t.err != nil {
+		return &Result{Error: t.err}
+	}
+	if t.params == nil {
+		return &Result{Error: fmt.Errorf("webhook params is empty")}
+	}
+
+	timeout := defaultWebhookTimeout
+	if t.params.Timeout > 0 {
+		timeout = time.Duration(t.params.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := t.renderBody()
+	if err != nil {
+		return &Result{Error: err}
+	}
+
+	method := t.params.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.params.URL, body)
+	if err != nil {
+		return &Result{Error: err}
+	}
+	for k, v := range t.params.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return &Result{Error: err}
+	}
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	if t.BodyWriter != nil {
+		reader = io.TeeReader(resp.Body, t.BodyWriter)
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(reader, maxWebhookBodyLength))
+	if t.BodyWriter != nil {
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			return &Result{Error: fmt.Errorf("read response body failed: %v", err)}
+		}
+	}
+	output, _ := json.Marshal(&WebhookOutput{StatusCode: resp.StatusCode, Body: string(respBody)})
+
+	result := &Result{Output: string(output)}
+	if !t.expected(resp.StatusCode) {
+		result.Error = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return result
+}
+
+func (t *WebhookTask) renderBody() (io.Reader, error) {
+	if t.params.Body == "" {
+		return nil, nil
+	}
+
+	tpl, err := template.New("body").Parse(t.params.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse body template failed: %v", err)
+	}
+
+	now := time.Now()
+	buf := new(bytes.Buffer)
+	if err := tpl.Execute(buf, map[string]any{"Now": now.Format(time.RFC3339), "Unix": now.Unix()}); err != nil {
+		return nil, fmt.Errorf("render body template failed: %v", err)
+	}
+	return buf, nil
+}
+
+func (t *WebhookTask) expected(statusCode int) bool {
+	if len(t.params.ExpectedStatus) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	return slices.Contains(t.params.ExpectedStatus, statusCode)
+}
@@ -0,0 +1,115 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookTask(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(r.Method + " " + r.Header.Get("X-Token") + " " + string(body)))
+		case "/slow":
+			time.Sleep(2 * time.Second)
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("a", maxWebhookBodyLength*2)))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		params     string
+		wantErr    bool
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "post with headers and body",
+			params:     `{"method":"POST","url":"` + server.URL + `/echo","headers":{"X-Token":"secret"},"body":"hello"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   "POST secret hello",
+		},
+		{
+			name:       "unexpected status",
+			params:     `{"url":"` + server.URL + `/error"}`,
+			wantErr:    true,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "expected status",
+			params:     `{"url":"` + server.URL + `/error","expectedStatus":[500]}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:    "timeout",
+			params:  `{"url":"` + server.URL + `/slow","timeout":1}`,
+			wantErr: true,
+		},
+		{
+			name:       "truncated body",
+			params:     `{"url":"` + server.URL + `/large"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   strings.Repeat("a", maxWebhookBodyLength),
+		},
+		{
+			name:    "invalid params",
+			params:  `invalid`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid body template",
+			params:  `{"url":"` + server.URL + `/echo","body":"{{.Now"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &WebhookTask{}
+			task.SetParams(tt.params)
+			res := task.Run(context.Background())
+
+			if (res.Error != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, res.Error)
+			}
+			if tt.wantStatus == 0 {
+				return
+			}
+
+			output := new(WebhookOutput)
+			if err := json.Unmarshal([]byte(res.Output), output); err != nil {
+				t.Fatalf("unmarshal output failed: %v", err)
+			}
+			if output.StatusCode != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, output.StatusCode)
+			}
+			if tt.wantBody != "" && output.Body != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, output.Body)
+			}
+		})
+	}
+}
+
+func TestWebhookParamsSchema(t *testing.T) {
+	if err := webhookParamsSchema.ValidateJSON(`{"url":"http://localhost","method":"POST","expectedStatus":[200,201],"timeout":10}`); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := webhookParamsSchema.ValidateJSON(`{"method":"CONNECT"}`); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+	if err := webhookParamsSchema.ValidateJSON(`{"url":"http://localhost","headers":{"X-Retry":1}}`); err == nil {
+		t.Errorf("expected error of non-string header, got nil")
+	}
+}
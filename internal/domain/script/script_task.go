@@ -3,7 +3,9 @@ package script
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/MR5356/aurora/internal/domain/host"
+	"github.com/MR5356/aurora/internal/domain/schedule"
 	database2 "github.com/MR5356/aurora/internal/infrastructure/database"
 	"github.com/MR5356/jietan/pkg/executor"
 	"github.com/MR5356/jietan/pkg/executor/api"
@@ -59,22 +61,22 @@ func (t *Task) SetParams(params string) {
 	t.params = ps
 }
 
-func (t *Task) Run() {
+func (t *Task) Run(ctx context.Context) *schedule.Result {
 	if t.params == nil {
 		logrus.Errorf("task params is empty")
-		return
+		return &schedule.Result{Error: fmt.Errorf("task params is empty")}
 	}
 	script, err := t.scriptDB.Detail(&Script{ID: t.params.ScriptId})
 	if err != nil {
 		logrus.Errorf("script %s not found", t.params.ScriptId)
-		return
+		return &schedule.Result{Error: fmt.Errorf("script %s not found", t.params.ScriptId)}
 	}
 	hosts := make([]*api.HostInfo, 0)
 	for _, id := range t.params.HostIds {
 		h, err := t.hostDB.Detail(&host.Host{ID: id})
 		if err != nil {
 			logrus.Errorf("host %s not found", id)
-			return
+			return &schedule.Result{Error: fmt.Errorf("host %s not found", id)}
 		}
 		hosts = append(hosts, &api.HostInfo{
 			Host:       h.HostInfo.Host,
@@ -101,7 +103,8 @@ func (t *Task) Run() {
 
 	exec := executor.GetExecutor("remote")
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	jobMap.Store(record.ID.String(), &JobInfo{
 		exec:      exec,
 		ctx:       ctx,
@@ -145,4 +148,10 @@ func (t *Task) Run() {
 	if err := t.recordDB.DB.Updates(record).Error; err != nil {
 		logrus.Errorf("update record error: %v", err)
 	}
+
+	result := &schedule.Result{Output: record.Result}
+	if res.Status != api.Success {
+		result.Error = fmt.Errorf("script %s run failed: %s %s", script.Title, res.Message, record.Error)
+	}
+	return result
 }
@@ -1,12 +1,14 @@
 package script
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/MR5356/aurora/internal/domain/host"
 	"github.com/MR5356/aurora/internal/domain/schedule"
 	"github.com/MR5356/aurora/internal/infrastructure/cache"
 	database2 "github.com/MR5356/aurora/internal/infrastructure/database"
+	"github.com/MR5356/aurora/pkg/util/jsonschema"
 	"github.com/MR5356/aurora/pkg/util/structutil"
 	"github.com/MR5356/aurora/pkg/util/validate"
 	"github.com/google/uuid"
@@ -19,6 +21,23 @@ var (
 	service     *Service
 )
 
+// runScriptParamsSchema json schema of RunScriptParams
+var runScriptParamsSchema = &jsonschema.Schema{
+	Type:     jsonschema.TypeObject,
+	Required: []string{"ScriptId", "HostIds"},
+	Properties: map[string]*jsonschema.Schema{
+		"ScriptId": {Type: jsonschema.TypeString, Format: jsonschema.FormatUUID, Title: "Script", Description: "id of script to run"},
+		"HostIds": {
+			Type:        jsonschema.TypeArray,
+			Title:       "Hosts",
+			Description: "ids of hosts to run script on",
+			MinItems:    jsonschema.Ptr(1),
+			Items:       &jsonschema.Schema{Type: jsonschema.TypeString, Format: jsonschema.FormatUUID},
+		},
+		"Params": {Type: jsonschema.TypeString, Title: "Params", Description: "params passed to script"},
+	},
+}
+
 type Service struct {
 	scriptDB database2.Mapper[*Script]
 	hostDB   *database2.BaseMapper[*host.Host]
@@ -98,7 +117,7 @@ func (s *Service) RunScriptOnHosts(rsp *RunScriptParams) error {
 	task := NewTask()
 	psStr, _ := json.Marshal(rsp)
 	task.SetParams(string(psStr))
-	go task.Run()
+	go task.Run(context.Background())
 	return nil
 }
 
@@ -141,8 +160,9 @@ func (s *Service) Initialize() error {
 	}
 
 	if err := schedule.GetExecutorManager().Register(schedule.Executor{
-		Name:        "script",
-		DisplayName: "script executor",
+		Name:         "script",
+		DisplayName:  "script executor",
+		ParamsSchema: runScriptParamsSchema,
 	}, func() schedule.Task {
 		return NewTask()
 	}); err != nil {
@@ -32,6 +32,39 @@ func NewGitlabAuth(oAuthName string, conf config.OAuthConfig) *GitlabAuth {
 	}
 }
 
+// BaseURL returns url of gitlab server, it is extracted from auth url
+func (p *GitlabAuth) BaseURL() (string, error) {
+	parsedURL, err := url.Parse(p.config.Endpoint.AuthURL)
+	if err != nil {
+		return "", err
+	}
+	extractedURL := url.URL{
+		Scheme: parsedURL.Scheme,
+		Host:   parsedURL.Host,
+	}
+	return extractedURL.String(), nil
+}
+
+// NewClient returns gitlab api client of oauth access token
+func (p *GitlabAuth) NewClient(accessToken string) (*gitlab.Client, error) {
+	baseURL, err := p.BaseURL()
+	if err != nil {
+		return nil, err
+	}
+	return gitlab.NewOAuthClient(accessToken, gitlab.WithBaseURL(baseURL))
+}
+
+// APIConfig returns oauth2 config which is authorized to read api of gitlab, code is exchanged at redirectURL
+func (p *GitlabAuth) APIConfig(redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		Scopes:       []string{"read_api", "read_user"},
+		Endpoint:     p.config.Endpoint,
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  redirectURL,
+	}
+}
+
 func (p *GitlabAuth) GetAuthURL(redirectURL string) string {
 	return p.config.AuthCodeURL(redirectURL, oauth2.AccessTypeOffline)
 }
@@ -45,16 +78,7 @@ func (p *GitlabAuth) GetInfo(code string) (*UserInfo, error) {
 	}
 	logrus.Debugf("token: %+v", token)
 
-	parsedURL, err := url.Parse(p.config.Endpoint.AuthURL)
-	if err != nil {
-		return nil, ErrAuthFailed
-	}
-	extractedURL := url.URL{
-		Scheme: parsedURL.Scheme,
-		Host:   parsedURL.Host,
-	}
-
-	git, err := gitlab.NewOAuthClient(token.AccessToken, gitlab.WithBaseURL(extractedURL.String()))
+	git, err := p.NewClient(token.AccessToken)
 	if err != nil {
 		logrus.Errorf("GetInfo.Create gitlab client error: %+v", err)
 		return nil, ErrAuthFailed
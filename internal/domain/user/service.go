@@ -115,6 +115,27 @@ func (s *Service) DetailUser(userID string) (*User, error) {
 	}
 }
 
+// DetailGroup detail user group
+func (s *Service) DetailGroup(id uuid.UUID) (*Group, error) {
+	if id == uuid.Nil {
+		return nil, errors.New("group not found")
+	}
+	return s.groupDB.Detail(&Group{ID: id})
+}
+
+// IsGroupMember reports whether user is a member of group, admin is a member of all groups
+func (s *Service) IsGroupMember(userID string, groupID uuid.UUID) bool {
+	// zero value fields are ignored by query conditions
+	if userID == "" || groupID == uuid.Nil {
+		return false
+	}
+	if (&User{ID: userID}).IsAdmin() {
+		return true
+	}
+	_, err := s.relationDB.Detail(&Relation{UserID: userID, GroupID: groupID})
+	return err == nil
+}
+
 // ListUser list user
 func (s *Service) ListUser(user *User) ([]*ListUserResponse, error) {
 	var users []*ListUserResponse
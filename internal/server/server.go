@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"github.com/MR5356/aurora/docs"
 	"github.com/MR5356/aurora/internal/config"
+	"github.com/MR5356/aurora/internal/domain/catalog"
 	"github.com/MR5356/aurora/internal/domain/health"
 	"github.com/MR5356/aurora/internal/domain/host"
 	"github.com/MR5356/aurora/internal/domain/module"
@@ -111,6 +112,7 @@ func New(cfg *config.Config) (server *Server, err error) {
 		health.GetService(),
 		schedule.GetService(),
 		module.GetService(),
+		catalog.GetService(),
 	}
 
 	for _, svc := range services {
@@ -131,6 +133,7 @@ func New(cfg *config.Config) (server *Server, err error) {
 		plugin.NewController(),
 		script.NewController(),
 		module.NewController(),
+		catalog.NewController(),
 	}
 
 	for _, ctl := range controllers {
@@ -14,6 +14,7 @@ type Client interface {
 	ListNetwork(ctx context.Context) ([]*Network, error)
 	Logs(ctx context.Context, containerId string) (io.ReadCloser, error)
 	Terminal(ctx *gin.Context, containerId string, user, cmd string) error
+	Exec(ctx context.Context, containerId string, user string, cmd []string, output io.Writer) (int, error) // Run command in container without tty, returns the exit code
 	Close()
 }
 
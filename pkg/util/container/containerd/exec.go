@@ -0,0 +1,11 @@
+package containerd
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+func (c *Client) Exec(ctx context.Context, containerId string, user string, cmd []string, output io.Writer) (int, error) {
+	return 0, errors.New("exec is not supported by containerd client")
+}
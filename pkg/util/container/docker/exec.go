@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func (c *Client) Exec(ctx context.Context, containerId string, user string, cmd []string, output io.Writer) (int, error) {
+	execIDResp, err := c.client.ContainerExecCreate(ctx, containerId, container.ExecOptions{
+		User:         user,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	hijackedResp, err := c.client.ContainerExecAttach(ctx, execIDResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer hijackedResp.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(output, output, hijackedResp.Reader)
+		done <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case err := <-done:
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	inspect, err := c.client.ContainerExecInspect(ctx, execIDResp.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
+}
@@ -0,0 +1,257 @@
+// Package jsonschema implements the subset of JSON Schema used to describe and validate params,
+// supported keywords: type, properties, required, patternProperties, additionalProperties, items, enum,
+// minimum, maximum, minLength, maxLength, minItems, maxItems, pattern and format (uuid, uri, date-time).
+// params are decoded by encoding/json, so property names are matched case-insensitively like it does
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+
+	FormatUUID     = "uuid"
+	FormatURI      = "uri"
+	FormatDateTime = "date-time"
+)
+
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	PatternProperties    map[string]*Schema `json:"patternProperties,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// Ptr returns pointer of v, it is used to set optional keywords like Minimum
+func Ptr[T int | float64 | bool](v T) *T {
+	return &v
+}
+
+// ValidateJSON validate json data against schema, empty data is validated as an empty object
+func (s *Schema) ValidateJSON(data string) error {
+	if strings.TrimSpace(data) == "" {
+		data = "{}"
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return fmt.Errorf("invalid json: %v", err)
+	}
+	return s.Validate(v)
+}
+
+// Validate validate decoded json value against schema, all problems are returned in one error
+func (s *Schema) Validate(v any) error {
+	errs := make([]string, 0)
+	s.validate("$", v, &errs)
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, v any, errs *[]string) {
+	addErr := func(format string, args ...any) {
+		*errs = append(*errs, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if s.Type != "" && !matchType(s.Type, v) {
+		addErr("expected %s, got %s", s.Type, typeOf(v))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		addErr("must be one of %v", s.Enum)
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for _, key := range s.Required {
+			if _, ok := lookup(val, key); !ok {
+				addErr("missing required property %q", key)
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if prop, ok := lookup(s.Properties, key); ok {
+				prop.validate(path+"."+key, val[key], errs)
+				continue
+			}
+			matched := false
+			for pattern, prop := range s.PatternProperties {
+				if re, err := regexp.Compile(pattern); err != nil {
+					addErr("invalid pattern %q", pattern)
+				} else if re.MatchString(key) {
+					matched = true
+					prop.validate(path+"."+key, val[key], errs)
+				}
+			}
+			if !matched && s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				addErr("unknown property %q", key)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			addErr("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			addErr("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case string:
+		length := len([]rune(val))
+		if s.MinLength != nil && length < *s.MinLength {
+			addErr("length must be at least %d", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			addErr("length must be at most %d", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err != nil {
+				addErr("invalid pattern %q", s.Pattern)
+			} else if !re.MatchString(val) {
+				addErr("must match pattern %q", s.Pattern)
+			}
+		}
+		if err := checkFormat(s.Format, val); err != nil {
+			addErr("invalid %s: %v", s.Format, err)
+		}
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			addErr("must be greater than or equal to %v", *s.Minimum)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			addErr("must be less than or equal to %v", *s.Maximum)
+		}
+	}
+}
+
+// lookup value of key in m, an exact match is preferred to a case-insensitive one
+func lookup[V any](m map[string]V, key string) (V, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+func matchType(t string, v any) bool {
+	switch t {
+	case TypeObject:
+		_, ok := v.(map[string]any)
+		return ok
+	case TypeArray:
+		_, ok := v.([]any)
+		return ok
+	case TypeString:
+		_, ok := v.(string)
+		return ok
+	case TypeNumber:
+		_, ok := v.(float64)
+		return ok
+	case TypeInteger:
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case TypeBoolean:
+		_, ok := v.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	case string:
+		return TypeString
+	case float64:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	default:
+		return reflect.TypeOf(v).String()
+	}
+}
+
+func inEnum(enum []any, v any) bool {
+	for _, e := range enum {
+		// normalize enum values declared in go, e.g. int to float64
+		if bs, err := json.Marshal(e); err == nil {
+			var ev any
+			if json.Unmarshal(bs, &ev) == nil && reflect.DeepEqual(ev, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func checkFormat(format, v string) error {
+	switch format {
+	case FormatUUID:
+		_, err := uuid.Parse(v)
+		return err
+	case FormatURI:
+		u, err := url.Parse(v)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" {
+			return errors.New("missing scheme")
+		}
+		return nil
+	case FormatDateTime:
+		_, err := time.Parse(time.RFC3339, v)
+		return err
+	default:
+		return nil
+	}
+}
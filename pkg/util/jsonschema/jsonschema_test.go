@@ -0,0 +1,57 @@
+package jsonschema
+
+import (
+	"testing"
+)
+
+func TestSchema_ValidateJSON(t *testing.T) {
+	schema := &Schema{
+		Type:     TypeObject,
+		Required: []string{"id", "hosts"},
+		Properties: map[string]*Schema{
+			"id":      {Type: TypeString, Format: FormatUUID},
+			"hosts":   {Type: TypeArray, MinItems: Ptr(1), Items: &Schema{Type: TypeString}},
+			"method":  {Type: TypeString, Enum: []any{"GET", "POST"}},
+			"retry":   {Type: TypeInteger, Minimum: Ptr(0.0), Maximum: Ptr(10.0)},
+			"name":    {Type: TypeString, MinLength: Ptr(2), Pattern: "^[a-z]+$"},
+			"url":     {Type: TypeString, Format: FormatURI},
+			"enabled": {Type: TypeBoolean},
+		},
+		PatternProperties:    map[string]*Schema{"^x-": {Type: TypeString}},
+		AdditionalProperties: Ptr(false),
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"method":"GET","retry":3,"name":"abc","url":"http://localhost","enabled":true}`},
+		{name: "empty", data: ``, wantErr: true},
+		{name: "invalid json", data: `{`, wantErr: true},
+		{name: "not object", data: `[]`, wantErr: true},
+		{name: "missing required", data: `{"id":"00000000-0000-0000-0000-000000000000"}`, wantErr: true},
+		{name: "invalid uuid", data: `{"id":"1","hosts":["a"]}`, wantErr: true},
+		{name: "min items", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":[]}`, wantErr: true},
+		{name: "item type", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":[1]}`, wantErr: true},
+		{name: "enum", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"method":"PUT"}`, wantErr: true},
+		{name: "integer", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"retry":1.5}`, wantErr: true},
+		{name: "maximum", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"retry":11}`, wantErr: true},
+		{name: "pattern", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"name":"ABC"}`, wantErr: true},
+		{name: "min length", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"name":"a"}`, wantErr: true},
+		{name: "uri", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"url":"localhost"}`, wantErr: true},
+		{name: "case insensitive", data: `{"ID":"00000000-0000-0000-0000-000000000000","Hosts":["a"]}`},
+		{name: "case insensitive invalid", data: `{"ID":"1","hosts":["a"]}`, wantErr: true},
+		{name: "pattern property", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"x-a":"b"}`},
+		{name: "pattern property type", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"x-a":1}`, wantErr: true},
+		{name: "additional property", data: `{"id":"00000000-0000-0000-0000-000000000000","hosts":["a"],"other":1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := schema.ValidateJSON(tt.data); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}